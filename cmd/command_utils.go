@@ -52,38 +52,38 @@ func MustOutFile(path string) *os.File {
 	return outFile
 }
 
-func ExportEntry(entry interface{}, outFile *os.File, extra map[string]string) (int, error) {
-	// This extra marshalling/unmarshalling is silly, but it's required to properly handle the null.[String|Int*] types, and add the extra fields.
-	m, err := json.Marshal(entry)
+// encodeRow returns the JSON encoding of row with the extra fields added, as
+// written to the exported files.
+func encodeRow(row interface{}, extra map[string]string) ([]byte, error) {
+	fields, err := rowFields(row, extra)
 	if err != nil {
-		cmdLogger.Errorf("Error marshalling %+v: %v ", entry, err)
+		return nil, err
 	}
-	i := map[string]interface{}{}
-	// Use a decoder here so that 'UseNumber' ensures large ints are properly decoded
-	decoder := json.NewDecoder(bytes.NewReader(m))
-	decoder.UseNumber()
-	err = decoder.Decode(&i)
+	encoded, err := json.Marshal(fields)
 	if err != nil {
-		cmdLogger.Errorf("Error unmarshalling %+v: %v ", i, err)
-	}
-	for k, v := range extra {
-		i[k] = v
+		return nil, fmt.Errorf("could not json encode %+v: %s", row, err)
 	}
+	return encoded, nil
+}
 
-	marshalled, err := json.Marshal(i)
+// rowFields decodes the JSON encoding of row into a map so that the
+// null.[String|Int*] types are handled and the extra fields can be added.
+func rowFields(row interface{}, extra map[string]string) (map[string]interface{}, error) {
+	m, err := json.Marshal(row)
 	if err != nil {
-		return 0, fmt.Errorf("could not json encode %+v: %s", entry, err)
+		return nil, fmt.Errorf("could not json encode %+v: %s", row, err)
 	}
-	cmdLogger.Debugf("Writing entry to %s", outFile.Name())
-	numBytes, err := outFile.Write(marshalled)
-	if err != nil {
-		cmdLogger.Errorf("Error writing %+v to file: %s", entry, err)
+	fields := map[string]interface{}{}
+	// Use a decoder here so that 'UseNumber' ensures large ints are properly decoded
+	decoder := json.NewDecoder(bytes.NewReader(m))
+	decoder.UseNumber()
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, fmt.Errorf("could not json decode %s: %s", m, err)
 	}
-	newLineNumBytes, err := outFile.WriteString("\n")
-	if err != nil {
-		cmdLogger.Errorf("Error writing new line to file %s: %s", outFile.Name(), err)
+	for k, v := range extra {
+		fields[k] = v
 	}
-	return numBytes + newLineNumBytes, nil
+	return fields, nil
 }
 
 // Prints the number of attempted, failed, and successful transformations as a JSON object
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/stellar-etl/v2/internal/transform"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+)
+
+// newContractSpecRegistry returns the registry used to decode contract values with their spec, seeded from the
+// exports named in flags. It returns nil if decode-with-spec is not set.
+func newContractSpecRegistry(flags utils.ContractSpecFlagValues) (*transform.ContractSpecRegistry, error) {
+	if !flags.Decode {
+		return nil, nil
+	}
+
+	specs := transform.NewContractSpecRegistry()
+	if err := loadContractSpecFile(flags.SpecsFile, specs.LoadSpecs); err != nil {
+		return nil, err
+	}
+	if err := loadContractSpecFile(flags.InstancesFile, specs.LoadInstances); err != nil {
+		return nil, err
+	}
+	return specs, nil
+}
+
+func loadContractSpecFile(path string, load func(io.Reader) error) error {
+	if path == "" {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := load(f); err != nil {
+		return fmt.Errorf("could not load %s: %v", path, err)
+	}
+	return nil
+}
+
+// learnContractSpecs adds the contract code and instances created by changes to specs
+func learnContractSpecs(specs *transform.ContractSpecRegistry, changes []ingest.Change) {
+	if specs == nil {
+		return
+	}
+	for _, change := range changes {
+		if err := specs.AddChange(change); err != nil {
+			cmdLogger.LogError(fmt.Errorf("could not learn contract spec: %v", err))
+		}
+	}
+}
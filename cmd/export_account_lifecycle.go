@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-etl/v2/internal/input"
+	"github.com/stellar/stellar-etl/v2/internal/transform"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+)
+
+var accountLifecycleCmd = &cobra.Command{
+	Use:   "export_account_lifecycle",
+	Short: "Exports the creation, merge and last activity of accounts over a specified range.",
+	Long: `Exports the lifecycle of the accounts active over a specified range: when
+and by whom they were created, with which starting balance and sponsor, when
+and into which account they were merged, and the last ledger they were the
+source of a transaction or operation in. Every batch writes one row per
+account that changed in it, as of the end of the batch, so the last row of an
+account is its current lifecycle. Pass the rows of earlier runs with
+--lifecycle-file to carry the lifecycles over. Ledgers are processed in
+batches of batch-size; each batch produces one file named
+{start}-{end}-account_lifecycle.txt in the output folder.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		path, err := cmd.Flags().GetString("lifecycle-file")
+		if err != nil {
+			cmdLogger.Fatal("could not get lifecycle file: ", err)
+		}
+		lifecycles := transform.AccountLifecycles{}
+		if path != "" {
+			f, err := os.Open(path)
+			if err != nil {
+				cmdLogger.Fatal("could not open lifecycle file: ", err)
+			}
+			lifecycles, err = transform.LoadAccountLifecycles(f)
+			f.Close()
+			if err != nil {
+				cmdLogger.Fatal(fmt.Sprintf("could not load %s: ", path), err)
+			}
+		}
+		changed := map[string]bool{}
+		runLedgerBatchExportWithBatchEnd(cmd, "account_lifecycle", nil, newAccountLifecycleProcessor(lifecycles, changed), newAccountLifecycleBatchEnd(lifecycles, changed))
+	},
+}
+
+// newAccountLifecycleProcessor returns a processor that updates lifecycles with the transactions of each ledger, and
+// records the accounts they changed in changed
+func newAccountLifecycleProcessor(lifecycles transform.AccountLifecycles, changed map[string]bool) processLedgerFunc {
+	return func(lcm xdr.LedgerCloseMeta, env utils.EnvironmentDetails, sink Sink) (int, int) {
+		txInputs, err := input.TransactionsFromLedger(lcm, env.NetworkPassphrase)
+		if err != nil {
+			cmdLogger.LogError(fmt.Errorf("could not read transactions from ledger %d: %v", lcm.LedgerSequence(), err))
+			return 1, 1
+		}
+		attempts, failures := 0, 0
+		for _, txInput := range txInputs {
+			attempts++
+			accounts, err := lifecycles.AddTransaction(txInput.Transaction, txInput.LedgerHistory)
+			if err != nil {
+				cmdLogger.LogError(fmt.Errorf("could not add transaction %d in ledger %d to account lifecycles: %v", txInput.Transaction.Index, lcm.LedgerSequence(), err))
+				failures++
+				continue
+			}
+			for _, account := range accounts {
+				changed[account] = true
+			}
+		}
+
+		return attempts, failures
+	}
+}
+
+// newAccountLifecycleBatchEnd returns a batch end that writes the lifecycles of the accounts changed in a batch
+func newAccountLifecycleBatchEnd(lifecycles transform.AccountLifecycles, changed map[string]bool) batchEndFunc {
+	return func(lcm xdr.LedgerCloseMeta, sink Sink) (int, int) {
+		failures := 0
+		accounts := make([]string, 0, len(changed))
+		for account := range changed {
+			accounts = append(accounts, account)
+		}
+		sort.Strings(accounts)
+		for _, account := range accounts {
+			if err := sink.WriteRow(lifecycles[account]); err != nil {
+				cmdLogger.LogError(fmt.Errorf("could not export account lifecycle: %v", err))
+				failures++
+			}
+			delete(changed, account)
+		}
+		return 0, failures
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(accountLifecycleCmd)
+	utils.AddCommonFlags(accountLifecycleCmd.Flags())
+	utils.AddLedgerBatchFlags("account_lifecycle", accountLifecycleCmd.Flags(), "exported_account_lifecycle/")
+	utils.AddCloudStorageFlags(accountLifecycleCmd.Flags())
+	utils.AddKafkaFlags(accountLifecycleCmd.Flags())
+	utils.AddPostgresFlags(accountLifecycleCmd.Flags())
+	accountLifecycleCmd.Flags().String("lifecycle-file", "", "Earlier export_account_lifecycle output the lifecycles are carried over from")
+	accountLifecycleCmd.MarkFlagRequired("start-ledger")
+	accountLifecycleCmd.MarkFlagRequired("end-ledger")
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-etl/v2/internal/input"
+	"github.com/stellar/stellar-etl/v2/internal/transform"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+)
+
+var archivalEventsCmd = &cobra.Command{
+	Use:   "export_archival_events",
+	Short: "Exports the state archival events of Soroban entries over a specified range.",
+	Long: `Exports an event whenever a contract data or code entry expires, is evicted
+from the live bucket list or is restored over a specified range. The TTLs are
+not seeded from the bucket list, so expirations are only reported for the
+entries whose TTL was created, extended or restored within the range, and
+evictions only carry the TTL of those entries. Start the export early enough
+to cover the entries of interest. Expired persistent entries are followed
+until they are evicted, as they can still be restored. Ledgers are processed
+in batches of batch-size; each batch produces one file named
+{start}-{end}-archival_events.txt in the output folder.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runLedgerBatchExport(cmd, "archival_events", nil, newArchivalEventsProcessor(transform.NewArchivalTracker()))
+	},
+}
+
+// newArchivalEventsProcessor returns a processor that follows the TTLs of the Soroban entries in tracker across the
+// ledgers of the export
+func newArchivalEventsProcessor(tracker *transform.ArchivalTracker) processLedgerFunc {
+	return func(lcm xdr.LedgerCloseMeta, env utils.EnvironmentDetails, sink Sink) (int, int) {
+		txInputs, err := input.TransactionsFromLedger(lcm, env.NetworkPassphrase)
+		if err != nil {
+			cmdLogger.LogError(fmt.Errorf("could not read transactions from ledger %d: %v", lcm.LedgerSequence(), err))
+			return 0, 0
+		}
+		transactions := make([]ingest.LedgerTransaction, 0, len(txInputs))
+		for _, txInput := range txInputs {
+			transactions = append(transactions, txInput.Transaction)
+		}
+
+		events, err := tracker.TransformArchivalEvents(lcm, transactions)
+		if err != nil {
+			cmdLogger.LogError(fmt.Errorf("could not transform archival events: %v", err))
+			return 1, 1
+		}
+		failures := 0
+		for _, event := range events {
+			if err := sink.WriteRow(event); err != nil {
+				cmdLogger.LogError(fmt.Errorf("could not export archival event: %v", err))
+				failures++
+			}
+		}
+		return 1, failures
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(archivalEventsCmd)
+	utils.AddCommonFlags(archivalEventsCmd.Flags())
+	utils.AddLedgerBatchFlags("archival_events", archivalEventsCmd.Flags(), "exported_archival_events/")
+	utils.AddCloudStorageFlags(archivalEventsCmd.Flags())
+	utils.AddKafkaFlags(archivalEventsCmd.Flags())
+	utils.AddPostgresFlags(archivalEventsCmd.Flags())
+	archivalEventsCmd.MarkFlagRequired("start-ledger")
+	archivalEventsCmd.MarkFlagRequired("end-ledger")
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-etl/v2/internal/input"
+	"github.com/stellar/stellar-etl/v2/internal/transform"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+)
+
+var assetRegistryCmd = &cobra.Command{
+	Use:   "export_asset_registry",
+	Short: "Exports every asset the first time it is seen over a specified range.",
+	Long: `Exports the assets used by the successful transactions of a specified range,
+whether in payments, path payment paths, offers, trustlines, claimable
+balances, liquidity pools or Stellar Asset Contract deployments. Each asset is
+written once, with the ledger, operation and source it was first seen in and
+the ID of its Stellar Asset Contract. With --registry-file, the assets already
+seen are read from the file and the new ones are appended to it, so that
+incremental runs skip them and reruns write them again only in the ledger they
+were first seen in. Ledgers are processed in batches of batch-size; each batch
+produces one file named {start}-{end}-asset_registry.txt in the output folder.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		path, err := cmd.Flags().GetString("registry-file")
+		if err != nil {
+			cmdLogger.Fatal("could not get registry file: ", err)
+		}
+		registry := map[int64]transform.AssetRegistryOutput{}
+		var store io.Writer = io.Discard
+		if path != "" {
+			f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+			if err != nil {
+				cmdLogger.Fatal("could not open registry file: ", err)
+			}
+			defer f.Close()
+			registry, err = transform.LoadAssetRegistry(f)
+			if err != nil {
+				cmdLogger.Fatal(fmt.Sprintf("could not load %s: ", path), err)
+			}
+			store = f
+		}
+		runLedgerBatchExport(cmd, "asset_registry", nil, newAssetRegistryProcessor(registry, store))
+	},
+}
+
+// newAssetRegistryProcessor returns a processor that writes the assets of each ledger that are not in registry yet,
+// or that registry has as first seen in that ledger. Assets that are new to registry are added to it and appended to
+// store.
+func newAssetRegistryProcessor(registry map[int64]transform.AssetRegistryOutput, store io.Writer) processLedgerFunc {
+	written := map[int64]bool{}
+	return func(lcm xdr.LedgerCloseMeta, env utils.EnvironmentDetails, sink Sink) (int, int) {
+		txInputs, err := input.TransactionsFromLedger(lcm, env.NetworkPassphrase)
+		if err != nil {
+			cmdLogger.LogError(fmt.Errorf("could not read transactions from ledger %d: %v", lcm.LedgerSequence(), err))
+			return 0, 0
+		}
+		attempts, failures := 0, 0
+		for _, txInput := range txInputs {
+			attempts++
+			assets, err := transform.TransformAssetRegistry(txInput.Transaction, txInput.LedgerHistory, env.NetworkPassphrase)
+			if err != nil {
+				cmdLogger.LogError(fmt.Errorf("could not transform assets of transaction %d in ledger %d: %v", txInput.Transaction.Index, lcm.LedgerSequence(), err))
+				failures++
+				continue
+			}
+			for _, asset := range assets {
+				previous, seen := registry[asset.AssetID]
+				if written[asset.AssetID] || (seen && previous.FirstSeenLedger < asset.FirstSeenLedger) {
+					continue
+				}
+				written[asset.AssetID] = true
+				if err := sink.WriteRow(asset); err != nil {
+					cmdLogger.LogError(fmt.Errorf("could not export asset: %v", err))
+					failures++
+					continue
+				}
+				if seen && previous.FirstSeenLedger == asset.FirstSeenLedger {
+					continue
+				}
+				registry[asset.AssetID] = asset
+				line, err := json.Marshal(asset)
+				if err == nil {
+					_, err = store.Write(append(line, '\n'))
+				}
+				if err != nil {
+					cmdLogger.Fatal("could not add asset to the registry file: ", err)
+				}
+			}
+		}
+		return attempts, failures
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(assetRegistryCmd)
+	utils.AddCommonFlags(assetRegistryCmd.Flags())
+	utils.AddLedgerBatchFlags("asset_registry", assetRegistryCmd.Flags(), "exported_asset_registry/")
+	utils.AddCloudStorageFlags(assetRegistryCmd.Flags())
+	utils.AddKafkaFlags(assetRegistryCmd.Flags())
+	utils.AddPostgresFlags(assetRegistryCmd.Flags())
+	assetRegistryCmd.Flags().String("registry-file", "", "File of the assets already seen, which the new assets are appended to")
+	assetRegistryCmd.MarkFlagRequired("start-ledger")
+	assetRegistryCmd.MarkFlagRequired("end-ledger")
+}
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-etl/v2/internal/input"
+	"github.com/stellar/stellar-etl/v2/internal/transform"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+)
+
+// assetStatsEntryTypes are the ledger entries that hold assets or set the flags of their issuers
+var assetStatsEntryTypes = []xdr.LedgerEntryType{
+	xdr.LedgerEntryTypeAccount,
+	xdr.LedgerEntryTypeTrustline,
+	xdr.LedgerEntryTypeClaimableBalance,
+	xdr.LedgerEntryTypeLiquidityPool,
+	xdr.LedgerEntryTypeContractData,
+}
+
+var assetStatsCmd = &cobra.Command{
+	Use:   "export_asset_stats",
+	Short: "Exports the supply and holders of every asset over a specified range.",
+	Long: `Exports the supply and holders of the credit assets, as held in trustlines,
+claimable balances, liquidity pools and Stellar Asset Contract balances of
+contracts, along with the flags of their issuers. Evicted contract balances
+leave the totals until they are restored. The totals are seeded from
+the bucket list of the most recent checkpoint before start-ledger, so the
+export starts from the ledger after it. Ledgers are processed in batches of
+batch-size; each batch produces one file named {start}-{end}-asset_stats.txt
+in the output folder, with one row for every asset that changed in the batch,
+as of the last ledger of the batch. --kafka-resume is not supported, as the
+ledgers skipped by resuming would be missing from the totals.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commonArgs := utils.MustCommonFlags(cmd.Flags(), cmdLogger)
+		env := utils.GetEnvironmentDetails(commonArgs)
+		start, _, _, _ := utils.MustLedgerBatchFlags(cmd.Flags(), cmdLogger)
+		if utils.MustKafkaFlags(cmd.Flags(), cmdLogger).Resume {
+			cmdLogger.Fatal("kafka-resume is not supported by export_asset_stats, whose totals are seeded from the checkpoint before start-ledger")
+		}
+
+		tracker := transform.NewAssetStatsTracker(env.NetworkPassphrase)
+		// There is no checkpoint before ledger 63, and the genesis ledger holds no credit assets
+		startNum := uint32(2)
+		if start > 64 {
+			checkpoint := utils.GetMostRecentCheckpoint(start - 1)
+			seedAssetStats(tracker, env, checkpoint)
+			startNum = checkpoint + 1
+		}
+		if err := cmd.Flags().Set("start-ledger", strconv.FormatUint(uint64(startNum), 10)); err != nil {
+			cmdLogger.Fatal("could not set start sequence number: ", err)
+		}
+		runLedgerBatchExportWithBatchEnd(cmd, "asset_stats", nil, newAssetStatsProcessor(tracker), newAssetStatsBatchEnd(tracker))
+	},
+}
+
+// seedAssetStats adds every entry of the bucket list at checkpoint to tracker
+func seedAssetStats(tracker *transform.AssetStatsTracker, env utils.EnvironmentDetails, checkpoint uint32) {
+	archive, err := utils.CreateHistoryArchiveClient(env.ArchiveURLs)
+	if err != nil {
+		cmdLogger.Fatal("could not connect to the history archives: ", err)
+	}
+	isAssetStatsType := func(entryType xdr.LedgerEntryType) bool {
+		for _, assetStatsType := range assetStatsEntryTypes {
+			if entryType == assetStatsType {
+				return true
+			}
+		}
+		return false
+	}
+	reader, err := ingest.NewCheckpointChangeReader(context.Background(), archive, checkpoint, ingest.WithFilter(
+		func(entry xdr.LedgerEntry) bool { return isAssetStatsType(entry.Data.Type) },
+		func(key xdr.LedgerKey) bool { return isAssetStatsType(key.Type) },
+	))
+	if err != nil {
+		cmdLogger.Fatal(fmt.Sprintf("could not read the bucket list of checkpoint %d: ", checkpoint), err)
+	}
+	defer reader.Close()
+
+	for {
+		change, err := reader.Read()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			cmdLogger.Fatal(fmt.Sprintf("could not read the bucket list of checkpoint %d: ", checkpoint), err)
+		}
+		if err := tracker.AddChange(change); err != nil {
+			cmdLogger.LogError(fmt.Errorf("could not add entry of checkpoint %d to asset stats: %v", checkpoint, err))
+		}
+	}
+}
+
+// newAssetStatsProcessor returns a processor that adds the changes of each ledger to tracker, and removes the entries
+// it evicts
+func newAssetStatsProcessor(tracker *transform.AssetStatsTracker) processLedgerFunc {
+	return func(lcm xdr.LedgerCloseMeta, env utils.EnvironmentDetails, sink Sink) (int, int) {
+		changes, err := input.ChangesFromLedger(lcm, env, cmdLogger)
+		if err != nil {
+			cmdLogger.LogError(err)
+			return 1, 1
+		}
+		attempts, failures := 0, 0
+		for _, entryType := range assetStatsEntryTypes {
+			for _, change := range changes.Changes[entryType].Changes {
+				attempts++
+				if err := tracker.AddChange(change); err != nil {
+					cmdLogger.LogError(fmt.Errorf("could not add change of ledger %d to asset stats: %v", lcm.LedgerSequence(), err))
+					failures++
+				}
+			}
+		}
+
+		attempts++
+		evictedKeys, err := lcm.EvictedLedgerKeys()
+		if err != nil {
+			cmdLogger.LogError(fmt.Errorf("could not read evicted keys of ledger %d: %v", lcm.LedgerSequence(), err))
+			return attempts, failures + 1
+		}
+		for _, key := range evictedKeys {
+			tracker.EvictLedgerKey(key)
+		}
+		return attempts, failures
+	}
+}
+
+// newAssetStatsBatchEnd returns a batch end that writes the stats of the assets touched in a batch with its last ledger
+func newAssetStatsBatchEnd(tracker *transform.AssetStatsTracker) batchEndFunc {
+	return func(lcm xdr.LedgerCloseMeta, sink Sink) (int, int) {
+		stats, err := tracker.Stats(lcm.LedgerHeaderHistoryEntry())
+		if err != nil {
+			cmdLogger.LogError(fmt.Errorf("could not get asset stats of ledger %d: %v", lcm.LedgerSequence(), err))
+			return 1, 1
+		}
+		failures := 0
+		for _, stat := range stats {
+			if err := sink.WriteRow(stat); err != nil {
+				cmdLogger.LogError(fmt.Errorf("could not export asset stats: %v", err))
+				failures++
+			}
+		}
+		return 0, failures
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(assetStatsCmd)
+	utils.AddCommonFlags(assetStatsCmd.Flags())
+	utils.AddLedgerBatchFlags("asset_stats", assetStatsCmd.Flags(), "exported_asset_stats/")
+	utils.AddCloudStorageFlags(assetStatsCmd.Flags())
+	utils.AddKafkaFlags(assetStatsCmd.Flags())
+	utils.AddPostgresFlags(assetStatsCmd.Flags())
+	assetStatsCmd.MarkFlagRequired("start-ledger")
+	assetStatsCmd.MarkFlagRequired("end-ledger")
+}
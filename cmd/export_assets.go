@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"os"
 
 	"github.com/spf13/cobra"
 	"github.com/stellar/go-stellar-sdk/xdr"
@@ -28,8 +27,7 @@ Duplicate assets are deduplicated across the entire run.`,
 // output files.
 func newAssetsProcessor() processLedgerFunc {
 	seenIDs := map[int64]bool{}
-	return func(lcm xdr.LedgerCloseMeta, _ utils.EnvironmentDetails, outFile *os.File, writeParquet bool, extra map[string]string) ([]transform.SchemaParquet, int, int) {
-		var rows []transform.SchemaParquet
+	return func(lcm xdr.LedgerCloseMeta, _ utils.EnvironmentDetails, sink Sink) (int, int) {
 		attempts, failures := 0, 0
 		for _, assetInput := range input.PaymentOperationsFromLedger(lcm) {
 			attempts++
@@ -43,16 +41,12 @@ func newAssetsProcessor() processLedgerFunc {
 				continue
 			}
 			seenIDs[transformed.AssetID] = true
-			if _, err := ExportEntry(transformed, outFile, extra); err != nil {
+			if err := sink.WriteRow(transformed); err != nil {
 				cmdLogger.LogError(fmt.Errorf("could not export asset: %v", err))
 				failures++
-				continue
-			}
-			if writeParquet {
-				rows = append(rows, transformed)
 			}
 		}
-		return rows, attempts, failures
+		return attempts, failures
 	}
 }
 
@@ -61,5 +55,7 @@ func init() {
 	utils.AddCommonFlags(assetsCmd.Flags())
 	utils.AddLedgerBatchFlags("assets", assetsCmd.Flags(), "exported_assets/")
 	utils.AddCloudStorageFlags(assetsCmd.Flags())
+	utils.AddKafkaFlags(assetsCmd.Flags())
+	utils.AddPostgresFlags(assetsCmd.Flags())
 	assetsCmd.MarkFlagRequired("end-ledger")
 }
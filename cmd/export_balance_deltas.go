@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-etl/v2/internal/input"
+	"github.com/stellar/stellar-etl/v2/internal/transform"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+)
+
+var balanceDeltasCmd = &cobra.Command{
+	Use:   "export_balance_deltas",
+	Short: "Exports the balance changes of every transaction over a specified range.",
+	Long: `Exports one row per transaction, holder and asset with the net change of
+the balance of an account or contract, read from the account, trustline and
+contract balance entries the transaction changed. The change is split into the
+fee and the transfer, trade, liquidity pool, claimable balance, clawback and
+other components, by the operations that caused it. Failed transactions only
+have fee changes. Ledgers are processed in batches of batch-size; each batch
+produces one file named {start}-{end}-balance_deltas.txt in the output folder.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runLedgerBatchExport(cmd, "balance_deltas", nil, processBalanceDeltas)
+	},
+}
+
+func processBalanceDeltas(lcm xdr.LedgerCloseMeta, env utils.EnvironmentDetails, sink Sink) (int, int) {
+	txInputs, err := input.TransactionsFromLedger(lcm, env.NetworkPassphrase)
+	if err != nil {
+		cmdLogger.LogError(fmt.Errorf("could not read transactions from ledger %d: %v", lcm.LedgerSequence(), err))
+		return 1, 1
+	}
+	attempts, failures := 0, 0
+	for _, txInput := range txInputs {
+		attempts++
+		deltas, err := transform.TransformBalanceDeltas(txInput.Transaction, txInput.LedgerHistory, env.NetworkPassphrase)
+		if err != nil {
+			ledgerSeq := txInput.LedgerHistory.Header.LedgerSeq
+			cmdLogger.LogError(fmt.Errorf("could not transform balance deltas of transaction %d in ledger %d: %v", txInput.Transaction.Index, ledgerSeq, err))
+			failures++
+			continue
+		}
+		for _, delta := range deltas {
+			if err := sink.WriteRow(delta); err != nil {
+				cmdLogger.LogError(fmt.Errorf("could not export balance delta: %v", err))
+				failures++
+			}
+		}
+	}
+	return attempts, failures
+}
+
+func init() {
+	rootCmd.AddCommand(balanceDeltasCmd)
+	utils.AddCommonFlags(balanceDeltasCmd.Flags())
+	utils.AddLedgerBatchFlags("balance_deltas", balanceDeltasCmd.Flags(), "exported_balance_deltas/")
+	utils.AddCloudStorageFlags(balanceDeltasCmd.Flags())
+	utils.AddKafkaFlags(balanceDeltasCmd.Flags())
+	utils.AddPostgresFlags(balanceDeltasCmd.Flags())
+	balanceDeltasCmd.MarkFlagRequired("start-ledger")
+	balanceDeltasCmd.MarkFlagRequired("end-ledger")
+}
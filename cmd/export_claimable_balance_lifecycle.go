@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-etl/v2/internal/input"
+	"github.com/stellar/stellar-etl/v2/internal/transform"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+)
+
+var claimableBalanceLifecycleCmd = &cobra.Command{
+	Use:   "export_claimable_balance_lifecycle",
+	Short: "Exports the creation, claim, clawback and expiry of claimable balances over a specified range.",
+	Long: `Exports one row per creation, claim and clawback of a claimable balance over
+a specified range, with the operation and account behind it, and one row when
+a balance expires because none of its claimants can claim it anymore. Every
+row holds the claimants of the balance with the absolute windows during which
+they can claim it, resolved from their predicates. Pass the rows of earlier
+runs with --lifecycle-file to carry over the balances created before the
+range. Ledgers are processed in batches of batch-size; each batch produces one
+file named {start}-{end}-claimable_balance_lifecycle.txt in the output folder.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		path, err := cmd.Flags().GetString("lifecycle-file")
+		if err != nil {
+			cmdLogger.Fatal("could not get lifecycle file: ", err)
+		}
+		lifecycles := transform.ClaimableBalanceLifecycles{}
+		if path != "" {
+			f, err := os.Open(path)
+			if err != nil {
+				cmdLogger.Fatal("could not open lifecycle file: ", err)
+			}
+			lifecycles, err = transform.LoadClaimableBalanceLifecycles(f)
+			f.Close()
+			if err != nil {
+				cmdLogger.Fatal(fmt.Sprintf("could not load %s: ", path), err)
+			}
+		}
+		runLedgerBatchExport(cmd, "claimable_balance_lifecycle", nil, newClaimableBalanceLifecycleProcessor(lifecycles))
+	},
+}
+
+// newClaimableBalanceLifecycleProcessor returns a processor that writes the claimable balance events of the
+// transactions of each ledger, followed by the balances that expired by the time it closed
+func newClaimableBalanceLifecycleProcessor(lifecycles transform.ClaimableBalanceLifecycles) processLedgerFunc {
+	return func(lcm xdr.LedgerCloseMeta, env utils.EnvironmentDetails, sink Sink) (int, int) {
+		txInputs, err := input.TransactionsFromLedger(lcm, env.NetworkPassphrase)
+		if err != nil {
+			cmdLogger.LogError(fmt.Errorf("could not read transactions from ledger %d: %v", lcm.LedgerSequence(), err))
+			return 1, 1
+		}
+		attempts, failures := 0, 0
+		for _, txInput := range txInputs {
+			attempts++
+			events, err := lifecycles.AddTransaction(txInput.Transaction, txInput.LedgerHistory)
+			if err != nil {
+				cmdLogger.LogError(fmt.Errorf("could not transform claimable balance events of transaction %d in ledger %d: %v", txInput.Transaction.Index, lcm.LedgerSequence(), err))
+				failures++
+				continue
+			}
+			for _, event := range events {
+				if err := sink.WriteRow(event); err != nil {
+					cmdLogger.LogError(fmt.Errorf("could not export claimable balance event: %v", err))
+					failures++
+				}
+			}
+		}
+
+		expired, err := lifecycles.Expired(lcm.LedgerHeaderHistoryEntry())
+		if err != nil {
+			cmdLogger.LogError(fmt.Errorf("could not find the claimable balances expired in ledger %d: %v", lcm.LedgerSequence(), err))
+			return attempts + 1, failures + 1
+		}
+		for _, event := range expired {
+			if err := sink.WriteRow(event); err != nil {
+				cmdLogger.LogError(fmt.Errorf("could not export claimable balance expiry: %v", err))
+				failures++
+			}
+		}
+		return attempts, failures
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(claimableBalanceLifecycleCmd)
+	utils.AddCommonFlags(claimableBalanceLifecycleCmd.Flags())
+	utils.AddLedgerBatchFlags("claimable_balance_lifecycle", claimableBalanceLifecycleCmd.Flags(), "exported_claimable_balance_lifecycle/")
+	utils.AddCloudStorageFlags(claimableBalanceLifecycleCmd.Flags())
+	utils.AddKafkaFlags(claimableBalanceLifecycleCmd.Flags())
+	utils.AddPostgresFlags(claimableBalanceLifecycleCmd.Flags())
+	claimableBalanceLifecycleCmd.Flags().String("lifecycle-file", "", "Earlier export_claimable_balance_lifecycle output the open balances are carried over from")
+	claimableBalanceLifecycleCmd.MarkFlagRequired("start-ledger")
+	claimableBalanceLifecycleCmd.MarkFlagRequired("end-ledger")
+}
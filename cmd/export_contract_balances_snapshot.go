@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-etl/v2/internal/transform"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+)
+
+var contractBalancesSnapshotCmd = &cobra.Command{
+	Use:   "export_contract_balances_snapshot",
+	Short: "Exports every contract token balance at a checkpoint ledger.",
+	Long: `Exports the balance of every holder of a Stellar Asset Contract or SEP-41
+token at the most recent checkpoint at or before end-ledger, read from the
+bucket list of the history archives. The snapshot is written as one file named
+{checkpoint}-{checkpoint}-contract_balances_snapshot.txt in the output folder,
+and can be combined with the contract_balances dataset of
+export_ledger_entry_changes to follow balances from there.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmdLogger.SetLevel(logrus.InfoLevel)
+		commonArgs := utils.MustCommonFlags(cmd.Flags(), cmdLogger)
+		cmdLogger.StrictExport = commonArgs.StrictExport
+		outputFolder, err := cmd.Flags().GetString("output")
+		if err != nil {
+			cmdLogger.Fatal("could not get output folder: ", err)
+		}
+		cloudStorageBucket, cloudCredentials, cloudProvider := utils.MustCloudStorageFlags(cmd.Flags(), cmdLogger)
+		kafkaArgs := utils.MustKafkaFlags(cmd.Flags(), cmdLogger)
+		postgresArgs := utils.MustPostgresFlags(cmd.Flags(), cmdLogger)
+		env := utils.GetEnvironmentDetails(commonArgs)
+
+		if commonArgs.Stdout {
+			cmdLogger.SetOutput(os.Stderr)
+		} else if err := os.MkdirAll(outputFolder, os.ModePerm); err != nil {
+			cmdLogger.Fatalf("unable to mkdir %s: %v", outputFolder, err)
+		}
+
+		publishers := newKafkaPublishers(kafkaArgs)
+		defer publishers.Close()
+		loader, err := newPostgresLoader(postgresArgs, commonArgs.Extra)
+		if err != nil {
+			cmdLogger.Fatal(err)
+		}
+		defer loader.Close()
+		sinks := newExportSinks(commonArgs, outputFolder, "",
+			cloudUpload{credentials: cloudCredentials, bucket: cloudStorageBucket, provider: cloudProvider}, publishers, loader)
+		defer sinks.Close()
+		sink := sinks.sink("contract_balances_snapshot", nil)
+
+		checkpoint := utils.GetMostRecentCheckpoint(commonArgs.EndNum)
+		archive, err := utils.CreateHistoryArchiveClient(env.ArchiveURLs)
+		if err != nil {
+			cmdLogger.Fatal("could not connect to the history archives: ", err)
+		}
+		header, err := archive.GetLedgerHeader(checkpoint)
+		if err != nil {
+			cmdLogger.Fatal(fmt.Sprintf("could not get the header of checkpoint %d: ", checkpoint), err)
+		}
+		reader, err := ingest.NewCheckpointChangeReader(context.Background(), archive, checkpoint, ingest.WithFilter(
+			func(entry xdr.LedgerEntry) bool { return entry.Data.Type == xdr.LedgerEntryTypeContractData },
+			func(key xdr.LedgerKey) bool { return key.Type == xdr.LedgerEntryTypeContractData },
+		))
+		if err != nil {
+			cmdLogger.Fatal(fmt.Sprintf("could not read the bucket list of checkpoint %d: ", checkpoint), err)
+		}
+		defer reader.Close()
+
+		if err := sink.OpenBatch(checkpoint, checkpoint); err != nil {
+			cmdLogger.Fatalf("could not open batch %d-%d: %v", checkpoint, checkpoint, err)
+		}
+		attempts, failures := 0, 0
+		for {
+			change, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				cmdLogger.Fatal(fmt.Sprintf("could not read the bucket list of checkpoint %d: ", checkpoint), err)
+			}
+
+			balance, ok, err := transform.TransformContractBalance(change, header)
+			if err == nil && !ok {
+				continue
+			}
+			attempts++
+			if err != nil {
+				cmdLogger.LogError(fmt.Errorf("could not transform contract balance: %v", err))
+				failures++
+				continue
+			}
+			if err := sink.WriteRow(balance); err != nil {
+				cmdLogger.LogError(fmt.Errorf("could not export contract balance: %v", err))
+				failures++
+			}
+		}
+		if err := sink.CloseBatch(); err != nil {
+			cmdLogger.Fatalf("could not close batch %d-%d: %v", checkpoint, checkpoint, err)
+		}
+		if err := sink.Commit(); err != nil {
+			cmdLogger.Fatalf("could not commit batch %d-%d: %v", checkpoint, checkpoint, err)
+		}
+		PrintTransformStats(attempts, failures)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(contractBalancesSnapshotCmd)
+	utils.AddCommonFlags(contractBalancesSnapshotCmd.Flags())
+	contractBalancesSnapshotCmd.Flags().StringP("output", "o", "exported_contract_balances_snapshot/", "Folder that will contain the contract balances snapshot output files")
+	utils.AddCloudStorageFlags(contractBalancesSnapshotCmd.Flags())
+	utils.AddKafkaFlags(contractBalancesSnapshotCmd.Flags())
+	utils.AddPostgresFlags(contractBalancesSnapshotCmd.Flags())
+	contractBalancesSnapshotCmd.MarkFlagRequired("end-ledger")
+}
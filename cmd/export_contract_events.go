@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"os"
 
 	"github.com/spf13/cobra"
 	"github.com/stellar/go-stellar-sdk/xdr"
@@ -18,39 +17,56 @@ var contractEventsCmd = &cobra.Command{
 processed in batches of batch-size; each batch produces one file named
 {start}-{end}-contract_events.txt in the output folder.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		runLedgerBatchExport(cmd, "contract_events", new(transform.ContractEventOutputParquet), processContractEvents)
+		specs, err := newContractSpecRegistry(utils.MustContractSpecFlags(cmd.Flags(), cmdLogger))
+		if err != nil {
+			cmdLogger.Fatal(err)
+		}
+		runLedgerBatchExport(cmd, "contract_events", new(transform.ContractEventOutputParquet), newContractEventsProcessor(specs))
 	},
 }
 
-func processContractEvents(lcm xdr.LedgerCloseMeta, env utils.EnvironmentDetails, outFile *os.File, writeParquet bool, extra map[string]string) ([]transform.SchemaParquet, int, int) {
-	txInputs, err := input.TransactionsFromLedger(lcm, env.NetworkPassphrase)
-	if err != nil {
-		cmdLogger.LogError(fmt.Errorf("could not read transactions from ledger %d: %v", lcm.LedgerSequence(), err))
-		return nil, 0, 0
-	}
-	var rows []transform.SchemaParquet
-	attempts, failures := 0, 0
-	for _, txInput := range txInputs {
-		attempts++
-		events, err := transform.TransformContractEvent(txInput.Transaction, txInput.LedgerHistory)
+// newContractEventsProcessor returns a processor that, when specs is not nil,
+// decodes the topics and data of every event with the spec of its contract.
+// The contract code and instances created along the way are added to specs
+// so contracts deployed within the exported range are decoded too.
+func newContractEventsProcessor(specs *transform.ContractSpecRegistry) processLedgerFunc {
+	return func(lcm xdr.LedgerCloseMeta, env utils.EnvironmentDetails, sink Sink) (int, int) {
+		txInputs, err := input.TransactionsFromLedger(lcm, env.NetworkPassphrase)
 		if err != nil {
-			ledgerSeq := txInput.LedgerHistory.Header.LedgerSeq
-			cmdLogger.LogError(fmt.Errorf("could not transform contract events for transaction %d in ledger %d: %v", txInput.Transaction.Index, ledgerSeq, err))
-			failures++
-			continue
+			cmdLogger.LogError(fmt.Errorf("could not read transactions from ledger %d: %v", lcm.LedgerSequence(), err))
+			return 0, 0
 		}
-		for _, event := range events {
-			if _, err := ExportEntry(event, outFile, extra); err != nil {
-				cmdLogger.LogError(fmt.Errorf("could not export contract event: %v", err))
+		attempts, failures := 0, 0
+		for _, txInput := range txInputs {
+			attempts++
+			ledgerSeq := txInput.LedgerHistory.Header.LedgerSeq
+			events, err := transform.TransformContractEvent(txInput.Transaction, txInput.LedgerHistory)
+			if err != nil {
+				cmdLogger.LogError(fmt.Errorf("could not transform contract events for transaction %d in ledger %d: %v", txInput.Transaction.Index, ledgerSeq, err))
 				failures++
 				continue
 			}
-			if writeParquet {
-				rows = append(rows, event)
+			if specs != nil {
+				changes, err := txInput.Transaction.GetChanges()
+				if err != nil {
+					cmdLogger.LogError(fmt.Errorf("could not read changes of transaction %d in ledger %d: %v", txInput.Transaction.Index, ledgerSeq, err))
+				}
+				learnContractSpecs(specs, changes)
+			}
+			for _, event := range events {
+				if specs != nil {
+					if err := transform.DecodeContractEventWithSpec(&event, specs.Spec(event.ContractId)); err != nil {
+						cmdLogger.LogError(fmt.Errorf("could not decode contract event of transaction %d in ledger %d with its spec: %v", txInput.Transaction.Index, ledgerSeq, err))
+					}
+				}
+				if err := sink.WriteRow(event); err != nil {
+					cmdLogger.LogError(fmt.Errorf("could not export contract event: %v", err))
+					failures++
+				}
 			}
 		}
+		return attempts, failures
 	}
-	return rows, attempts, failures
 }
 
 func init() {
@@ -58,6 +74,9 @@ func init() {
 	utils.AddCommonFlags(contractEventsCmd.Flags())
 	utils.AddLedgerBatchFlags("contract_events", contractEventsCmd.Flags(), "exported_contract_events/")
 	utils.AddCloudStorageFlags(contractEventsCmd.Flags())
+	utils.AddKafkaFlags(contractEventsCmd.Flags())
+	utils.AddPostgresFlags(contractEventsCmd.Flags())
+	utils.AddContractSpecFlags(contractEventsCmd.Flags())
 	contractEventsCmd.MarkFlagRequired("start-ledger")
 	contractEventsCmd.MarkFlagRequired("end-ledger")
 }
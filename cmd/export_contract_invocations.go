@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-etl/v2/internal/input"
+	"github.com/stellar/stellar-etl/v2/internal/transform"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+)
+
+var contractInvocationsCmd = &cobra.Command{
+	Use:   "export_contract_invocations",
+	Short: "Exports the call trees of the contract invocations over a specified range.",
+	Long: `Exports one row per contract (sub)invocation of every Soroban transaction
+over a specified range. Cross-contract calls are rebuilt from the fn_call and
+fn_return diagnostic events, so ledgers closed without diagnostic events only
+produce the top level invocation. Ledgers are processed in batches of
+batch-size; each batch produces one file named
+{start}-{end}-contract_invocations.txt in the output folder.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		specs, err := newContractSpecRegistry(utils.MustContractSpecFlags(cmd.Flags(), cmdLogger))
+		if err != nil {
+			cmdLogger.Fatal(err)
+		}
+		runLedgerBatchExport(cmd, "contract_invocations", nil, newContractInvocationsProcessor(specs))
+	},
+}
+
+// newContractInvocationsProcessor returns a processor that, when specs is not
+// nil, decodes the arguments and return values of every invocation with the
+// spec of the invoked contract.
+func newContractInvocationsProcessor(specs *transform.ContractSpecRegistry) processLedgerFunc {
+	return func(lcm xdr.LedgerCloseMeta, env utils.EnvironmentDetails, sink Sink) (int, int) {
+		txInputs, err := input.TransactionsFromLedger(lcm, env.NetworkPassphrase)
+		if err != nil {
+			cmdLogger.LogError(fmt.Errorf("could not read transactions from ledger %d: %v", lcm.LedgerSequence(), err))
+			return 0, 0
+		}
+		attempts, failures := 0, 0
+		for _, txInput := range txInputs {
+			if !txInput.Transaction.IsSorobanTx() {
+				continue
+			}
+			attempts++
+			ledgerSeq := txInput.LedgerHistory.Header.LedgerSeq
+			invocations, err := transform.TransformContractInvocations(txInput.Transaction, txInput.LedgerHistory)
+			if err != nil {
+				cmdLogger.LogError(fmt.Errorf("could not transform contract invocations for transaction %d in ledger %d: %v", txInput.Transaction.Index, ledgerSeq, err))
+				failures++
+				continue
+			}
+			if specs != nil {
+				changes, err := txInput.Transaction.GetChanges()
+				if err != nil {
+					cmdLogger.LogError(fmt.Errorf("could not read changes of transaction %d in ledger %d: %v", txInput.Transaction.Index, ledgerSeq, err))
+				}
+				learnContractSpecs(specs, changes)
+			}
+			// Attempts are counted per transaction, so a transaction fails once however many of its rows do
+			failed := false
+			for _, invocation := range invocations {
+				if specs != nil {
+					if err := transform.DecodeContractInvocationWithSpec(&invocation, specs.Spec(invocation.ContractId)); err != nil {
+						cmdLogger.LogError(fmt.Errorf("could not decode contract invocation of transaction %d in ledger %d with its spec: %v", txInput.Transaction.Index, ledgerSeq, err))
+					}
+				}
+				if err := sink.WriteRow(invocation); err != nil {
+					cmdLogger.LogError(fmt.Errorf("could not export contract invocation: %v", err))
+					failed = true
+				}
+			}
+			if failed {
+				failures++
+			}
+		}
+		return attempts, failures
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(contractInvocationsCmd)
+	utils.AddCommonFlags(contractInvocationsCmd.Flags())
+	utils.AddLedgerBatchFlags("contract_invocations", contractInvocationsCmd.Flags(), "exported_contract_invocations/")
+	utils.AddCloudStorageFlags(contractInvocationsCmd.Flags())
+	utils.AddKafkaFlags(contractInvocationsCmd.Flags())
+	utils.AddPostgresFlags(contractInvocationsCmd.Flags())
+	utils.AddContractSpecFlags(contractInvocationsCmd.Flags())
+	contractInvocationsCmd.MarkFlagRequired("start-ledger")
+	contractInvocationsCmd.MarkFlagRequired("end-ledger")
+}
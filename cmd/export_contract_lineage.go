@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-etl/v2/internal/input"
+	"github.com/stellar/stellar-etl/v2/internal/transform"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+)
+
+var contractLineageCmd = &cobra.Command{
+	Use:   "export_contract_lineage",
+	Short: "Exports the deployments and upgrades of contracts over a specified range.",
+	Long: `Exports one row per contract instance created over a specified range,
+with the preimage it was created from and its initial executable, and one row
+per later change of the executable of an instance. The preimage is only known
+for contracts created by the host function or an authorization entry of the
+transaction; contracts deployed by another contract with its own address,
+such as those of factory contracts, have no deployer, salt or constructor
+arguments. Ledgers are processed in batches of batch-size; each batch
+produces one file named {start}-{end}-contract_lineage.txt in the output
+folder.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runLedgerBatchExport(cmd, "contract_lineage", nil, processContractLineage)
+	},
+}
+
+func processContractLineage(lcm xdr.LedgerCloseMeta, env utils.EnvironmentDetails, sink Sink) (int, int) {
+	txInputs, err := input.TransactionsFromLedger(lcm, env.NetworkPassphrase)
+	if err != nil {
+		cmdLogger.LogError(fmt.Errorf("could not read transactions from ledger %d: %v", lcm.LedgerSequence(), err))
+		return 0, 0
+	}
+	attempts, failures := 0, 0
+	for _, txInput := range txInputs {
+		if !txInput.Transaction.IsSorobanTx() {
+			continue
+		}
+		attempts++
+		lineage, err := transform.TransformContractLineage(txInput.Transaction, txInput.LedgerHistory, env.NetworkPassphrase)
+		if err != nil {
+			ledgerSeq := txInput.LedgerHistory.Header.LedgerSeq
+			cmdLogger.LogError(fmt.Errorf("could not transform contract lineage for transaction %d in ledger %d: %v", txInput.Transaction.Index, ledgerSeq, err))
+			failures++
+			continue
+		}
+		for _, event := range lineage {
+			if err := sink.WriteRow(event); err != nil {
+				cmdLogger.LogError(fmt.Errorf("could not export contract lineage: %v", err))
+				failures++
+			}
+		}
+	}
+	return attempts, failures
+}
+
+func init() {
+	rootCmd.AddCommand(contractLineageCmd)
+	utils.AddCommonFlags(contractLineageCmd.Flags())
+	utils.AddLedgerBatchFlags("contract_lineage", contractLineageCmd.Flags(), "exported_contract_lineage/")
+	utils.AddCloudStorageFlags(contractLineageCmd.Flags())
+	utils.AddKafkaFlags(contractLineageCmd.Flags())
+	utils.AddPostgresFlags(contractLineageCmd.Flags())
+	contractLineageCmd.MarkFlagRequired("start-ledger")
+	contractLineageCmd.MarkFlagRequired("end-ledger")
+}
@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"os"
 
 	"github.com/spf13/cobra"
 	"github.com/stellar/go-stellar-sdk/xdr"
@@ -22,13 +21,12 @@ processed in batches of batch-size; each batch produces one file named
 	},
 }
 
-func processEffects(lcm xdr.LedgerCloseMeta, env utils.EnvironmentDetails, outFile *os.File, writeParquet bool, extra map[string]string) ([]transform.SchemaParquet, int, int) {
+func processEffects(lcm xdr.LedgerCloseMeta, env utils.EnvironmentDetails, sink Sink) (int, int) {
 	txInputs, err := input.TransactionsFromLedger(lcm, env.NetworkPassphrase)
 	if err != nil {
 		cmdLogger.LogError(fmt.Errorf("could not read transactions from ledger %d: %v", lcm.LedgerSequence(), err))
-		return nil, 0, 0
+		return 0, 0
 	}
-	var rows []transform.SchemaParquet
 	attempts, failures := 0, 0
 	for _, txInput := range txInputs {
 		attempts++
@@ -40,17 +38,13 @@ func processEffects(lcm xdr.LedgerCloseMeta, env utils.EnvironmentDetails, outFi
 			continue
 		}
 		for _, effect := range effects {
-			if _, err := ExportEntry(effect, outFile, extra); err != nil {
+			if err := sink.WriteRow(effect); err != nil {
 				cmdLogger.LogError(fmt.Errorf("could not export effect: %v", err))
 				failures++
-				continue
-			}
-			if writeParquet {
-				rows = append(rows, effect)
 			}
 		}
 	}
-	return rows, attempts, failures
+	return attempts, failures
 }
 
 func init() {
@@ -58,5 +52,7 @@ func init() {
 	utils.AddCommonFlags(effectsCmd.Flags())
 	utils.AddLedgerBatchFlags("effects", effectsCmd.Flags(), "exported_effects/")
 	utils.AddCloudStorageFlags(effectsCmd.Flags())
+	utils.AddKafkaFlags(effectsCmd.Flags())
+	utils.AddPostgresFlags(effectsCmd.Flags())
 	effectsCmd.MarkFlagRequired("end-ledger")
 }
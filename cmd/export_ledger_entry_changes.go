@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"math"
 	"os"
-	"path/filepath"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -37,11 +36,19 @@ be exported.`,
 		_, configPath, startNum, batchSize, outputFolder, parquetOutputFolder := utils.MustCoreFlags(cmd.Flags(), cmdLogger)
 		exports := utils.MustExportTypeFlags(cmd.Flags(), cmdLogger)
 		cloudStorageBucket, cloudCredentials, cloudProvider := utils.MustCloudStorageFlags(cmd.Flags(), cmdLogger)
+		kafkaArgs := utils.MustKafkaFlags(cmd.Flags(), cmdLogger)
+		postgresArgs := utils.MustPostgresFlags(cmd.Flags(), cmdLogger)
+		specs, err := newContractSpecRegistry(utils.MustContractSpecFlags(cmd.Flags(), cmdLogger))
+		if err != nil {
+			cmdLogger.Fatal(err)
+		}
 
 		cmd.Flags()
 
-		err := os.MkdirAll(outputFolder, os.ModePerm)
-		if err != nil {
+		if commonArgs.Stdout {
+			// Rows go to stdout, so keep it free of anything else
+			cmdLogger.SetOutput(os.Stderr)
+		} else if err := os.MkdirAll(outputFolder, os.ModePerm); err != nil {
 			cmdLogger.Fatalf("unable to mkdir %s: %v", outputFolder, err)
 		}
 
@@ -58,6 +65,28 @@ be exported.`,
 			cmdLogger.Fatal("stellar-core needs a config file path when exporting ledgers continuously (endNum = 0)")
 		}
 
+		publishers := newKafkaPublishers(kafkaArgs)
+		defer publishers.Close()
+		var resources []string
+		for flagName, outputKeys := range changeExportMapping {
+			if exports[flagName] {
+				resources = append(resources, outputKeys...)
+			}
+		}
+		startNum = publishers.resumeLedger(resources, startNum)
+
+		loader, err := newPostgresLoader(postgresArgs, commonArgs.Extra)
+		if err != nil {
+			cmdLogger.Fatal(err)
+		}
+		defer loader.Close()
+
+		sinks := newExportSinks(commonArgs, outputFolder, parquetOutputFolder,
+			cloudUpload{credentials: cloudCredentials, bucket: cloudStorageBucket, provider: cloudProvider}, publishers, loader)
+		// Rows of every resource share stdout, so each row names its dataset
+		sinks.tagDatasets = true
+		defer sinks.Close()
+
 		ctx := context.Background()
 		backend, err := utils.CreateLedgerBackend(ctx, commonArgs.UseCaptiveCore, env)
 		if err != nil {
@@ -85,291 +114,313 @@ be exported.`,
 					continue
 				}
 
-				transformedOutputs := map[string][]interface{}{}
-
-				exportMapping := map[string][]string{
-					"export-accounts":        {"accounts", "signers"},
-					"export-balances":        {"claimable_balances"},
-					"export-offers":          {"offers"},
-					"export-trustlines":      {"trustlines"},
-					"export-pools":           {"liquidity_pools"},
-					"export-contract-data":   {"contract_data"},
-					"export-contract-code":   {"contract_code"},
-					"export-config-settings": {"config_settings"},
-					"export-ttl":             {"ttl"},
-					"export-restored-keys":   {"restored_key"},
+				transformedOutputs := transformChangeBatch(batch, exports, env, specs)
+
+				err := exportTransformedData(
+					batch.BatchStart,
+					batch.BatchEnd,
+					transformedOutputs,
+					sinks,
+				)
+				if err != nil {
+					cmdLogger.LogError(err)
+					continue
 				}
+			}
+		}
+	},
+}
+
+// changeExportMapping maps each export-{type} flag to the resources it writes
+var changeExportMapping = map[string][]string{
+	"export-accounts":          {"accounts", "signers"},
+	"export-account-security":  {"account_security"},
+	"export-balances":          {"claimable_balances"},
+	"export-offers":            {"offers"},
+	"export-trustlines":        {"trustlines"},
+	"export-pools":             {"liquidity_pools"},
+	"export-contract-data":     {"contract_data"},
+	"export-contract-code":     {"contract_code"},
+	"export-contract-specs":    {"contract_specs"},
+	"export-contract-balances": {"contract_balances"},
+	"export-config-settings":   {"config_settings"},
+	"export-ttl":               {"ttl"},
+	"export-restored-keys":     {"restored_key"},
+}
 
-				for flagName, outputKeys := range exportMapping {
-					if exports[flagName] {
-						for _, key := range outputKeys {
-							transformedOutputs[key] = []interface{}{}
-						}
+// transformChangeBatch transforms every change in batch for the data types
+// enabled in exports, keyed by the resource name used in the output filename.
+// Resources that are enabled but saw no changes map to an empty slice so that
+// an (empty) output file is still written for them. If specs is not nil,
+// contract data is decoded with the spec of its contract.
+func transformChangeBatch(batch input.ChangeBatch, exports map[string]bool, env utils.EnvironmentDetails, specs *transform.ContractSpecRegistry) map[string][]interface{} {
+	transformedOutputs := map[string][]interface{}{}
+
+	for flagName, outputKeys := range changeExportMapping {
+		if exports[flagName] {
+			for _, key := range outputKeys {
+				transformedOutputs[key] = []interface{}{}
+			}
+		}
+	}
+
+	if exports["export-contract-data"] {
+		learnContractSpecs(specs, batch.Changes[xdr.LedgerEntryTypeContractCode].Changes)
+		learnContractSpecs(specs, batch.Changes[xdr.LedgerEntryTypeContractData].Changes)
+	}
+
+	for entryType, changes := range batch.Changes {
+		if exports["export-restored-keys"] {
+			for i, change := range changes.Changes {
+				entry, changeType, _, err := utils.ExtractEntryFromChange(change)
+
+				if changeType != xdr.LedgerEntryChangeTypeLedgerEntryRestored {
+					continue
+				}
+
+				key, err := transform.TransformRestoredKey(change, changes.LedgerHeaders[i])
+				if err != nil {
+					cmdLogger.LogError(fmt.Errorf("error transforming restored key entry last updated at %d: %s", entry.LastModifiedLedgerSeq, err))
+					continue
+				}
+				transformedOutputs["restored_key"] = append(transformedOutputs["restored_key"], key)
+			}
+		}
+
+		switch entryType {
+		case xdr.LedgerEntryTypeAccount:
+			if !exports["export-accounts"] && !exports["export-account-security"] {
+				continue
+			}
+			for i, change := range changes.Changes {
+				if exports["export-account-security"] {
+					security, ok, err := transform.TransformAccountSecurity(change, changes.LedgerHeaders[i])
+					if err != nil {
+						entry, _, _, _ := utils.ExtractEntryFromChange(change)
+						cmdLogger.LogError(fmt.Errorf("error transforming account security of entry last updated at %d: %s", entry.LastModifiedLedgerSeq, err))
+					} else if ok {
+						transformedOutputs["account_security"] = append(transformedOutputs["account_security"], security)
 					}
 				}
+				if !exports["export-accounts"] {
+					continue
+				}
+				if changed, err := change.AccountChangedExceptSigners(); err != nil {
+					cmdLogger.LogError(fmt.Errorf("unable to identify changed accounts: %v", err))
+					continue
+				} else if changed {
 
-				for entryType, changes := range batch.Changes {
-					if exports["export-restored-keys"] {
-						for i, change := range changes.Changes {
-							entry, changeType, _, err := utils.ExtractEntryFromChange(change)
-
-							if changeType != xdr.LedgerEntryChangeTypeLedgerEntryRestored {
-								continue
-							}
-
-							key, err := transform.TransformRestoredKey(change, changes.LedgerHeaders[i])
-							if err != nil {
-								cmdLogger.LogError(fmt.Errorf("error transforming restored key entry last updated at %d: %s", entry.LastModifiedLedgerSeq, err))
-								continue
-							}
-							transformedOutputs["restored_key"] = append(transformedOutputs["restored_key"], key)
-						}
+					acc, err := transform.TransformAccount(change, changes.LedgerHeaders[i])
+					if err != nil {
+						entry, _, _, _ := utils.ExtractEntryFromChange(change)
+						cmdLogger.LogError(fmt.Errorf("error transforming account entry last updated at %d: %s", entry.LastModifiedLedgerSeq, err))
+						continue
+					}
+					transformedOutputs["accounts"] = append(transformedOutputs["accounts"], acc)
+				}
+				if utils.AccountSignersChanged(change) {
+					signers, err := transform.TransformSigners(change, changes.LedgerHeaders[i])
+					if err != nil {
+						entry, _, _, _ := utils.ExtractEntryFromChange(change)
+						cmdLogger.LogError(fmt.Errorf("error transforming account signers from %d :%s", entry.LastModifiedLedgerSeq, err))
+						continue
+					}
+					for _, s := range signers {
+						transformedOutputs["signers"] = append(transformedOutputs["signers"], s)
+					}
+				}
+			}
+		case xdr.LedgerEntryTypeClaimableBalance:
+			if !exports["export-balances"] {
+				continue
+			}
+			for i, change := range changes.Changes {
+				balance, err := transform.TransformClaimableBalance(change, changes.LedgerHeaders[i])
+				if err != nil {
+					entry, _, _, _ := utils.ExtractEntryFromChange(change)
+					cmdLogger.LogError(fmt.Errorf("error transforming balance entry last updated at %d: %s", entry.LastModifiedLedgerSeq, err))
+					continue
+				}
+				transformedOutputs["claimable_balances"] = append(transformedOutputs["claimable_balances"], balance)
+			}
+		case xdr.LedgerEntryTypeOffer:
+			if !exports["export-offers"] {
+				continue
+			}
+			for i, change := range changes.Changes {
+				offer, err := transform.TransformOffer(change, changes.LedgerHeaders[i])
+				if err != nil {
+					entry, _, _, _ := utils.ExtractEntryFromChange(change)
+					cmdLogger.LogError(fmt.Errorf("error transforming offer entry last updated at %d: %s", entry.LastModifiedLedgerSeq, err))
+					continue
+				}
+				transformedOutputs["offers"] = append(transformedOutputs["offers"], offer)
+			}
+		case xdr.LedgerEntryTypeTrustline:
+			if !exports["export-trustlines"] {
+				continue
+			}
+			for i, change := range changes.Changes {
+				trust, err := transform.TransformTrustline(change, changes.LedgerHeaders[i])
+				if err != nil {
+					entry, _, _, _ := utils.ExtractEntryFromChange(change)
+					cmdLogger.LogError(fmt.Errorf("error transforming trustline entry last updated at %d: %s", entry.LastModifiedLedgerSeq, err))
+					continue
+				}
+				transformedOutputs["trustlines"] = append(transformedOutputs["trustlines"], trust)
+			}
+		case xdr.LedgerEntryTypeLiquidityPool:
+			if !exports["export-pools"] {
+				continue
+			}
+			for i, change := range changes.Changes {
+				pool, err := transform.TransformPool(change, changes.LedgerHeaders[i])
+				if err != nil {
+					entry, _, _, _ := utils.ExtractEntryFromChange(change)
+					cmdLogger.LogError(fmt.Errorf("error transforming liquidity pool entry last updated at %d: %s", entry.LastModifiedLedgerSeq, err))
+					continue
+				}
+				transformedOutputs["liquidity_pools"] = append(transformedOutputs["liquidity_pools"], pool)
+			}
+		case xdr.LedgerEntryTypeContractData:
+			if !exports["export-contract-data"] && !exports["export-contract-balances"] {
+				continue
+			}
+			for i, change := range changes.Changes {
+				if exports["export-contract-balances"] {
+					balance, ok, err := transform.TransformContractBalance(change, changes.LedgerHeaders[i])
+					if err != nil {
+						entry, _, _, _ := utils.ExtractEntryFromChange(change)
+						cmdLogger.LogError(fmt.Errorf("error transforming contract balance of entry last updated at %d: %s", entry.LastModifiedLedgerSeq, err))
+					} else if ok {
+						transformedOutputs["contract_balances"] = append(transformedOutputs["contract_balances"], balance)
 					}
+				}
+				if !exports["export-contract-data"] {
+					continue
+				}
+				TransformContractData := transform.NewTransformContractDataStruct(transform.AssetFromContractData, transform.ContractBalanceFromContractData)
+				contractData, err, _ := TransformContractData.TransformContractData(change, env.NetworkPassphrase, changes.LedgerHeaders[i])
+				if err != nil {
+					entry, _, _, _ := utils.ExtractEntryFromChange(change)
+					cmdLogger.LogError(fmt.Errorf("error transforming contract data entry last updated at %d: %s", entry.LastModifiedLedgerSeq, err))
+					continue
+				}
 
-					switch entryType {
-					case xdr.LedgerEntryTypeAccount:
-						if !exports["export-accounts"] {
-							continue
-						}
-						for i, change := range changes.Changes {
-							if changed, err := change.AccountChangedExceptSigners(); err != nil {
-								cmdLogger.LogError(fmt.Errorf("unable to identify changed accounts: %v", err))
-								continue
-							} else if changed {
-
-								acc, err := transform.TransformAccount(change, changes.LedgerHeaders[i])
-								if err != nil {
-									entry, _, _, _ := utils.ExtractEntryFromChange(change)
-									cmdLogger.LogError(fmt.Errorf("error transforming account entry last updated at %d: %s", entry.LastModifiedLedgerSeq, err))
-									continue
-								}
-								transformedOutputs["accounts"] = append(transformedOutputs["accounts"], acc)
-							}
-							if utils.AccountSignersChanged(change) {
-								signers, err := transform.TransformSigners(change, changes.LedgerHeaders[i])
-								if err != nil {
-									entry, _, _, _ := utils.ExtractEntryFromChange(change)
-									cmdLogger.LogError(fmt.Errorf("error transforming account signers from %d :%s", entry.LastModifiedLedgerSeq, err))
-									continue
-								}
-								for _, s := range signers {
-									transformedOutputs["signers"] = append(transformedOutputs["signers"], s)
-								}
-							}
-						}
-					case xdr.LedgerEntryTypeClaimableBalance:
-						if !exports["export-balances"] {
-							continue
-						}
-						for i, change := range changes.Changes {
-							balance, err := transform.TransformClaimableBalance(change, changes.LedgerHeaders[i])
-							if err != nil {
-								entry, _, _, _ := utils.ExtractEntryFromChange(change)
-								cmdLogger.LogError(fmt.Errorf("error transforming balance entry last updated at %d: %s", entry.LastModifiedLedgerSeq, err))
-								continue
-							}
-							transformedOutputs["claimable_balances"] = append(transformedOutputs["claimable_balances"], balance)
-						}
-					case xdr.LedgerEntryTypeOffer:
-						if !exports["export-offers"] {
-							continue
-						}
-						for i, change := range changes.Changes {
-							offer, err := transform.TransformOffer(change, changes.LedgerHeaders[i])
-							if err != nil {
-								entry, _, _, _ := utils.ExtractEntryFromChange(change)
-								cmdLogger.LogError(fmt.Errorf("error transforming offer entry last updated at %d: %s", entry.LastModifiedLedgerSeq, err))
-								continue
-							}
-							transformedOutputs["offers"] = append(transformedOutputs["offers"], offer)
-						}
-					case xdr.LedgerEntryTypeTrustline:
-						if !exports["export-trustlines"] {
-							continue
-						}
-						for i, change := range changes.Changes {
-							trust, err := transform.TransformTrustline(change, changes.LedgerHeaders[i])
-							if err != nil {
-								entry, _, _, _ := utils.ExtractEntryFromChange(change)
-								cmdLogger.LogError(fmt.Errorf("error transforming trustline entry last updated at %d: %s", entry.LastModifiedLedgerSeq, err))
-								continue
-							}
-							transformedOutputs["trustlines"] = append(transformedOutputs["trustlines"], trust)
-						}
-					case xdr.LedgerEntryTypeLiquidityPool:
-						if !exports["export-pools"] {
-							continue
-						}
-						for i, change := range changes.Changes {
-							pool, err := transform.TransformPool(change, changes.LedgerHeaders[i])
-							if err != nil {
-								entry, _, _, _ := utils.ExtractEntryFromChange(change)
-								cmdLogger.LogError(fmt.Errorf("error transforming liquidity pool entry last updated at %d: %s", entry.LastModifiedLedgerSeq, err))
-								continue
-							}
-							transformedOutputs["liquidity_pools"] = append(transformedOutputs["liquidity_pools"], pool)
-						}
-					case xdr.LedgerEntryTypeContractData:
-						if !exports["export-contract-data"] {
-							continue
-						}
-						for i, change := range changes.Changes {
-							TransformContractData := transform.NewTransformContractDataStruct(transform.AssetFromContractData, transform.ContractBalanceFromContractData)
-							contractData, err, _ := TransformContractData.TransformContractData(change, env.NetworkPassphrase, changes.LedgerHeaders[i])
-							if err != nil {
-								entry, _, _, _ := utils.ExtractEntryFromChange(change)
-								cmdLogger.LogError(fmt.Errorf("error transforming contract data entry last updated at %d: %s", entry.LastModifiedLedgerSeq, err))
-								continue
-							}
-
-							// Empty contract data that has no error is a nonce. Does not need to be recorded
-							if contractData.ContractId == "" {
-								continue
-							}
-
-							transformedOutputs["contract_data"] = append(transformedOutputs["contract_data"], contractData)
-						}
-					case xdr.LedgerEntryTypeContractCode:
-						if !exports["export-contract-code"] {
-							continue
-						}
-						for i, change := range changes.Changes {
-							contractCode, err := transform.TransformContractCode(change, changes.LedgerHeaders[i])
-							if err != nil {
-								entry, _, _, _ := utils.ExtractEntryFromChange(change)
-								cmdLogger.LogError(fmt.Errorf("error transforming contract code entry last updated at %d: %s", entry.LastModifiedLedgerSeq, err))
-								continue
-							}
-							transformedOutputs["contract_code"] = append(transformedOutputs["contract_code"], contractCode)
-						}
-					case xdr.LedgerEntryTypeConfigSetting:
-						if !exports["export-config-settings"] {
-							continue
-						}
-						for i, change := range changes.Changes {
-							configSettings, err := transform.TransformConfigSetting(change, changes.LedgerHeaders[i])
-							if err != nil {
-								entry, _, _, _ := utils.ExtractEntryFromChange(change)
-								cmdLogger.LogError(fmt.Errorf("error transforming config settings entry last updated at %d: %s", entry.LastModifiedLedgerSeq, err))
-								continue
-							}
-							transformedOutputs["config_settings"] = append(transformedOutputs["config_settings"], configSettings)
-						}
-					case xdr.LedgerEntryTypeTtl:
-						if !exports["export-ttl"] {
-							continue
-						}
-						for i, change := range changes.Changes {
-							ttl, err := transform.TransformTtl(change, changes.LedgerHeaders[i])
-							if err != nil {
-								entry, _, _, _ := utils.ExtractEntryFromChange(change)
-								cmdLogger.LogError(fmt.Errorf("error transforming ttl entry last updated at %d: %s", entry.LastModifiedLedgerSeq, err))
-								continue
-							}
-							transformedOutputs["ttl"] = append(transformedOutputs["ttl"], ttl)
-						}
+				// Empty contract data that has no error is a nonce. Does not need to be recorded
+				if contractData.ContractId == "" {
+					continue
+				}
+
+				if specs != nil {
+					if err := transform.DecodeContractDataWithSpec(&contractData, specs.Spec(contractData.ContractId)); err != nil {
+						cmdLogger.LogError(fmt.Errorf("could not decode contract data of %s with its spec: %s", contractData.ContractId, err))
 					}
 				}
 
-				err := exportTransformedData(
-					batch.BatchStart,
-					batch.BatchEnd,
-					outputFolder,
-					parquetOutputFolder,
-					transformedOutputs,
-					cloudCredentials,
-					cloudStorageBucket,
-					cloudProvider,
-					commonArgs.Extra,
-					commonArgs.WriteParquet,
-				)
+				transformedOutputs["contract_data"] = append(transformedOutputs["contract_data"], contractData)
+			}
+		case xdr.LedgerEntryTypeContractCode:
+			if !exports["export-contract-code"] && !exports["export-contract-specs"] {
+				continue
+			}
+			for i, change := range changes.Changes {
+				if exports["export-contract-code"] {
+					contractCode, err := transform.TransformContractCode(change, changes.LedgerHeaders[i])
+					if err != nil {
+						entry, _, _, _ := utils.ExtractEntryFromChange(change)
+						cmdLogger.LogError(fmt.Errorf("error transforming contract code entry last updated at %d: %s", entry.LastModifiedLedgerSeq, err))
+					} else {
+						transformedOutputs["contract_code"] = append(transformedOutputs["contract_code"], contractCode)
+					}
+				}
+				if exports["export-contract-specs"] {
+					contractSpec, err := transform.TransformContractSpec(change, changes.LedgerHeaders[i])
+					if err != nil {
+						entry, _, _, _ := utils.ExtractEntryFromChange(change)
+						cmdLogger.LogError(fmt.Errorf("error transforming contract spec of entry last updated at %d: %s", entry.LastModifiedLedgerSeq, err))
+					} else {
+						transformedOutputs["contract_specs"] = append(transformedOutputs["contract_specs"], contractSpec)
+					}
+				}
+			}
+		case xdr.LedgerEntryTypeConfigSetting:
+			if !exports["export-config-settings"] {
+				continue
+			}
+			for i, change := range changes.Changes {
+				configSettings, err := transform.TransformConfigSetting(change, changes.LedgerHeaders[i])
 				if err != nil {
-					cmdLogger.LogError(err)
+					entry, _, _, _ := utils.ExtractEntryFromChange(change)
+					cmdLogger.LogError(fmt.Errorf("error transforming config settings entry last updated at %d: %s", entry.LastModifiedLedgerSeq, err))
 					continue
 				}
+				transformedOutputs["config_settings"] = append(transformedOutputs["config_settings"], configSettings)
+			}
+		case xdr.LedgerEntryTypeTtl:
+			if !exports["export-ttl"] {
+				continue
+			}
+			for i, change := range changes.Changes {
+				ttl, err := transform.TransformTtl(change, changes.LedgerHeaders[i])
+				if err != nil {
+					entry, _, _, _ := utils.ExtractEntryFromChange(change)
+					cmdLogger.LogError(fmt.Errorf("error transforming ttl entry last updated at %d: %s", entry.LastModifiedLedgerSeq, err))
+					continue
+				}
+				transformedOutputs["ttl"] = append(transformedOutputs["ttl"], ttl)
 			}
 		}
-	},
+	}
+
+	return transformedOutputs
 }
 
+// changeParquetSchemas maps the resources with Parquet output to their schema.
+// Claimable balances are skipped because ClaimableBalanceOutputParquet uses
+// nested structs that will need to be handled for parquet conversion.
+var changeParquetSchemas = map[string]interface{}{
+	"accounts":        new(transform.AccountOutputParquet),
+	"signers":         new(transform.AccountSignerOutputParquet),
+	"config_settings": new(transform.ConfigSettingOutputParquet),
+	"contract_code":   new(transform.ContractCodeOutputParquet),
+	"contract_data":   new(transform.ContractDataOutputParquet),
+	"liquidity_pools": new(transform.PoolOutputParquet),
+	"offers":          new(transform.OfferOutputParquet),
+	"trustlines":      new(transform.TrustlineOutputParquet),
+	"ttl":             new(transform.TtlOutputParquet),
+}
+
+// exportTransformedData writes the rows of every resource of the batch
+// [start, end] to the resource's sink. Every resource is committed as one
+// unit, since a restart replays the whole batch. A resource whose rows cannot
+// all be written is closed without being committed, so that its partial batch
+// is neither uploaded, published nor loaded.
 func exportTransformedData(
 	start, end uint32,
-	folderPath string,
-	parquetFolderPath string,
 	transformedOutput map[string][]interface{},
-	cloudCredentials, cloudStorageBucket, cloudProvider string,
-	extra map[string]string,
-	writeParquet bool) error {
+	sinks *exportSinks) error {
 
 	for resource, output := range transformedOutput {
+		sink := sinks.sink(resource, changeParquetSchemas[resource])
+		if err := sink.OpenBatch(start, end); err != nil {
+			cmdLogger.Fatalf("could not open %s batch %d-%d: %v", resource, start, end, err)
+		}
 
-		// Filenames are typically exclusive of end point. This processor
-		// is different and we have to increment by 1 since the end batch number
-		// is included in this filename.
-		path := filepath.Join(folderPath, exportFilename(start, end+1, resource))
-		parquetPath := filepath.Join(parquetFolderPath, exportParquetFilename(start, end+1, resource))
-		outFile := MustOutFile(path)
-		var transformedResource []transform.SchemaParquet
-		var parquetSchema interface{}
-		var skip bool
 		for _, o := range output {
-			_, err := ExportEntry(o, outFile, extra)
-			if err != nil {
-				return err
-			}
-
-			if writeParquet {
-				switch v := o.(type) {
-				case transform.AccountOutput:
-					transformedResource = append(transformedResource, v)
-					parquetSchema = new(transform.AccountOutputParquet)
-					skip = false
-				case transform.AccountSignerOutput:
-					transformedResource = append(transformedResource, v)
-					parquetSchema = new(transform.AccountSignerOutputParquet)
-					skip = false
-				case transform.ClaimableBalanceOutput:
-					// Skipping ClaimableBalanceOutputParquet because it is not needed in the current scope of work
-					// Note that ClaimableBalanceOutputParquet uses nested structs that will need to be handled
-					// for parquet conversion
-					skip = true
-				case transform.ConfigSettingOutput:
-					transformedResource = append(transformedResource, v)
-					parquetSchema = new(transform.ConfigSettingOutputParquet)
-					skip = false
-				case transform.ContractCodeOutput:
-					transformedResource = append(transformedResource, v)
-					parquetSchema = new(transform.ContractCodeOutputParquet)
-					skip = false
-				case transform.ContractDataOutput:
-					transformedResource = append(transformedResource, v)
-					parquetSchema = new(transform.ContractDataOutputParquet)
-					skip = false
-				case transform.PoolOutput:
-					transformedResource = append(transformedResource, v)
-					parquetSchema = new(transform.PoolOutputParquet)
-					skip = false
-				case transform.OfferOutput:
-					transformedResource = append(transformedResource, v)
-					parquetSchema = new(transform.OfferOutputParquet)
-					skip = false
-				case transform.TrustlineOutput:
-					transformedResource = append(transformedResource, v)
-					parquetSchema = new(transform.TrustlineOutputParquet)
-					skip = false
-				case transform.TtlOutput:
-					transformedResource = append(transformedResource, v)
-					parquetSchema = new(transform.TtlOutputParquet)
-					skip = false
+			if err := sink.WriteRow(o); err != nil {
+				if closeErr := sink.CloseBatch(); closeErr != nil {
+					cmdLogger.Errorf("could not close %s batch %d-%d: %v", resource, start, end, closeErr)
 				}
+				return fmt.Errorf("could not write %s batch %d-%d: %v", resource, start, end, err)
 			}
 		}
 
-		MaybeUpload(cloudCredentials, cloudStorageBucket, cloudProvider, path)
-
-		if !skip && writeParquet {
-			WriteParquet(transformedResource, parquetPath, parquetSchema)
-			MaybeUpload(cloudCredentials, cloudStorageBucket, cloudProvider, parquetPath)
+		if err := sink.CloseBatch(); err != nil {
+			cmdLogger.Fatalf("could not close %s batch %d-%d: %v", resource, start, end, err)
+		}
+		if err := sink.Commit(); err != nil {
+			cmdLogger.Fatalf("could not commit %s batch %d-%d: %v", resource, start, end, err)
 		}
 	}
 
@@ -382,6 +433,9 @@ func init() {
 	utils.AddCoreFlags(exportLedgerEntryChangesCmd.Flags(), "changes_output/")
 	utils.AddExportTypeFlags(exportLedgerEntryChangesCmd.Flags())
 	utils.AddCloudStorageFlags(exportLedgerEntryChangesCmd.Flags())
+	utils.AddKafkaFlags(exportLedgerEntryChangesCmd.Flags())
+	utils.AddPostgresFlags(exportLedgerEntryChangesCmd.Flags())
+	utils.AddContractSpecFlags(exportLedgerEntryChangesCmd.Flags())
 
 	exportLedgerEntryChangesCmd.MarkFlagRequired("start-ledger")
 	/*
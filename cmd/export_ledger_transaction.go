@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"os"
 
 	"github.com/spf13/cobra"
 	"github.com/stellar/go-stellar-sdk/xdr"
@@ -22,11 +21,11 @@ are processed in batches of batch-size; each batch produces one file named
 	},
 }
 
-func processLedgerTransaction(lcm xdr.LedgerCloseMeta, env utils.EnvironmentDetails, outFile *os.File, _ bool, extra map[string]string) ([]transform.SchemaParquet, int, int) {
+func processLedgerTransaction(lcm xdr.LedgerCloseMeta, env utils.EnvironmentDetails, sink Sink) (int, int) {
 	txInputs, err := input.TransactionsFromLedger(lcm, env.NetworkPassphrase)
 	if err != nil {
 		cmdLogger.LogError(fmt.Errorf("could not read transactions from ledger %d: %v", lcm.LedgerSequence(), err))
-		return nil, 0, 0
+		return 0, 0
 	}
 	attempts, failures := 0, 0
 	for _, txInput := range txInputs {
@@ -38,13 +37,13 @@ func processLedgerTransaction(lcm xdr.LedgerCloseMeta, env utils.EnvironmentDeta
 			failures++
 			continue
 		}
-		if _, err := ExportEntry(transformed, outFile, extra); err != nil {
+		if err := sink.WriteRow(transformed); err != nil {
 			cmdLogger.LogError(fmt.Errorf("could not export ledger_transaction: %v", err))
 			failures++
 			continue
 		}
 	}
-	return nil, attempts, failures
+	return attempts, failures
 }
 
 func init() {
@@ -52,5 +51,7 @@ func init() {
 	utils.AddCommonFlags(ledgerTransactionCmd.Flags())
 	utils.AddLedgerBatchFlags("ledger_transaction", ledgerTransactionCmd.Flags(), "exported_ledger_transaction/")
 	utils.AddCloudStorageFlags(ledgerTransactionCmd.Flags())
+	utils.AddKafkaFlags(ledgerTransactionCmd.Flags())
+	utils.AddPostgresFlags(ledgerTransactionCmd.Flags())
 	ledgerTransactionCmd.MarkFlagRequired("end-ledger")
 }
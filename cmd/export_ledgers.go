@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"os"
 
 	"github.com/spf13/cobra"
 	"github.com/stellar/go-stellar-sdk/xdr"
@@ -22,21 +21,18 @@ processed in batches of batch-size; each batch produces one file named
 	},
 }
 
-func processLedger(lcm xdr.LedgerCloseMeta, _ utils.EnvironmentDetails, outFile *os.File, writeParquet bool, extra map[string]string) ([]transform.SchemaParquet, int, int) {
+func processLedger(lcm xdr.LedgerCloseMeta, _ utils.EnvironmentDetails, sink Sink) (int, int) {
 	ledger := input.HistoryArchiveLedgerFromLCM(lcm)
 	transformed, err := transform.TransformLedger(ledger, lcm)
 	if err != nil {
 		cmdLogger.LogError(fmt.Errorf("could not transform ledger %d: %v", lcm.LedgerSequence(), err))
-		return nil, 1, 1
+		return 1, 1
 	}
-	if _, err := ExportEntry(transformed, outFile, extra); err != nil {
+	if err := sink.WriteRow(transformed); err != nil {
 		cmdLogger.LogError(fmt.Errorf("could not export ledger %d: %v", lcm.LedgerSequence(), err))
-		return nil, 1, 1
+		return 1, 1
 	}
-	if writeParquet {
-		return []transform.SchemaParquet{transformed}, 1, 0
-	}
-	return nil, 1, 0
+	return 1, 0
 }
 
 func init() {
@@ -44,5 +40,7 @@ func init() {
 	utils.AddCommonFlags(ledgersCmd.Flags())
 	utils.AddLedgerBatchFlags("ledgers", ledgersCmd.Flags(), "exported_ledgers/")
 	utils.AddCloudStorageFlags(ledgersCmd.Flags())
+	utils.AddKafkaFlags(ledgersCmd.Flags())
+	utils.AddPostgresFlags(ledgersCmd.Flags())
 	ledgersCmd.MarkFlagRequired("end-ledger")
 }
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-etl/v2/internal/input"
+	"github.com/stellar/stellar-etl/v2/internal/transform"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+)
+
+var liquidityPoolMetricsCmd = &cobra.Command{
+	Use:   "export_liquidity_pool_metrics",
+	Short: "Exports per pool reserves, volume and fees over a specified range.",
+	Long: `Exports one row per liquidity pool and time window of the given resolution
+in which the pool changed, with its reserves, shares, implied price and value
+locked at the end of the window, along with the volume traded against it, the
+fees it earned and the deposits and withdrawals it received. A window is
+written once the first ledger after it closes, and the windows still open are
+written with the last ledger of the range. Ledgers are processed in batches of
+batch-size; each batch produces one file named
+{start}-{end}-liquidity_pool_metrics.txt in the output folder.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		resolution, err := cmd.Flags().GetString("resolution")
+		if err != nil {
+			cmdLogger.Fatal("could not get resolution: ", err)
+		}
+		aggregator, err := transform.NewPoolMetricsAggregator(resolution)
+		if err != nil {
+			cmdLogger.Fatal("could not create liquidity pool metrics aggregator: ", err)
+		}
+		endNum, err := cmd.Flags().GetUint32("end-ledger")
+		if err != nil {
+			cmdLogger.Fatal("could not get end sequence number: ", err)
+		}
+		runLedgerBatchExport(cmd, "liquidity_pool_metrics", nil, newLiquidityPoolMetricsProcessor(aggregator, endNum))
+	},
+}
+
+// newLiquidityPoolMetricsProcessor returns a processor that adds the transactions of each ledger to aggregator, writing
+// the windows closed by the ledger, and every remaining window once endNum is reached
+func newLiquidityPoolMetricsProcessor(aggregator *transform.PoolMetricsAggregator, endNum uint32) processLedgerFunc {
+	return func(lcm xdr.LedgerCloseMeta, env utils.EnvironmentDetails, sink Sink) (int, int) {
+		attempts, failures := 0, 0
+		write := func(metrics []transform.LiquidityPoolMetricOutput) {
+			for _, metric := range metrics {
+				if err := sink.WriteRow(metric); err != nil {
+					cmdLogger.LogError(fmt.Errorf("could not export liquidity pool metric: %v", err))
+					failures++
+				}
+			}
+		}
+
+		closeTime, err := utils.TimePointToUTCTimeStamp(lcm.LedgerHeaderHistoryEntry().Header.ScpValue.CloseTime)
+		if err != nil {
+			cmdLogger.LogError(fmt.Errorf("could not read close time of ledger %d: %v", lcm.LedgerSequence(), err))
+			return 1, 1
+		}
+		write(aggregator.Closed(closeTime))
+
+		txInputs, err := input.TransactionsFromLedger(lcm, env.NetworkPassphrase)
+		if err != nil {
+			cmdLogger.LogError(fmt.Errorf("could not read transactions from ledger %d: %v", lcm.LedgerSequence(), err))
+		}
+		for _, txInput := range txInputs {
+			attempts++
+			if err := aggregator.AddTransaction(txInput.Transaction, txInput.LedgerHistory); err != nil {
+				cmdLogger.LogError(fmt.Errorf("could not add liquidity pool activity of transaction %d in ledger %d: %v", txInput.Transaction.Index, lcm.LedgerSequence(), err))
+				failures++
+			}
+		}
+
+		if lcm.LedgerSequence() == endNum {
+			write(aggregator.Flush())
+		}
+		return attempts, failures
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(liquidityPoolMetricsCmd)
+	utils.AddCommonFlags(liquidityPoolMetricsCmd.Flags())
+	utils.AddLedgerBatchFlags("liquidity_pool_metrics", liquidityPoolMetricsCmd.Flags(), "exported_liquidity_pool_metrics/")
+	utils.AddCloudStorageFlags(liquidityPoolMetricsCmd.Flags())
+	utils.AddKafkaFlags(liquidityPoolMetricsCmd.Flags())
+	utils.AddPostgresFlags(liquidityPoolMetricsCmd.Flags())
+	liquidityPoolMetricsCmd.Flags().String("resolution", "1h", "Length of the time windows: 1m, 5m, 15m, 1h, 1d or 1w")
+	liquidityPoolMetricsCmd.MarkFlagRequired("start-ledger")
+	liquidityPoolMetricsCmd.MarkFlagRequired("end-ledger")
+}
@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"os"
 
 	"github.com/spf13/cobra"
 	"github.com/stellar/go-stellar-sdk/xdr"
@@ -22,13 +21,12 @@ processed in batches of batch-size; each batch produces one file named
 	},
 }
 
-func processOperations(lcm xdr.LedgerCloseMeta, env utils.EnvironmentDetails, outFile *os.File, writeParquet bool, extra map[string]string) ([]transform.SchemaParquet, int, int) {
+func processOperations(lcm xdr.LedgerCloseMeta, env utils.EnvironmentDetails, sink Sink) (int, int) {
 	opInputs, err := input.OperationsFromLedger(lcm, env.NetworkPassphrase)
 	if err != nil {
 		cmdLogger.LogError(fmt.Errorf("could not read operations from ledger %d: %v", lcm.LedgerSequence(), err))
-		return nil, 0, 0
+		return 0, 0
 	}
-	var rows []transform.SchemaParquet
 	attempts, failures := 0, 0
 	for _, opInput := range opInputs {
 		attempts++
@@ -38,16 +36,12 @@ func processOperations(lcm xdr.LedgerCloseMeta, env utils.EnvironmentDetails, ou
 			failures++
 			continue
 		}
-		if _, err := ExportEntry(transformed, outFile, extra); err != nil {
+		if err := sink.WriteRow(transformed); err != nil {
 			cmdLogger.LogError(fmt.Errorf("could not export operation: %v", err))
 			failures++
-			continue
-		}
-		if writeParquet {
-			rows = append(rows, transformed)
 		}
 	}
-	return rows, attempts, failures
+	return attempts, failures
 }
 
 func init() {
@@ -55,5 +49,7 @@ func init() {
 	utils.AddCommonFlags(operationsCmd.Flags())
 	utils.AddLedgerBatchFlags("operations", operationsCmd.Flags(), "exported_operations/")
 	utils.AddCloudStorageFlags(operationsCmd.Flags())
+	utils.AddKafkaFlags(operationsCmd.Flags())
+	utils.AddPostgresFlags(operationsCmd.Flags())
 	operationsCmd.MarkFlagRequired("end-ledger")
 }
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-etl/v2/internal/input"
+	"github.com/stellar/stellar-etl/v2/internal/transform"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+)
+
+var sorobanAuthEntriesCmd = &cobra.Command{
+	Use:   "export_soroban_auth_entries",
+	Short: "Exports the authorization entries of the Soroban transactions over a specified range.",
+	Long: `Exports one row per authorization entry of every InvokeHostFunction
+operation over a specified range, with the credentials of the entry and its
+authorized invocation tree flattened in depth first order. Ledgers are
+processed in batches of batch-size; each batch produces one file named
+{start}-{end}-soroban_auth_entries.txt in the output folder.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runLedgerBatchExport(cmd, "soroban_auth_entries", nil, processSorobanAuthEntries)
+	},
+}
+
+func processSorobanAuthEntries(lcm xdr.LedgerCloseMeta, env utils.EnvironmentDetails, sink Sink) (int, int) {
+	txInputs, err := input.TransactionsFromLedger(lcm, env.NetworkPassphrase)
+	if err != nil {
+		cmdLogger.LogError(fmt.Errorf("could not read transactions from ledger %d: %v", lcm.LedgerSequence(), err))
+		return 0, 0
+	}
+	attempts, failures := 0, 0
+	for _, txInput := range txInputs {
+		if !txInput.Transaction.IsSorobanTx() {
+			continue
+		}
+		attempts++
+		entries, err := transform.TransformSorobanAuthEntries(txInput.Transaction, txInput.LedgerHistory)
+		if err != nil {
+			ledgerSeq := txInput.LedgerHistory.Header.LedgerSeq
+			cmdLogger.LogError(fmt.Errorf("could not transform soroban auth entries for transaction %d in ledger %d: %v", txInput.Transaction.Index, ledgerSeq, err))
+			failures++
+			continue
+		}
+		for _, entry := range entries {
+			if err := sink.WriteRow(entry); err != nil {
+				cmdLogger.LogError(fmt.Errorf("could not export soroban auth entry: %v", err))
+				failures++
+			}
+		}
+	}
+	return attempts, failures
+}
+
+func init() {
+	rootCmd.AddCommand(sorobanAuthEntriesCmd)
+	utils.AddCommonFlags(sorobanAuthEntriesCmd.Flags())
+	utils.AddLedgerBatchFlags("soroban_auth_entries", sorobanAuthEntriesCmd.Flags(), "exported_soroban_auth_entries/")
+	utils.AddCloudStorageFlags(sorobanAuthEntriesCmd.Flags())
+	utils.AddKafkaFlags(sorobanAuthEntriesCmd.Flags())
+	utils.AddPostgresFlags(sorobanAuthEntriesCmd.Flags())
+	sorobanAuthEntriesCmd.MarkFlagRequired("start-ledger")
+	sorobanAuthEntriesCmd.MarkFlagRequired("end-ledger")
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-etl/v2/internal/input"
+	"github.com/stellar/stellar-etl/v2/internal/transform"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+)
+
+var sponsorshipsCmd = &cobra.Command{
+	Use:   "export_sponsorships",
+	Short: "Exports the changes of reserve sponsorships over a specified range.",
+	Long: `Exports one row per change of the sponsor paying the reserves of an account,
+trustline, offer, data entry, claimable balance or account signer over a
+specified range. Sponsorships begin when a sponsored entry is created or a
+sponsor is added, end when the entry is removed or the sponsorship is revoked,
+and are transferred when revoke_sponsorship moves them to another sponsor.
+Ledgers are processed in batches of batch-size; each batch produces one file
+named {start}-{end}-sponsorships.txt in the output folder.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runLedgerBatchExport(cmd, "sponsorships", nil, processSponsorships)
+	},
+}
+
+func processSponsorships(lcm xdr.LedgerCloseMeta, env utils.EnvironmentDetails, sink Sink) (int, int) {
+	txInputs, err := input.TransactionsFromLedger(lcm, env.NetworkPassphrase)
+	if err != nil {
+		cmdLogger.LogError(fmt.Errorf("could not read transactions from ledger %d: %v", lcm.LedgerSequence(), err))
+		return 0, 0
+	}
+	attempts, failures := 0, 0
+	for _, txInput := range txInputs {
+		attempts++
+		sponsorships, err := transform.TransformSponsorships(txInput.Transaction, txInput.LedgerHistory)
+		if err != nil {
+			ledgerSeq := txInput.LedgerHistory.Header.LedgerSeq
+			cmdLogger.LogError(fmt.Errorf("could not transform sponsorships of transaction %d in ledger %d: %v", txInput.Transaction.Index, ledgerSeq, err))
+			failures++
+			continue
+		}
+		for _, sponsorship := range sponsorships {
+			if err := sink.WriteRow(sponsorship); err != nil {
+				cmdLogger.LogError(fmt.Errorf("could not export sponsorship change: %v", err))
+				failures++
+			}
+		}
+	}
+	return attempts, failures
+}
+
+func init() {
+	rootCmd.AddCommand(sponsorshipsCmd)
+	utils.AddCommonFlags(sponsorshipsCmd.Flags())
+	utils.AddLedgerBatchFlags("sponsorships", sponsorshipsCmd.Flags(), "exported_sponsorships/")
+	utils.AddCloudStorageFlags(sponsorshipsCmd.Flags())
+	utils.AddKafkaFlags(sponsorshipsCmd.Flags())
+	utils.AddPostgresFlags(sponsorshipsCmd.Flags())
+	sponsorshipsCmd.MarkFlagRequired("start-ledger")
+	sponsorshipsCmd.MarkFlagRequired("end-ledger")
+}
@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"os"
 
 	"github.com/spf13/cobra"
 	"github.com/stellar/go-stellar-sdk/xdr"
@@ -21,21 +20,21 @@ named {start}-{end}-token_transfer.txt in the output folder.`,
 	},
 }
 
-func processTokenTransfers(lcm xdr.LedgerCloseMeta, env utils.EnvironmentDetails, outFile *os.File, _ bool, extra map[string]string) ([]transform.SchemaParquet, int, int) {
+func processTokenTransfers(lcm xdr.LedgerCloseMeta, env utils.EnvironmentDetails, sink Sink) (int, int) {
 	transfers, err := transform.TransformTokenTransfer(lcm, env.NetworkPassphrase)
 	if err != nil {
 		cmdLogger.LogError(fmt.Errorf("could not transform token transfers for ledger %d: %v", lcm.LedgerSequence(), err))
-		return nil, 1, 1
+		return 1, 1
 	}
 	failures := 0
 	for _, transfer := range transfers {
-		if _, err := ExportEntry(transfer, outFile, extra); err != nil {
+		if err := sink.WriteRow(transfer); err != nil {
 			cmdLogger.LogError(fmt.Errorf("could not export token transfer from ledger %d: %v", lcm.LedgerSequence(), err))
 			failures++
 			continue
 		}
 	}
-	return nil, 1, failures
+	return 1, failures
 }
 
 func init() {
@@ -43,5 +42,7 @@ func init() {
 	utils.AddCommonFlags(tokenTransfersCmd.Flags())
 	utils.AddLedgerBatchFlags("token_transfer", tokenTransfersCmd.Flags(), "exported_token_transfer/")
 	utils.AddCloudStorageFlags(tokenTransfersCmd.Flags())
+	utils.AddKafkaFlags(tokenTransfersCmd.Flags())
+	utils.AddPostgresFlags(tokenTransfersCmd.Flags())
 	tokenTransfersCmd.MarkFlagRequired("end-ledger")
 }
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-etl/v2/internal/input"
+	"github.com/stellar/stellar-etl/v2/internal/transform"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+)
+
+var tokensCmd = &cobra.Command{
+	Use:   "export_tokens",
+	Short: "Exports the registry of token contracts over a specified range.",
+	Long: `Exports the Stellar Asset Contracts and SEP-41 token contracts seen over a
+specified range. A row is written the first time a token is seen, whenever its
+metadata or admin changes, and when it first emits a standard token event, so
+the last row of every contract is its current state. A contract whose instance
+keeps no token metadata is registered as a sep41 token without name, symbol and
+decimals when it first emits a standard token event. Pass the rows of earlier
+runs with --tokens-file to carry the registry over. Ledgers are processed in
+batches of batch-size; each batch produces one file named
+{start}-{end}-tokens.txt in the output folder.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		path, err := cmd.Flags().GetString("tokens-file")
+		if err != nil {
+			cmdLogger.Fatal("could not get tokens file: ", err)
+		}
+		tokens := map[string]transform.TokenOutput{}
+		if path != "" {
+			f, err := os.Open(path)
+			if err != nil {
+				cmdLogger.Fatal("could not open tokens file: ", err)
+			}
+			tokens, err = transform.LoadTokens(f)
+			f.Close()
+			if err != nil {
+				cmdLogger.Fatal(fmt.Sprintf("could not load %s: ", path), err)
+			}
+		}
+		runLedgerBatchExport(cmd, "tokens", nil, newTokensProcessor(tokens))
+	},
+}
+
+// newTokensProcessor returns a processor that updates the registry in tokens with the token instances and standard
+// token events of each ledger, writing the tokens that are new or changed.
+func newTokensProcessor(tokens map[string]transform.TokenOutput) processLedgerFunc {
+	return func(lcm xdr.LedgerCloseMeta, env utils.EnvironmentDetails, sink Sink) (int, int) {
+		txInputs, err := input.TransactionsFromLedger(lcm, env.NetworkPassphrase)
+		if err != nil {
+			cmdLogger.LogError(fmt.Errorf("could not read transactions from ledger %d: %v", lcm.LedgerSequence(), err))
+			return 1, 1
+		}
+		attempts, failures := 0, 0
+		write := func(token transform.TokenOutput) {
+			tokens[token.ContractId] = token
+			if err := sink.WriteRow(token); err != nil {
+				cmdLogger.LogError(fmt.Errorf("could not export token: %v", err))
+				failures++
+			}
+		}
+
+		for _, txInput := range txInputs {
+			transaction := txInput.Transaction
+			if !transaction.IsSorobanTx() {
+				continue
+			}
+			attempts++
+			ledgerSeq := txInput.LedgerHistory.Header.LedgerSeq
+			changes, err := transaction.GetChanges()
+			if err != nil {
+				cmdLogger.LogError(fmt.Errorf("could not read changes of transaction %d in ledger %d: %v", transaction.Index, ledgerSeq, err))
+				failures++
+				continue
+			}
+
+			for _, change := range changes {
+				if change.Type != xdr.LedgerEntryTypeContractData || change.Post == nil {
+					continue
+				}
+				token, ok, err := transform.TransformToken(*change.Post, env.NetworkPassphrase, txInput.LedgerHistory)
+				if err != nil {
+					cmdLogger.LogError(fmt.Errorf("could not transform token in transaction %d in ledger %d: %v", transaction.Index, ledgerSeq, err))
+					failures++
+					continue
+				}
+				if !ok {
+					continue
+				}
+				previous, seen := tokens[token.ContractId]
+				if seen {
+					token.FirstSeenLedger = previous.FirstSeenLedger
+					token.EmitsStandardEvents = previous.EmitsStandardEvents
+					if sameTokenDetails(previous, token) {
+						continue
+					}
+				}
+				write(token)
+			}
+
+			if !transaction.Result.Successful() {
+				continue
+			}
+			events, err := transaction.GetContractEvents()
+			if err != nil {
+				cmdLogger.LogError(fmt.Errorf("could not read events of transaction %d in ledger %d: %v", transaction.Index, ledgerSeq, err))
+				failures++
+				continue
+			}
+			for _, event := range events {
+				previous, seen := tokens[transform.TokenEventContractId(event)]
+				if (seen && previous.EmitsStandardEvents) || !transform.IsStandardTokenEvent(event) {
+					continue
+				}
+				token, err := transform.TransformTokenEvent(event, txInput.LedgerHistory)
+				if err != nil {
+					cmdLogger.LogError(err)
+					failures++
+					continue
+				}
+				if seen {
+					previous.EmitsStandardEvents = true
+					previous.LedgerSequence = token.LedgerSequence
+					previous.ClosedAt = token.ClosedAt
+					token = previous
+				}
+				write(token)
+			}
+		}
+		return attempts, failures
+	}
+}
+
+// sameTokenDetails reports whether the registry details of two rows of the same token are equal
+func sameTokenDetails(a, b transform.TokenOutput) bool {
+	return a.TokenType == b.TokenType && a.Name == b.Name && a.Symbol == b.Symbol && a.Decimals == b.Decimals &&
+		a.Admin == b.Admin && a.AssetCode == b.AssetCode && a.AssetIssuer == b.AssetIssuer
+}
+
+func init() {
+	rootCmd.AddCommand(tokensCmd)
+	utils.AddCommonFlags(tokensCmd.Flags())
+	utils.AddLedgerBatchFlags("tokens", tokensCmd.Flags(), "exported_tokens/")
+	utils.AddCloudStorageFlags(tokensCmd.Flags())
+	utils.AddKafkaFlags(tokensCmd.Flags())
+	utils.AddPostgresFlags(tokensCmd.Flags())
+	tokensCmd.Flags().String("tokens-file", "", "Earlier export_tokens output the registry is carried over from")
+	tokensCmd.MarkFlagRequired("start-ledger")
+	tokensCmd.MarkFlagRequired("end-ledger")
+}
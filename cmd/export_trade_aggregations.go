@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/stellar/go-stellar-sdk/ingest/ledgerbackend"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-etl/v2/internal/input"
+	"github.com/stellar/stellar-etl/v2/internal/toid"
+	"github.com/stellar/stellar-etl/v2/internal/transform"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+)
+
+var tradeAggregationsCmd = &cobra.Command{
+	Use:   "export_trade_aggregations",
+	Short: "Exports OHLCV candles of the trades over a specified range.",
+	Long: `Exports OHLCV candles of the trades within the specified range, per
+resolution, trade type (orderbook or liquidity pool) and asset pair. A candle
+is written once the first ledger after its bucket closes, and the candles
+still open at end-ledger are left to the export that continues the range.
+The trades from the start of the bucket of the longest resolution holding
+the ledger before start-ledger are read first, so the candles spanning
+start-ledger are complete and the ones closed before it are not written
+again. Ledgers are processed in batches of batch-size; each batch produces
+one file named {start}-{end}-trade_aggregations.txt in the output folder.
+--kafka-resume is not supported, as the candles open at the resumed ledger
+would miss the trades before it.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		commonArgs := utils.MustCommonFlags(cmd.Flags(), cmdLogger)
+		env := utils.GetEnvironmentDetails(commonArgs)
+		start, _, _, _ := utils.MustLedgerBatchFlags(cmd.Flags(), cmdLogger)
+		if utils.MustKafkaFlags(cmd.Flags(), cmdLogger).Resume {
+			cmdLogger.Fatal("kafka-resume is not supported by export_trade_aggregations, whose candles start before start-ledger")
+		}
+		resolutions, err := cmd.Flags().GetStringSlice("resolutions")
+		if err != nil {
+			cmdLogger.Fatal("could not get resolutions: ", err)
+		}
+		aggregator, err := transform.NewTradeAggregator(resolutions)
+		if err != nil {
+			cmdLogger.Fatal("could not create trade aggregator: ", err)
+		}
+		// The genesis ledger has no trades
+		if start > 2 {
+			from, err := input.GetIntervalStartLedger(start-1, aggregator.LongestResolution(), env)
+			if err != nil {
+				cmdLogger.Fatal("could not find the start of the candles open before start-ledger: ", err)
+			}
+			seedTradeAggregations(aggregator, commonArgs.UseCaptiveCore, env, from, start-1)
+		}
+		runLedgerBatchExport(cmd, "trade_aggregations", nil, newTradeAggregationsProcessor(aggregator))
+	},
+}
+
+// seedTradeAggregations adds the trades of the ledgers from to to aggregator, dropping the candles they close, which
+// were written by the export of those ledgers
+func seedTradeAggregations(aggregator *transform.TradeAggregator, useCaptiveCore bool, env utils.EnvironmentDetails, from, to uint32) {
+	ctx := context.Background()
+	backend, err := utils.CreateLedgerBackend(ctx, useCaptiveCore, env)
+	if err != nil {
+		cmdLogger.Fatal("could not create ledger backend: ", err)
+	}
+	defer backend.Close()
+	if err := backend.PrepareRange(ctx, ledgerbackend.BoundedRange(from, to)); err != nil {
+		cmdLogger.Fatal("could not prepare ledger range: ", err)
+	}
+	for seq := from; seq <= to; seq++ {
+		lcm, err := backend.GetLedger(ctx, seq)
+		if err != nil {
+			cmdLogger.Fatalf("unable to get ledger %d from backend: %v", seq, err)
+		}
+		aggregateLedgerTrades(aggregator, lcm, env)
+	}
+}
+
+// newTradeAggregationsProcessor returns a processor that adds the trades of each ledger to aggregator, writing the
+// candles closed by the ledger
+func newTradeAggregationsProcessor(aggregator *transform.TradeAggregator) processLedgerFunc {
+	return func(lcm xdr.LedgerCloseMeta, env utils.EnvironmentDetails, sink Sink) (int, int) {
+		candles, attempts, failures := aggregateLedgerTrades(aggregator, lcm, env)
+		for _, candle := range candles {
+			if err := sink.WriteRow(candle); err != nil {
+				cmdLogger.LogError(fmt.Errorf("could not export trade aggregation: %v", err))
+				failures++
+			}
+		}
+		return attempts, failures
+	}
+}
+
+// aggregateLedgerTrades adds the trades of a ledger to aggregator and returns the candles closed by the ledger
+func aggregateLedgerTrades(aggregator *transform.TradeAggregator, lcm xdr.LedgerCloseMeta, env utils.EnvironmentDetails) ([]transform.TradeAggregationOutput, int, int) {
+	closeTime, err := utils.TimePointToUTCTimeStamp(lcm.LedgerHeaderHistoryEntry().Header.ScpValue.CloseTime)
+	if err != nil {
+		cmdLogger.LogError(fmt.Errorf("could not read close time of ledger %d: %v", lcm.LedgerSequence(), err))
+		return nil, 1, 1
+	}
+	candles := aggregator.Closed(closeTime)
+
+	tradeInputs, err := input.TradesFromLedger(lcm, env.NetworkPassphrase)
+	if err != nil {
+		cmdLogger.LogError(fmt.Errorf("could not read trades from ledger %d: %v", lcm.LedgerSequence(), err))
+	}
+	attempts, failures := 0, 0
+	for _, tradeInput := range tradeInputs {
+		attempts++
+		err := aggregator.AddTrades(tradeInput.OperationIndex, tradeInput.OperationHistoryID, tradeInput.Transaction, tradeInput.CloseTime)
+		if err != nil {
+			parsedID := toid.Parse(tradeInput.OperationHistoryID)
+			cmdLogger.LogError(fmt.Errorf("from ledger %d, transaction %d, operation %d: %v", parsedID.LedgerSequence, parsedID.TransactionOrder, parsedID.OperationOrder, err))
+			failures++
+		}
+	}
+	return candles, attempts, failures
+}
+
+func init() {
+	rootCmd.AddCommand(tradeAggregationsCmd)
+	utils.AddCommonFlags(tradeAggregationsCmd.Flags())
+	utils.AddLedgerBatchFlags("trade_aggregations", tradeAggregationsCmd.Flags(), "exported_trade_aggregations/")
+	utils.AddCloudStorageFlags(tradeAggregationsCmd.Flags())
+	utils.AddKafkaFlags(tradeAggregationsCmd.Flags())
+	utils.AddPostgresFlags(tradeAggregationsCmd.Flags())
+	tradeAggregationsCmd.Flags().StringSlice("resolutions", []string{"1m", "5m", "1h", "1d"}, "Candle resolutions to export: 1m, 5m, 15m, 1h, 1d or 1w")
+	tradeAggregationsCmd.MarkFlagRequired("start-ledger")
+	tradeAggregationsCmd.MarkFlagRequired("end-ledger")
+}
@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"os"
 
 	"github.com/spf13/cobra"
 	"github.com/stellar/go-stellar-sdk/xdr"
@@ -24,13 +23,12 @@ processed in batches of batch-size; each batch produces one file named
 	},
 }
 
-func processTrades(lcm xdr.LedgerCloseMeta, env utils.EnvironmentDetails, outFile *os.File, writeParquet bool, extra map[string]string) ([]transform.SchemaParquet, int, int) {
+func processTrades(lcm xdr.LedgerCloseMeta, env utils.EnvironmentDetails, sink Sink) (int, int) {
 	tradeInputs, err := input.TradesFromLedger(lcm, env.NetworkPassphrase)
 	if err != nil {
 		cmdLogger.LogError(fmt.Errorf("could not read trades from ledger %d: %v", lcm.LedgerSequence(), err))
-		return nil, 0, 0
+		return 0, 0
 	}
-	var rows []transform.SchemaParquet
 	attempts, failures := 0, 0
 	for _, tradeInput := range tradeInputs {
 		attempts++
@@ -42,17 +40,13 @@ func processTrades(lcm xdr.LedgerCloseMeta, env utils.EnvironmentDetails, outFil
 			continue
 		}
 		for _, trade := range trades {
-			if _, err := ExportEntry(trade, outFile, extra); err != nil {
+			if err := sink.WriteRow(trade); err != nil {
 				cmdLogger.LogError(fmt.Errorf("could not export trade: %v", err))
 				failures++
-				continue
-			}
-			if writeParquet {
-				rows = append(rows, trade)
 			}
 		}
 	}
-	return rows, attempts, failures
+	return attempts, failures
 }
 
 func init() {
@@ -60,5 +54,7 @@ func init() {
 	utils.AddCommonFlags(tradesCmd.Flags())
 	utils.AddLedgerBatchFlags("trades", tradesCmd.Flags(), "exported_trades/")
 	utils.AddCloudStorageFlags(tradesCmd.Flags())
+	utils.AddKafkaFlags(tradesCmd.Flags())
+	utils.AddPostgresFlags(tradesCmd.Flags())
 	tradesCmd.MarkFlagRequired("end-ledger")
 }
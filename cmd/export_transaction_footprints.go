@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-etl/v2/internal/input"
+	"github.com/stellar/stellar-etl/v2/internal/transform"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+)
+
+var transactionFootprintsCmd = &cobra.Command{
+	Use:   "export_transaction_footprints",
+	Short: "Exports the footprints of the Soroban transactions over a specified range.",
+	Long: `Exports one row per ledger key in the read-only and read-write footprint
+of every Soroban transaction over a specified range, noting whether the key
+was restored or modified by the transaction. Ledgers are processed in batches
+of batch-size; each batch produces one file named
+{start}-{end}-transaction_footprints.txt in the output folder.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runLedgerBatchExport(cmd, "transaction_footprints", nil, processTransactionFootprints)
+	},
+}
+
+func processTransactionFootprints(lcm xdr.LedgerCloseMeta, env utils.EnvironmentDetails, sink Sink) (int, int) {
+	txInputs, err := input.TransactionsFromLedger(lcm, env.NetworkPassphrase)
+	if err != nil {
+		cmdLogger.LogError(fmt.Errorf("could not read transactions from ledger %d: %v", lcm.LedgerSequence(), err))
+		return 0, 0
+	}
+	attempts, failures := 0, 0
+	for _, txInput := range txInputs {
+		if !txInput.Transaction.IsSorobanTx() {
+			continue
+		}
+		attempts++
+		footprint, err := transform.TransformTransactionFootprint(txInput.Transaction, txInput.LedgerHistory)
+		if err != nil {
+			ledgerSeq := txInput.LedgerHistory.Header.LedgerSeq
+			cmdLogger.LogError(fmt.Errorf("could not transform footprint for transaction %d in ledger %d: %v", txInput.Transaction.Index, ledgerSeq, err))
+			failures++
+			continue
+		}
+		for _, key := range footprint {
+			if err := sink.WriteRow(key); err != nil {
+				cmdLogger.LogError(fmt.Errorf("could not export footprint key: %v", err))
+				failures++
+			}
+		}
+	}
+	return attempts, failures
+}
+
+func init() {
+	rootCmd.AddCommand(transactionFootprintsCmd)
+	utils.AddCommonFlags(transactionFootprintsCmd.Flags())
+	utils.AddLedgerBatchFlags("transaction_footprints", transactionFootprintsCmd.Flags(), "exported_transaction_footprints/")
+	utils.AddCloudStorageFlags(transactionFootprintsCmd.Flags())
+	utils.AddKafkaFlags(transactionFootprintsCmd.Flags())
+	utils.AddPostgresFlags(transactionFootprintsCmd.Flags())
+	transactionFootprintsCmd.MarkFlagRequired("start-ledger")
+	transactionFootprintsCmd.MarkFlagRequired("end-ledger")
+}
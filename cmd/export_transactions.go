@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"os"
 
 	"github.com/spf13/cobra"
 	"github.com/stellar/go-stellar-sdk/xdr"
@@ -23,13 +22,12 @@ the output folder, which is uploaded before the next batch is processed.`,
 	},
 }
 
-func processTransactions(lcm xdr.LedgerCloseMeta, env utils.EnvironmentDetails, outFile *os.File, writeParquet bool, extra map[string]string) ([]transform.SchemaParquet, int, int) {
+func processTransactions(lcm xdr.LedgerCloseMeta, env utils.EnvironmentDetails, sink Sink) (int, int) {
 	txInputs, err := input.TransactionsFromLedger(lcm, env.NetworkPassphrase)
 	if err != nil {
 		cmdLogger.LogError(fmt.Errorf("could not read transactions from ledger %d: %v", lcm.LedgerSequence(), err))
-		return nil, 0, 0
+		return 0, 0
 	}
-	var rows []transform.SchemaParquet
 	attempts, failures := 0, 0
 	for _, txInput := range txInputs {
 		attempts++
@@ -40,16 +38,12 @@ func processTransactions(lcm xdr.LedgerCloseMeta, env utils.EnvironmentDetails,
 			failures++
 			continue
 		}
-		if _, err := ExportEntry(transformed, outFile, extra); err != nil {
+		if err := sink.WriteRow(transformed); err != nil {
 			cmdLogger.LogError(fmt.Errorf("could not export transaction: %v", err))
 			failures++
-			continue
-		}
-		if writeParquet {
-			rows = append(rows, transformed)
 		}
 	}
-	return rows, attempts, failures
+	return attempts, failures
 }
 
 func init() {
@@ -57,5 +51,7 @@ func init() {
 	utils.AddCommonFlags(transactionsCmd.Flags())
 	utils.AddLedgerBatchFlags("transactions", transactionsCmd.Flags(), "exported_transactions/")
 	utils.AddCloudStorageFlags(transactionsCmd.Flags())
+	utils.AddKafkaFlags(transactionsCmd.Flags())
+	utils.AddPostgresFlags(transactionsCmd.Flags())
 	transactionsCmd.MarkFlagRequired("end-ledger")
 }
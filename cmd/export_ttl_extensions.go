@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-etl/v2/internal/input"
+	"github.com/stellar/stellar-etl/v2/internal/transform"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+)
+
+var ttlExtensionsCmd = &cobra.Command{
+	Use:   "export_ttl_extensions",
+	Short: "Exports the TTL bought by the Soroban transactions over a specified range.",
+	Long: `Exports one row per contract data or code entry whose TTL was extended,
+restored or created by a Soroban transaction over a specified range, with the
+rent fee of the whole transaction. The rent of each entry is not exported, as
+it depends on the size of the entry and the rent settings of the network,
+which the transaction meta does not hold. Ledgers are processed in batches of
+batch-size; each batch produces one file named
+{start}-{end}-ttl_extensions.txt in the output folder.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runLedgerBatchExport(cmd, "ttl_extensions", nil, processTtlExtensions)
+	},
+}
+
+func processTtlExtensions(lcm xdr.LedgerCloseMeta, env utils.EnvironmentDetails, sink Sink) (int, int) {
+	txInputs, err := input.TransactionsFromLedger(lcm, env.NetworkPassphrase)
+	if err != nil {
+		cmdLogger.LogError(fmt.Errorf("could not read transactions from ledger %d: %v", lcm.LedgerSequence(), err))
+		return 0, 0
+	}
+	attempts, failures := 0, 0
+	for _, txInput := range txInputs {
+		if !txInput.Transaction.IsSorobanTx() {
+			continue
+		}
+		attempts++
+		extensions, err := transform.TransformTtlExtensions(txInput.Transaction, txInput.LedgerHistory)
+		if err != nil {
+			ledgerSeq := txInput.LedgerHistory.Header.LedgerSeq
+			cmdLogger.LogError(fmt.Errorf("could not transform TTL extensions for transaction %d in ledger %d: %v", txInput.Transaction.Index, ledgerSeq, err))
+			failures++
+			continue
+		}
+		for _, extension := range extensions {
+			if err := sink.WriteRow(extension); err != nil {
+				cmdLogger.LogError(fmt.Errorf("could not export TTL extension: %v", err))
+				failures++
+			}
+		}
+	}
+	return attempts, failures
+}
+
+func init() {
+	rootCmd.AddCommand(ttlExtensionsCmd)
+	utils.AddCommonFlags(ttlExtensionsCmd.Flags())
+	utils.AddLedgerBatchFlags("ttl_extensions", ttlExtensionsCmd.Flags(), "exported_ttl_extensions/")
+	utils.AddCloudStorageFlags(ttlExtensionsCmd.Flags())
+	utils.AddKafkaFlags(ttlExtensionsCmd.Flags())
+	utils.AddPostgresFlags(ttlExtensionsCmd.Flags())
+	ttlExtensionsCmd.MarkFlagRequired("start-ledger")
+	ttlExtensionsCmd.MarkFlagRequired("end-ledger")
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/spf13/cobra"
+)
+
+var generatePostgresDDLCmd = &cobra.Command{
+	Use:   "generate_postgres_ddl [dataset...]",
+	Short: "Prints the PostgreSQL DDL of the export tables.",
+	Long: `Prints the CREATE TABLE statements used when loading exports into PostgreSQL
+with --postgres-dsn. If no datasets are given, the DDL of every dataset that can
+be loaded is printed. Pass the same --extra-fields as the exports so the extra
+columns are included.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		schema, err := cmd.Flags().GetString("postgres-schema")
+		if err != nil {
+			cmdLogger.Fatal("could not get postgres schema: ", err)
+		}
+		prefix, err := cmd.Flags().GetString("postgres-table-prefix")
+		if err != nil {
+			cmdLogger.Fatal("could not get postgres table prefix: ", err)
+		}
+		extra, err := cmd.Flags().GetStringToString("extra-fields")
+		if err != nil {
+			cmdLogger.Fatal("could not get extra fields: ", err)
+		}
+
+		datasets := args
+		if len(datasets) == 0 {
+			for dataset := range postgresDatasets {
+				datasets = append(datasets, dataset)
+			}
+			sort.Strings(datasets)
+		}
+
+		extraFields := make([]string, 0, len(extra))
+		for name := range extra {
+			extraFields = append(extraFields, name)
+		}
+		sort.Strings(extraFields)
+
+		fmt.Printf("%s;\n\n", postgresLoadsCreateStatement(schema, prefix))
+		for _, dataset := range datasets {
+			table, err := newPostgresTable(dataset, prefix, extraFields)
+			if err != nil {
+				cmdLogger.Fatal(err)
+			}
+			for _, statement := range table.createStatements(schema) {
+				fmt.Printf("%s;\n\n", statement)
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(generatePostgresDDLCmd)
+	generatePostgresDDLCmd.Flags().String("postgres-schema", "public", "PostgreSQL schema the tables are created in")
+	generatePostgresDDLCmd.Flags().String("postgres-table-prefix", "", "Prefix added to the name of every PostgreSQL table")
+	generatePostgresDDLCmd.Flags().StringToStringP("extra-fields", "u", map[string]string{}, "Additional fields appended to the exported rows")
+}
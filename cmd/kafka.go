@@ -0,0 +1,340 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+)
+
+// kafkaLedgerHeader is the message header holding the first ledger of the
+// unit (a single ledger, or a change batch) the row was exported in.
+// Restarting an export from that ledger replays the whole unit, so resuming
+// from the highest published value gives at-least-once delivery.
+const kafkaLedgerHeader = "ledger_sequence"
+
+// kafkaBatchTimeout bounds how long the writer waits for a partial batch to
+// fill up. Rows are published synchronously ledger by ledger, so the writer's
+// 1s default would hold every ledger with fewer rows than a full batch.
+const kafkaBatchTimeout = 5 * time.Millisecond
+
+// KafkaPublisher publishes the rows of a single dataset to its Kafka topic.
+// The rows are encoded by encodeRow, the same JSON as the exported files.
+type KafkaPublisher struct {
+	writer       *kafka.Writer
+	brokers      []string
+	topic        string
+	partitionKey string
+}
+
+func newKafkaPublisher(flags utils.KafkaFlagValues, dataset string) *KafkaPublisher {
+	acks := kafka.RequireAll
+	switch flags.Acks {
+	case "none":
+		acks = kafka.RequireNone
+	case "one":
+		acks = kafka.RequireOne
+	}
+
+	topic := flags.Topic(dataset)
+	return &KafkaPublisher{
+		writer: &kafka.Writer{
+			Addr:                   kafka.TCP(flags.Brokers...),
+			Topic:                  topic,
+			Balancer:               &kafka.Hash{},
+			RequiredAcks:           acks,
+			BatchTimeout:           kafkaBatchTimeout,
+			AllowAutoTopicCreation: true,
+		},
+		brokers:      flags.Brokers,
+		topic:        topic,
+		partitionKey: flags.PartitionKey[dataset],
+	}
+}
+
+// PublishRows publishes every newline-delimited JSON row read from rows as
+// one message, waiting for the configured acknowledgements before returning.
+func (k *KafkaPublisher) PublishRows(ctx context.Context, rows io.Reader, ledgerSeq uint32) (int, error) {
+	messages, err := buildKafkaMessages(rows, k.partitionKey, ledgerSeq)
+	if err != nil {
+		return 0, err
+	}
+	if len(messages) == 0 {
+		return 0, nil
+	}
+
+	if err := k.writer.WriteMessages(ctx, messages...); err != nil {
+		return 0, fmt.Errorf("could not publish %d rows to topic %s: %v", len(messages), k.topic, err)
+	}
+	return len(messages), nil
+}
+
+// LastPublishedLedger returns the highest ledger sequence header found on
+// the last message of every partition of the topic. ok is false if the topic
+// has no messages yet.
+func (k *KafkaPublisher) LastPublishedLedger(ctx context.Context) (seq uint32, ok bool, err error) {
+	var conn *kafka.Conn
+	for _, broker := range k.brokers {
+		conn, err = kafka.DialContext(ctx, "tcp", broker)
+		if err == nil {
+			break
+		}
+	}
+	if conn == nil {
+		return 0, false, fmt.Errorf("could not connect to any kafka broker: %v", err)
+	}
+	defer conn.Close()
+
+	partitions, err := conn.ReadPartitions(k.topic)
+	if err != nil {
+		var kafkaErr kafka.Error
+		if errors.As(err, &kafkaErr) && kafkaErr == kafka.UnknownTopicOrPartition {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("could not read partitions of topic %s: %v", k.topic, err)
+	}
+
+	for _, partition := range partitions {
+		partitionSeq, found, err := lastLedgerInPartition(ctx, partition)
+		if err != nil {
+			return 0, false, err
+		}
+		if found && (!ok || partitionSeq > seq) {
+			seq, ok = partitionSeq, true
+		}
+	}
+	return seq, ok, nil
+}
+
+func lastLedgerInPartition(ctx context.Context, partition kafka.Partition) (uint32, bool, error) {
+	address := net.JoinHostPort(partition.Leader.Host, strconv.Itoa(partition.Leader.Port))
+	conn, err := kafka.DialLeader(ctx, "tcp", address, partition.Topic, partition.ID)
+	if err != nil {
+		return 0, false, fmt.Errorf("could not connect to leader of %s/%d: %v", partition.Topic, partition.ID, err)
+	}
+	defer conn.Close()
+
+	first, last, err := conn.ReadOffsets()
+	if err != nil {
+		return 0, false, fmt.Errorf("could not read offsets of %s/%d: %v", partition.Topic, partition.ID, err)
+	}
+	if last <= first {
+		return 0, false, nil
+	}
+
+	if _, err := conn.Seek(last-1, kafka.SeekAbsolute); err != nil {
+		return 0, false, fmt.Errorf("could not seek %s/%d: %v", partition.Topic, partition.ID, err)
+	}
+	conn.SetReadDeadline(time.Now().Add(30 * time.Second))
+	message, err := conn.ReadMessage(10e6)
+	if err != nil {
+		return 0, false, fmt.Errorf("could not read last message of %s/%d: %v", partition.Topic, partition.ID, err)
+	}
+
+	for _, header := range message.Headers {
+		if header.Key != kafkaLedgerHeader {
+			continue
+		}
+		seq, err := strconv.ParseUint(string(header.Value), 10, 32)
+		if err != nil {
+			return 0, false, fmt.Errorf("invalid %s header on %s/%d: %v", kafkaLedgerHeader, partition.Topic, partition.ID, err)
+		}
+		return uint32(seq), true, nil
+	}
+	return 0, false, nil
+}
+
+func (k *KafkaPublisher) Close() error {
+	return k.writer.Close()
+}
+
+// buildKafkaMessages turns newline-delimited JSON rows into Kafka messages.
+// The message key is the value of the partitionKey field of the row, if set
+// and present; keyless messages are spread across partitions.
+func buildKafkaMessages(rows io.Reader, partitionKey string, ledgerSeq uint32) ([]kafka.Message, error) {
+	var messages []kafka.Message
+	reader := bufio.NewReader(rows)
+	ledgerHeader := kafka.Header{Key: kafkaLedgerHeader, Value: []byte(strconv.FormatUint(uint64(ledgerSeq), 10))}
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			value := bytes.TrimRight(line, "\n")
+			key, keyErr := kafkaMessageKey(value, partitionKey)
+			if keyErr != nil {
+				return nil, keyErr
+			}
+			messages = append(messages, kafka.Message{
+				Key:     key,
+				Value:   value,
+				Headers: []kafka.Header{ledgerHeader},
+			})
+		}
+		if err == io.EOF {
+			return messages, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func kafkaMessageKey(row []byte, partitionKey string) ([]byte, error) {
+	if partitionKey == "" {
+		return nil, nil
+	}
+
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(row, &fields); err != nil {
+		return nil, fmt.Errorf("could not decode row to read partition key %s: %v", partitionKey, err)
+	}
+	raw, ok := fields[partitionKey]
+	if !ok || string(raw) == "null" {
+		return nil, nil
+	}
+
+	var str string
+	if err := json.Unmarshal(raw, &str); err == nil {
+		return []byte(str), nil
+	}
+	return raw, nil
+}
+
+// kafkaPublishers lazily creates one KafkaPublisher per dataset enabled by
+// the Kafka flags.
+type kafkaPublishers struct {
+	flags     utils.KafkaFlagValues
+	byDataset map[string]*KafkaPublisher
+}
+
+func newKafkaPublishers(flags utils.KafkaFlagValues) *kafkaPublishers {
+	return &kafkaPublishers{flags: flags, byDataset: map[string]*KafkaPublisher{}}
+}
+
+// get returns the publisher for dataset, or nil if it is not published to Kafka.
+func (k *kafkaPublishers) get(dataset string) *KafkaPublisher {
+	if !k.flags.Enabled(dataset) {
+		return nil
+	}
+	publisher, ok := k.byDataset[dataset]
+	if !ok {
+		publisher = newKafkaPublisher(k.flags, dataset)
+		k.byDataset[dataset] = publisher
+	}
+	return publisher
+}
+
+// resumeLedger returns the ledger to restart from when kafka-resume is set:
+// the oldest of the last ledgers published to each of the datasets' topics,
+// or start if any of them has not been published to yet, since the rows it
+// would have received from start on are still to be published.
+func (k *kafkaPublishers) resumeLedger(datasets []string, start uint32) uint32 {
+	if !k.flags.Resume {
+		return start
+	}
+
+	ctx := context.Background()
+	resume, found := uint32(0), false
+	for _, dataset := range datasets {
+		publisher := k.get(dataset)
+		if publisher == nil {
+			continue
+		}
+		seq, ok, err := publisher.LastPublishedLedger(ctx)
+		if err != nil {
+			cmdLogger.Fatal("could not read last published ledger: ", err)
+		}
+		if !ok {
+			return start
+		}
+		if !found || seq < resume {
+			resume, found = seq, true
+		}
+	}
+
+	if !found || resume < start {
+		return start
+	}
+	cmdLogger.Infof("Resuming Kafka export from ledger %d", resume)
+	return resume
+}
+
+// sink returns the sink publishing the rows of dataset, or nil if dataset is
+// not published to Kafka.
+func (k *kafkaPublishers) sink(dataset string, extra map[string]string) Sink {
+	publisher := k.get(dataset)
+	if publisher == nil {
+		return nil
+	}
+	return &kafkaSink{publisher: publisher, extra: extra}
+}
+
+func (k *kafkaPublishers) Close() {
+	for dataset, publisher := range k.byDataset {
+		if err := publisher.Close(); err != nil {
+			cmdLogger.Errorf("could not close kafka publisher for %s: %v", dataset, err)
+		}
+	}
+}
+
+// kafkaSink publishes the rows of a dataset as soon as the ledger they were
+// exported from is committed. Rows committed with the whole batch, as the
+// ledger entry changes are, are published under the first ledger of the
+// batch, since a restart replays the whole batch. Delivery is synchronous and
+// a failed delivery is returned as an error so that no ledger is skipped.
+type kafkaSink struct {
+	publisher  *KafkaPublisher
+	extra      map[string]string
+	pending    bytes.Buffer
+	batchStart uint32
+}
+
+func (k *kafkaSink) OpenBatch(start, end uint32) error {
+	k.batchStart = start
+	k.pending.Reset()
+	return nil
+}
+
+func (k *kafkaSink) WriteRow(row interface{}) error {
+	encoded, err := encodeRow(row, k.extra)
+	if err != nil {
+		return err
+	}
+	k.pending.Write(encoded)
+	k.pending.WriteByte('\n')
+	return nil
+}
+
+func (k *kafkaSink) CommitLedger(seq uint32) error {
+	return k.publish(seq)
+}
+
+func (k *kafkaSink) CloseBatch() error {
+	return nil
+}
+
+func (k *kafkaSink) Commit() error {
+	return k.publish(k.batchStart)
+}
+
+func (k *kafkaSink) publish(ledgerSeq uint32) error {
+	if k.pending.Len() == 0 {
+		return nil
+	}
+	_, err := k.publisher.PublishRows(context.Background(), bytes.NewReader(k.pending.Bytes()), ledgerSeq)
+	k.pending.Reset()
+	return err
+}
+
+// Close is a no-op: the publisher is shared and closed by kafkaPublishers.
+func (k *kafkaSink) Close() error {
+	return nil
+}
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/segmentio/kafka-go/protocol/metadata"
+	"github.com/segmentio/kafka-go/protocol/produce"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildKafkaMessages(t *testing.T) {
+	rows := `{"id":12884905985,"transaction_hash":"abc","ledger_sequence":3}
+{"id":12884905986,"transaction_hash":null,"ledger_sequence":3}
+
+{"id":12884905987,"ledger_sequence":3}
+`
+	tests := []struct {
+		name         string
+		partitionKey string
+		wantKeys     []string
+	}{
+		{"no partition key", "", []string{"", "", ""}},
+		{"string partition key", "transaction_hash", []string{"abc", "", ""}},
+		{"number partition key", "id", []string{"12884905985", "12884905986", "12884905987"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			messages, err := buildKafkaMessages(strings.NewReader(rows), test.partitionKey, 3)
+			assert.NoError(t, err)
+			assert.Len(t, messages, 3)
+			for i, message := range messages {
+				assert.Equal(t, test.wantKeys[i], string(message.Key))
+				assert.Equal(t, []kafka.Header{{Key: kafkaLedgerHeader, Value: []byte("3")}}, message.Headers)
+				assert.False(t, strings.HasSuffix(string(message.Value), "\n"))
+			}
+		})
+	}
+
+	_, err := buildKafkaMessages(strings.NewReader("not json\n"), "id", 3)
+	assert.Error(t, err)
+}
+
+func TestKafkaFlagValues(t *testing.T) {
+	flags := utils.KafkaFlagValues{
+		Brokers:     []string{"localhost:9092"},
+		TopicPrefix: "etl.",
+		Topics:      map[string]string{"trades": "dex-trades"},
+		Datasets:    []string{"trades", "accounts"},
+	}
+
+	assert.True(t, flags.Enabled("trades"))
+	assert.False(t, flags.Enabled("operations"))
+	assert.Equal(t, "dex-trades", flags.Topic("trades"))
+	assert.Equal(t, "etl.accounts", flags.Topic("accounts"))
+
+	flags.Datasets = nil
+	assert.True(t, flags.Enabled("operations"))
+	flags.Brokers = nil
+	assert.False(t, flags.Enabled("operations"))
+}
+
+// singlePartitionTransport answers the metadata and produce requests of a
+// kafka.Writer as a broker holding a single partition of every topic would.
+type singlePartitionTransport struct{}
+
+func (singlePartitionTransport) RoundTrip(ctx context.Context, addr net.Addr, req protocol.Message) (protocol.Message, error) {
+	switch req := req.(type) {
+	case *metadata.Request:
+		response := &metadata.Response{}
+		for _, topic := range req.TopicNames {
+			response.Topics = append(response.Topics, metadata.ResponseTopic{
+				Name:       topic,
+				Partitions: []metadata.ResponsePartition{{PartitionIndex: 0}},
+			})
+		}
+		return response, nil
+	case *produce.Request:
+		response := &produce.Response{}
+		for _, topic := range req.Topics {
+			response.Topics = append(response.Topics, produce.ResponseTopic{
+				Topic:      topic.Topic,
+				Partitions: []produce.ResponsePartition{{Partition: 0}},
+			})
+		}
+		return response, nil
+	default:
+		return nil, fmt.Errorf("unexpected %T request", req)
+	}
+}
+
+func TestKafkaPublisherSmallPublish(t *testing.T) {
+	flags := utils.KafkaFlagValues{Brokers: []string{"localhost:9092"}, Acks: "all"}
+	publisher := newKafkaPublisher(flags, "operations")
+	publisher.writer.Transport = singlePartitionTransport{}
+	defer publisher.Close()
+
+	// A ledger with far fewer rows than a batch must not wait for the batch to fill up
+	start := time.Now()
+	published, err := publisher.PublishRows(context.Background(), strings.NewReader("{\"ledger_sequence\":10}\n"), 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, published)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+// TestKafkaPublisherLocalBroker runs against the broker in docker-compose.yaml:
+//
+//	docker-compose up -d kafka
+//	STELLAR_ETL_KAFKA_BROKERS=localhost:9092 go test -run TestKafkaPublisherLocalBroker ./cmd
+func TestKafkaPublisherLocalBroker(t *testing.T) {
+	brokers := os.Getenv("STELLAR_ETL_KAFKA_BROKERS")
+	if brokers == "" {
+		t.Skip("STELLAR_ETL_KAFKA_BROKERS is not set")
+	}
+
+	flags := utils.KafkaFlagValues{
+		Brokers:      strings.Split(brokers, ","),
+		TopicPrefix:  fmt.Sprintf("stellar-etl-test-%d.", time.Now().UnixNano()),
+		PartitionKey: map[string]string{"operations": "transaction_id"},
+		Acks:         "all",
+	}
+	publisher := newKafkaPublisher(flags, "operations")
+	defer publisher.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	_, found, err := publisher.LastPublishedLedger(ctx)
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	for _, seq := range []uint32{10, 11} {
+		rows := fmt.Sprintf("{\"transaction_id\":%d,\"ledger_sequence\":%d}\n", seq, seq)
+		published, err := publisher.PublishRows(ctx, strings.NewReader(rows), seq)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, published)
+	}
+
+	seq, found, err := publisher.LastPublishedLedger(ctx)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, uint32(11), seq)
+}
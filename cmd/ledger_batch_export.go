@@ -3,49 +3,74 @@ package cmd
 import (
 	"context"
 	"os"
-	"path/filepath"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/stellar/go-stellar-sdk/ingest/ledgerbackend"
 	"github.com/stellar/go-stellar-sdk/xdr"
 	"github.com/stellar/stellar-etl/v2/internal/input"
-	"github.com/stellar/stellar-etl/v2/internal/transform"
 	"github.com/stellar/stellar-etl/v2/internal/utils"
 )
 
-// processLedgerFunc transforms a single ledger, writing JSON rows to outFile
-// via ExportEntry and returning any rows that should be collected for Parquet
-// output. attempts and failures are summed across the run by the caller.
+// processLedgerFunc transforms a single ledger, writing every output row to
+// sink. attempts and failures are summed across the run by the caller.
 type processLedgerFunc func(
 	lcm xdr.LedgerCloseMeta,
 	env utils.EnvironmentDetails,
-	outFile *os.File,
-	writeParquet bool,
-	extra map[string]string,
-) (parquetRows []transform.SchemaParquet, attempts int, failures int)
+	sink Sink,
+) (attempts int, failures int)
+
+// batchEndFunc is called once every ledger of a batch has been processed,
+// with the last ledger of the batch, so that exports holding rows back until
+// the end of a batch write them to the batch they belong to.
+type batchEndFunc func(
+	lcm xdr.LedgerCloseMeta,
+	sink Sink,
+) (attempts int, failures int)
 
 // runLedgerBatchExport drives the shared pipeline used by every streaming
-// batch export command: parse flags, prepare the ledger backend, stream batches, and
-// for each batch open an output file, fan the batch's ledgers through process,
-// then close, upload, and optionally write Parquet. Pass nil for parquetSchema
-// if the export has no Parquet output.
+// batch export command: parse flags, prepare the ledger backend, stream
+// batches, and for each batch open the exportName sink, fan the batch's
+// ledgers through process, then close and commit the batch. The sink writes
+// the output files (or stdout, with --stdout) and Parquet files, if enabled,
+// and publishes to Kafka or loads into PostgreSQL when those are configured.
+// Pass nil for parquetSchema if the export has no Parquet output.
 func runLedgerBatchExport(
 	cmd *cobra.Command,
 	exportName string,
 	parquetSchema interface{},
 	process processLedgerFunc,
+) {
+	runLedgerBatchExportWithBatchEnd(cmd, exportName, parquetSchema, process, nil)
+}
+
+// runLedgerBatchExportWithBatchEnd is runLedgerBatchExport for exports that
+// also write rows at the end of every batch, which endBatch does after the
+// last ledger of the batch has been processed and before it is committed.
+// The batches are those actually exported, which start from the resumed
+// ledger with --kafka-resume.
+func runLedgerBatchExportWithBatchEnd(
+	cmd *cobra.Command,
+	exportName string,
+	parquetSchema interface{},
+	process processLedgerFunc,
+	endBatch batchEndFunc,
 ) {
 	cmdLogger.SetLevel(logrus.InfoLevel)
 	commonArgs := utils.MustCommonFlags(cmd.Flags(), cmdLogger)
 	cmdLogger.StrictExport = commonArgs.StrictExport
 	startNum, batchSize, outputFolder, parquetOutputFolder := utils.MustLedgerBatchFlags(cmd.Flags(), cmdLogger)
 	cloudStorageBucket, cloudCredentials, cloudProvider := utils.MustCloudStorageFlags(cmd.Flags(), cmdLogger)
+	kafkaArgs := utils.MustKafkaFlags(cmd.Flags(), cmdLogger)
+	postgresArgs := utils.MustPostgresFlags(cmd.Flags(), cmdLogger)
 	env := utils.GetEnvironmentDetails(commonArgs)
 
 	writeParquet := commonArgs.WriteParquet && parquetSchema != nil
 
-	if err := os.MkdirAll(outputFolder, os.ModePerm); err != nil {
+	if commonArgs.Stdout {
+		// Rows go to stdout, so keep it free of anything else
+		cmdLogger.SetOutput(os.Stderr)
+	} else if err := os.MkdirAll(outputFolder, os.ModePerm); err != nil {
 		cmdLogger.Fatalf("unable to mkdir %s: %v", outputFolder, err)
 	}
 	if writeParquet {
@@ -57,6 +82,21 @@ func runLedgerBatchExport(
 		cmdLogger.Fatalf("batch-size (%d) must be greater than 0", batchSize)
 	}
 
+	publishers := newKafkaPublishers(kafkaArgs)
+	defer publishers.Close()
+	startNum = publishers.resumeLedger([]string{exportName}, startNum)
+
+	loader, err := newPostgresLoader(postgresArgs, commonArgs.Extra)
+	if err != nil {
+		cmdLogger.Fatal(err)
+	}
+	defer loader.Close()
+
+	sinks := newExportSinks(commonArgs, outputFolder, parquetOutputFolder,
+		cloudUpload{credentials: cloudCredentials, bucket: cloudStorageBucket, provider: cloudProvider}, publishers, loader)
+	defer sinks.Close()
+	sink := sinks.sink(exportName, parquetSchema)
+
 	ctx := context.Background()
 	backend, err := utils.CreateLedgerBackend(ctx, commonArgs.UseCaptiveCore, env)
 	if err != nil {
@@ -71,25 +111,29 @@ func runLedgerBatchExport(
 
 	totalAttempts, totalFailures := 0, 0
 	for batch := range batchChan {
-		path := filepath.Join(outputFolder, exportFilename(batch.BatchStart, batch.BatchEnd+1, exportName))
-		outFile := MustOutFile(path)
-		var parquetRows []transform.SchemaParquet
+		if err := sink.OpenBatch(batch.BatchStart, batch.BatchEnd); err != nil {
+			cmdLogger.Fatalf("could not open batch %d-%d: %v", batch.BatchStart, batch.BatchEnd, err)
+		}
 
-		for _, lcm := range batch.Ledgers {
-			rows, attempts, failures := process(lcm, env, outFile, writeParquet, commonArgs.Extra)
+		for i, lcm := range batch.Ledgers {
+			attempts, failures := process(lcm, env, sink)
 			totalAttempts += attempts
 			totalFailures += failures
-			if writeParquet {
-				parquetRows = append(parquetRows, rows...)
+			if endBatch != nil && i == len(batch.Ledgers)-1 {
+				attempts, failures := endBatch(lcm, sink)
+				totalAttempts += attempts
+				totalFailures += failures
+			}
+			if err := commitLedger(sink, lcm.LedgerSequence()); err != nil {
+				cmdLogger.Fatalf("could not commit ledger %d: %v", lcm.LedgerSequence(), err)
 			}
 		}
 
-		outFile.Close()
-		MaybeUpload(cloudCredentials, cloudStorageBucket, cloudProvider, path)
-		if writeParquet {
-			parquetPath := filepath.Join(parquetOutputFolder, exportParquetFilename(batch.BatchStart, batch.BatchEnd+1, exportName))
-			WriteParquet(parquetRows, parquetPath, parquetSchema)
-			MaybeUpload(cloudCredentials, cloudStorageBucket, cloudProvider, parquetPath)
+		if err := sink.CloseBatch(); err != nil {
+			cmdLogger.Fatalf("could not close batch %d-%d: %v", batch.BatchStart, batch.BatchEnd, err)
+		}
+		if err := sink.Commit(); err != nil {
+			cmdLogger.Fatalf("could not commit batch %d-%d: %v", batch.BatchStart, batch.BatchEnd, err)
 		}
 	}
 	PrintTransformStats(totalAttempts, totalFailures)
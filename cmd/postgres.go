@@ -0,0 +1,541 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/guregu/null"
+	"github.com/guregu/null/zero"
+	"github.com/lib/pq"
+	"github.com/stellar/stellar-etl/v2/internal/transform"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+)
+
+// postgresLoadsTable records every ledger range loaded into each table. A
+// rerun of the same range deletes the rows of the previous load before
+// copying the new ones, so batches are replaced rather than duplicated.
+const postgresLoadsTable = "stellar_etl_loads"
+
+// Every loaded row is tagged with the ledger range of the batch it was
+// exported in, which is the unit a rerun replaces.
+const (
+	postgresStartLedgerColumn = "etl_start_ledger"
+	postgresEndLedgerColumn   = "etl_end_ledger"
+)
+
+// postgresDatasets maps the datasets that can be loaded into PostgreSQL to the
+// output struct their table is generated from.
+var postgresDatasets = map[string]interface{}{
+	"ledgers":                     transform.LedgerOutput{},
+	"transactions":                transform.TransactionOutput{},
+	"ledger_transaction":          transform.LedgerTransactionOutput{},
+	"operations":                  transform.OperationOutput{},
+	"effects":                     transform.EffectOutput{},
+	"trades":                      transform.TradeOutput{},
+	"trade_aggregations":          transform.TradeAggregationOutput{},
+	"liquidity_pool_metrics":      transform.LiquidityPoolMetricOutput{},
+	"assets":                      transform.AssetOutput{},
+	"asset_stats":                 transform.AssetStatOutput{},
+	"asset_registry":              transform.AssetRegistryOutput{},
+	"contract_events":             transform.ContractEventOutput{},
+	"contract_invocations":        transform.ContractInvocationOutput{},
+	"soroban_auth_entries":        transform.SorobanAuthEntryOutput{},
+	"transaction_footprints":      transform.TransactionFootprintOutput{},
+	"ttl_extensions":              transform.TtlExtensionOutput{},
+	"archival_events":             transform.ArchivalEventOutput{},
+	"tokens":                      transform.TokenOutput{},
+	"token_transfer":              transform.TokenTransferOutput{},
+	"balance_deltas":              transform.BalanceDeltaOutput{},
+	"balance_discrepancies":       transform.BalanceDiscrepancyOutput{},
+	"accounts":                    transform.AccountOutput{},
+	"account_security":            transform.AccountSecurityOutput{},
+	"account_lifecycle":           transform.AccountLifecycleOutput{},
+	"signers":                     transform.AccountSignerOutput{},
+	"sponsorships":                transform.SponsorshipChangeOutput{},
+	"claimable_balances":          transform.ClaimableBalanceOutput{},
+	"claimable_balance_lifecycle": transform.ClaimableBalanceEventOutput{},
+	"offers":                      transform.OfferOutput{},
+	"trustlines":                  transform.TrustlineOutput{},
+	"liquidity_pools":             transform.PoolOutput{},
+	"contract_data":               transform.ContractDataOutput{},
+	"contract_balances":           transform.ContractBalanceOutput{},
+	"contract_balances_snapshot":  transform.ContractBalanceOutput{},
+	"contract_code":               transform.ContractCodeOutput{},
+	"contract_lineage":            transform.ContractLineageOutput{},
+	"contract_specs":              transform.ContractSpecOutput{},
+	"config_settings":             transform.ConfigSettingOutput{},
+	"ttl":                         transform.TtlOutput{},
+	"restored_key":                transform.RestoredKeyOutput{},
+}
+
+var (
+	timeType        = reflect.TypeOf(time.Time{})
+	nullStringType  = reflect.TypeOf(null.String{})
+	nullIntType     = reflect.TypeOf(null.Int{})
+	nullBoolType    = reflect.TypeOf(null.Bool{})
+	nullFloatType   = reflect.TypeOf(null.Float{})
+	nullTimeType    = reflect.TypeOf(null.Time{})
+	zeroIntType     = reflect.TypeOf(zero.Int{})
+	stringArrayType = reflect.TypeOf(pq.StringArray{})
+)
+
+type postgresColumn struct {
+	Name string
+	Type string
+}
+
+type postgresTable struct {
+	Name    string
+	Columns []postgresColumn
+}
+
+// newPostgresTable builds the table of dataset: one column per JSON field of
+// its output struct, followed by a TEXT column per extra field and the ledger
+// range bookkeeping columns.
+func newPostgresTable(dataset, prefix string, extra []string) (postgresTable, error) {
+	output, ok := postgresDatasets[dataset]
+	if !ok {
+		return postgresTable{}, fmt.Errorf("dataset %s cannot be loaded into postgres", dataset)
+	}
+
+	columns := postgresColumns(reflect.TypeOf(output))
+	seen := map[string]bool{}
+	for _, column := range columns {
+		seen[column.Name] = true
+	}
+	for _, name := range extra {
+		if seen[name] {
+			return postgresTable{}, fmt.Errorf("extra field %s conflicts with a column of %s", name, dataset)
+		}
+		columns = append(columns, postgresColumn{Name: name, Type: "TEXT"})
+	}
+	columns = append(columns,
+		postgresColumn{Name: postgresStartLedgerColumn, Type: "BIGINT NOT NULL"},
+		postgresColumn{Name: postgresEndLedgerColumn, Type: "BIGINT NOT NULL"},
+	)
+
+	return postgresTable{Name: prefix + dataset, Columns: columns}, nil
+}
+
+// postgresColumns returns a column for every field that encoding/json would
+// write for a struct of type t, in field order.
+func postgresColumns(t reflect.Type) []postgresColumn {
+	var columns []postgresColumn
+	for _, field := range jsonFields(t) {
+		columns = append(columns, postgresColumn{Name: field.Name, Type: postgresColumnType(field.Type)})
+	}
+	return columns
+}
+
+// postgresColumnType maps a Go type to the PostgreSQL type of its JSON
+// encoding. Nested structs, maps and interfaces are stored as JSONB.
+func postgresColumnType(t reflect.Type) string {
+	switch t {
+	case timeType, nullTimeType:
+		return "TIMESTAMPTZ"
+	case nullStringType:
+		return "TEXT"
+	case nullIntType, zeroIntType:
+		return "BIGINT"
+	case nullBoolType:
+		return "BOOLEAN"
+	case nullFloatType:
+		return "DOUBLE PRECISION"
+	case stringArrayType:
+		return "TEXT[]"
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return "TEXT"
+	case reflect.Bool:
+		return "BOOLEAN"
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return "INTEGER"
+	case reflect.Int, reflect.Int64, reflect.Uint32:
+		return "BIGINT"
+	case reflect.Uint, reflect.Uint64:
+		return "NUMERIC(20,0)"
+	case reflect.Float32, reflect.Float64:
+		return "DOUBLE PRECISION"
+	case reflect.Slice, reflect.Array:
+		// []byte is encoded as a base64 string
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "TEXT"
+		}
+		elem := postgresColumnType(t.Elem())
+		if elem == "JSONB" || strings.HasSuffix(elem, "[]") {
+			return "JSONB"
+		}
+		return elem + "[]"
+	default:
+		return "JSONB"
+	}
+}
+
+func (t postgresTable) qualifiedName(schema string) string {
+	return pq.QuoteIdentifier(schema) + "." + pq.QuoteIdentifier(t.Name)
+}
+
+func (t postgresTable) columnNames() []string {
+	names := make([]string, len(t.Columns))
+	for i, column := range t.Columns {
+		names[i] = column.Name
+	}
+	return names
+}
+
+// createStatements returns the DDL that creates the table and the index used
+// to replace the rows of a ledger range.
+func (t postgresTable) createStatements(schema string) []string {
+	definitions := make([]string, len(t.Columns))
+	for i, column := range t.Columns {
+		definitions[i] = fmt.Sprintf("    %s %s", pq.QuoteIdentifier(column.Name), column.Type)
+	}
+	return []string{
+		fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n%s\n)", t.qualifiedName(schema), strings.Join(definitions, ",\n")),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s (%s, %s)",
+			pq.QuoteIdentifier(t.Name+"_etl_ledgers_idx"), t.qualifiedName(schema),
+			pq.QuoteIdentifier(postgresStartLedgerColumn), pq.QuoteIdentifier(postgresEndLedgerColumn)),
+	}
+}
+
+func postgresLoadsCreateStatement(schema, prefix string) string {
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s.%s (
+    table_name TEXT NOT NULL,
+    start_ledger BIGINT NOT NULL,
+    end_ledger BIGINT NOT NULL,
+    row_count BIGINT NOT NULL,
+    loaded_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (table_name, start_ledger)
+)`, pq.QuoteIdentifier(schema), pq.QuoteIdentifier(prefix+postgresLoadsTable))
+}
+
+// postgresRowValues converts a JSON row encoded by encodeRow into the
+// values COPY expects for columns. Every value is sent in its text form and
+// parsed by PostgreSQL according to the column type; missing fields are NULL.
+func postgresRowValues(columns []postgresColumn, row []byte, startLedger, endLedger uint32) ([]interface{}, error) {
+	fields := map[string]interface{}{}
+	decoder := json.NewDecoder(bytes.NewReader(row))
+	decoder.UseNumber()
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, fmt.Errorf("could not decode row: %v", err)
+	}
+	fields[postgresStartLedgerColumn] = json.Number(fmt.Sprint(startLedger))
+	fields[postgresEndLedgerColumn] = json.Number(fmt.Sprint(endLedger))
+
+	values := make([]interface{}, len(columns))
+	for i, column := range columns {
+		value, err := postgresValue(column, fields[column.Name])
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for column %s: %v", column.Name, err)
+		}
+		values[i] = value
+		delete(fields, column.Name)
+	}
+	for name := range fields {
+		return nil, fmt.Errorf("row has field %s which is not a column of the table", name)
+	}
+	return values, nil
+}
+
+func postgresValue(column postgresColumn, value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	if column.Type == "JSONB" {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		return string(encoded), nil
+	}
+
+	if strings.HasSuffix(column.Type, "[]") {
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected an array, got %v", value)
+		}
+		elems := make(pq.StringArray, len(items))
+		for i, item := range items {
+			elem, err := postgresScalar(item)
+			if err != nil {
+				return nil, err
+			}
+			elems[i] = elem
+		}
+		return elems.Value()
+	}
+
+	return postgresScalar(value)
+}
+
+func postgresScalar(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return fmt.Sprint(v), nil
+	default:
+		return "", fmt.Errorf("expected a scalar, got %v", value)
+	}
+}
+
+// PostgresLoader bulk loads exported batch files into PostgreSQL.
+type PostgresLoader struct {
+	db     *sql.DB
+	flags  utils.PostgresFlagValues
+	extra  []string
+	tables map[string]postgresTable
+}
+
+// newPostgresLoader connects to the database in flags. It returns nil if no
+// DSN is configured.
+func newPostgresLoader(flags utils.PostgresFlagValues, extra map[string]string) (*PostgresLoader, error) {
+	if flags.DSN == "" {
+		return nil, nil
+	}
+
+	db, err := sql.Open("postgres", flags.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("could not open postgres connection: %v", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("could not connect to postgres: %v", err)
+	}
+
+	extraFields := make([]string, 0, len(extra))
+	for name := range extra {
+		extraFields = append(extraFields, name)
+	}
+	sort.Strings(extraFields)
+
+	if flags.CreateTables {
+		if _, err := db.Exec(postgresLoadsCreateStatement(flags.Schema, flags.TablePrefix)); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("could not create %s table: %v", postgresLoadsTable, err)
+		}
+	}
+
+	return &PostgresLoader{db: db, flags: flags, extra: extraFields, tables: map[string]postgresTable{}}, nil
+}
+
+// table returns the table of dataset, creating it on first use if
+// postgres-create-tables is set.
+func (p *PostgresLoader) table(ctx context.Context, dataset string) (postgresTable, error) {
+	if table, ok := p.tables[dataset]; ok {
+		return table, nil
+	}
+
+	table, err := newPostgresTable(dataset, p.flags.TablePrefix, p.extra)
+	if err != nil {
+		return postgresTable{}, err
+	}
+	if p.flags.CreateTables {
+		for _, statement := range table.createStatements(p.flags.Schema) {
+			if _, err := p.db.ExecContext(ctx, statement); err != nil {
+				return postgresTable{}, fmt.Errorf("could not create table %s: %v", table.Name, err)
+			}
+		}
+	}
+	p.tables[dataset] = table
+	return table, nil
+}
+
+// Load replaces the rows previously loaded for the ledger range
+// [startLedger, endLedger] of dataset with the newline-delimited JSON rows
+// read from rows, in a single transaction. Loading a range that partially
+// overlaps a previously loaded one is an error, since the rows of the old
+// range cannot be split.
+func (p *PostgresLoader) Load(ctx context.Context, dataset string, rows io.Reader, startLedger, endLedger uint32) error {
+	table, err := p.table(ctx, dataset)
+	if err != nil {
+		return err
+	}
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("could not begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	rowCount, err := p.replaceRange(ctx, tx, table, rows, startLedger, endLedger)
+	if err != nil {
+		return fmt.Errorf("could not load ledgers %d-%d into %s: %v", startLedger, endLedger, table.Name, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not commit load of ledgers %d-%d into %s: %v", startLedger, endLedger, table.Name, err)
+	}
+	cmdLogger.Infof("Loaded %d rows of ledgers %d-%d into %s", rowCount, startLedger, endLedger, table.Name)
+	return nil
+}
+
+func (p *PostgresLoader) replaceRange(ctx context.Context, tx *sql.Tx, table postgresTable, rows io.Reader, startLedger, endLedger uint32) (int64, error) {
+	loads := pq.QuoteIdentifier(p.flags.Schema) + "." + pq.QuoteIdentifier(p.flags.TablePrefix+postgresLoadsTable)
+
+	// Serialize loads of the same table so the overlap check below holds
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", table.Name); err != nil {
+		return 0, err
+	}
+
+	var overlapStart, overlapEnd uint32
+	err := tx.QueryRowContext(ctx, fmt.Sprintf(
+		`SELECT start_ledger, end_ledger FROM %s
+		WHERE table_name = $1 AND start_ledger <= $3 AND end_ledger >= $2 AND (start_ledger < $2 OR end_ledger > $3)
+		LIMIT 1`, loads), table.Name, startLedger, endLedger).Scan(&overlapStart, &overlapEnd)
+	if err == nil {
+		return 0, fmt.Errorf("ledgers %d-%d partially overlap the previously loaded ledgers %d-%d; rerun with the same batch boundaries",
+			startLedger, endLedger, overlapStart, overlapEnd)
+	}
+	if err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE %s >= $1 AND %s <= $2",
+		table.qualifiedName(p.flags.Schema), pq.QuoteIdentifier(postgresStartLedgerColumn), pq.QuoteIdentifier(postgresEndLedgerColumn)),
+		startLedger, endLedger); err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE table_name = $1 AND start_ledger >= $2 AND end_ledger <= $3", loads),
+		table.Name, startLedger, endLedger); err != nil {
+		return 0, err
+	}
+
+	rowCount, err := copyRows(ctx, tx, p.flags.Schema, table, rows, startLedger, endLedger)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s (table_name, start_ledger, end_ledger, row_count) VALUES ($1, $2, $3, $4)", loads),
+		table.Name, startLedger, endLedger, rowCount); err != nil {
+		return 0, err
+	}
+	return rowCount, nil
+}
+
+// copyRows streams the newline-delimited JSON rows into table with COPY.
+func copyRows(ctx context.Context, tx *sql.Tx, schema string, table postgresTable, rows io.Reader, startLedger, endLedger uint32) (int64, error) {
+	stmt, err := tx.PrepareContext(ctx, pq.CopyInSchema(schema, table.Name, table.columnNames()...))
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	var rowCount int64
+	reader := bufio.NewReader(rows)
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			values, err := postgresRowValues(table.Columns, line, startLedger, endLedger)
+			if err != nil {
+				return 0, err
+			}
+			if _, err := stmt.ExecContext(ctx, values...); err != nil {
+				return 0, err
+			}
+			rowCount++
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return 0, readErr
+		}
+	}
+
+	// An Exec without arguments flushes the buffered COPY data
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return 0, err
+	}
+	return rowCount, nil
+}
+
+func (p *PostgresLoader) Close() {
+	if p == nil {
+		return
+	}
+	if err := p.db.Close(); err != nil {
+		cmdLogger.Errorf("could not close postgres connection: %v", err)
+	}
+}
+
+// postgresSink spools the rows of a batch to a temporary file and loads them
+// with PostgresLoader.Load when the batch is committed.
+type postgresSink struct {
+	loader  *PostgresLoader
+	dataset string
+	extra   map[string]string
+
+	spool      *os.File
+	buffered   *bufio.Writer
+	start, end uint32
+}
+
+func newPostgresSink(loader *PostgresLoader, dataset string, extra map[string]string) *postgresSink {
+	return &postgresSink{loader: loader, dataset: dataset, extra: extra}
+}
+
+func (p *postgresSink) OpenBatch(start, end uint32) error {
+	// The spool of a batch closed without being committed is left over
+	p.removeSpool()
+	spool, err := os.CreateTemp("", "stellar-etl-postgres-*.txt")
+	if err != nil {
+		return fmt.Errorf("could not create postgres spool file: %v", err)
+	}
+	p.spool, p.buffered = spool, bufio.NewWriter(spool)
+	p.start, p.end = start, end
+	return nil
+}
+
+func (p *postgresSink) WriteRow(row interface{}) error {
+	encoded, err := encodeRow(row, p.extra)
+	if err != nil {
+		return err
+	}
+	if _, err := p.buffered.Write(append(encoded, '\n')); err != nil {
+		return fmt.Errorf("could not write to postgres spool file: %v", err)
+	}
+	return nil
+}
+
+func (p *postgresSink) CloseBatch() error {
+	if err := p.buffered.Flush(); err != nil {
+		return fmt.Errorf("could not write to postgres spool file: %v", err)
+	}
+	_, err := p.spool.Seek(0, io.SeekStart)
+	return err
+}
+
+func (p *postgresSink) Commit() error {
+	defer p.removeSpool()
+	return p.loader.Load(context.Background(), p.dataset, p.spool, p.start, p.end)
+}
+
+func (p *postgresSink) removeSpool() {
+	if p.spool == nil {
+		return
+	}
+	p.spool.Close()
+	os.Remove(p.spool.Name())
+	p.spool = nil
+}
+
+func (p *postgresSink) Close() error {
+	p.removeSpool()
+	return nil
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"bytes"
 	"os"
 	"testing"
 
@@ -52,12 +53,11 @@ func TestProcessLedger_TransformFailureIsFatal(t *testing.T) {
 		},
 	}
 
-	outFile, err := os.CreateTemp(t.TempDir(), "processLedger-fatal-*.txt")
-	assert.NoError(t, err)
-	defer outFile.Close()
+	var rows bytes.Buffer
+	sink := newWriterSink(&rows, nil)
 
 	assert.PanicsWithValue(t, "exit called", func() {
-		processLedger(badLCM, utils.EnvironmentDetails{}, outFile, false, nil)
+		processLedger(badLCM, utils.EnvironmentDetails{}, sink)
 	}, "processLedger must be fatal on transform failure under StrictExport")
 
 	assert.True(t, exitCalled, "expected transform failure to invoke logger exit")
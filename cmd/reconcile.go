@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-etl/v2/internal/input"
+	"github.com/stellar/stellar-etl/v2/internal/toid"
+	"github.com/stellar/stellar-etl/v2/internal/transform"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+)
+
+var reconcileCmd = &cobra.Command{
+	Use:   "reconcile",
+	Short: "Reconciles the token transfers with the balance changes over a specified range.",
+	Long: `Sums the token transfer events of every transaction over a specified range
+per address and asset, and compares the sums with the balance deltas read from
+the account, trustline and contract balance entries the transaction changed.
+One row is exported per transaction, address and asset whose transfers do not
+add up to the change of its balance. Ledgers are processed in batches of
+batch-size; each batch produces one file named
+{start}-{end}-balance_discrepancies.txt in the output folder.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runLedgerBatchExport(cmd, "balance_discrepancies", nil, processBalanceDiscrepancies)
+	},
+}
+
+func processBalanceDiscrepancies(lcm xdr.LedgerCloseMeta, env utils.EnvironmentDetails, sink Sink) (int, int) {
+	transfers, err := transform.TransformUnverifiedTokenTransfer(lcm, env.NetworkPassphrase)
+	if err != nil {
+		cmdLogger.LogError(fmt.Errorf("could not transform token transfers for ledger %d: %v", lcm.LedgerSequence(), err))
+		return 1, 1
+	}
+	txInputs, err := input.TransactionsFromLedger(lcm, env.NetworkPassphrase)
+	if err != nil {
+		cmdLogger.LogError(fmt.Errorf("could not read transactions from ledger %d: %v", lcm.LedgerSequence(), err))
+		return 1, 1
+	}
+
+	attempts, failures := 0, 0
+	deltas := []transform.BalanceDeltaOutput{}
+	skipped := map[int64]bool{}
+	for _, txInput := range txInputs {
+		attempts++
+		transactionDeltas, err := transform.TransformBalanceDeltas(txInput.Transaction, txInput.LedgerHistory, env.NetworkPassphrase)
+		if err != nil {
+			cmdLogger.LogError(fmt.Errorf("could not transform balance deltas of transaction %d in ledger %d: %v", txInput.Transaction.Index, lcm.LedgerSequence(), err))
+			failures++
+			// The transfers of the transaction would all look like discrepancies
+			skipped[toid.New(int32(lcm.LedgerSequence()), int32(txInput.Transaction.Index), 0).ToInt64()] = true
+			continue
+		}
+		deltas = append(deltas, transactionDeltas...)
+	}
+	reconciled := make([]transform.TokenTransferOutput, 0, len(transfers))
+	for _, transfer := range transfers {
+		if !skipped[transfer.TransactionID] {
+			reconciled = append(reconciled, transfer)
+		}
+	}
+
+	discrepancies, err := transform.ReconcileBalances(reconciled, deltas)
+	if err != nil {
+		cmdLogger.LogError(fmt.Errorf("could not reconcile balances of ledger %d: %v", lcm.LedgerSequence(), err))
+		return attempts, attempts
+	}
+	for _, discrepancy := range discrepancies {
+		if err := sink.WriteRow(discrepancy); err != nil {
+			cmdLogger.LogError(fmt.Errorf("could not export balance discrepancy: %v", err))
+			failures++
+		}
+	}
+	return attempts, failures
+}
+
+func init() {
+	rootCmd.AddCommand(reconcileCmd)
+	utils.AddCommonFlags(reconcileCmd.Flags())
+	utils.AddLedgerBatchFlags("balance_discrepancies", reconcileCmd.Flags(), "exported_balance_discrepancies/")
+	utils.AddCloudStorageFlags(reconcileCmd.Flags())
+	utils.AddKafkaFlags(reconcileCmd.Flags())
+	utils.AddPostgresFlags(reconcileCmd.Flags())
+	reconcileCmd.MarkFlagRequired("start-ledger")
+	reconcileCmd.MarkFlagRequired("end-ledger")
+}
@@ -0,0 +1,278 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/stellar/go-stellar-sdk/ingest/ledgerbackend"
+	"github.com/stellar/go-stellar-sdk/support/datastore"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-etl/v2/internal/input"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serves the transformed data of individual ledgers over HTTP.",
+	Long: `Starts an HTTP server that keeps the ledger datastore open and transforms
+single ledgers on demand. Every endpoint responds with newline-delimited JSON
+containing exactly the rows the matching export command would write for that
+ledger:
+
+  GET /ledgers/{seq}                         export_ledgers
+  GET /ledgers/{seq}/transactions            export_transactions
+  GET /ledgers/{seq}/operations              export_operations
+  GET /ledgers/{seq}/effects                 export_effects
+  GET /ledgers/{seq}/trades                  export_trades
+  GET /ledgers/{seq}/contract_events         export_contract_events
+  GET /ledgers/{seq}/contract_invocations    export_contract_invocations
+  GET /ledgers/{seq}/soroban_auth_entries    export_soroban_auth_entries
+  GET /ledgers/{seq}/transaction_footprints  export_transaction_footprints
+  GET /ledgers/{seq}/ttl_extensions          export_ttl_extensions
+  GET /ledgers/{seq}/contract_lineage        export_contract_lineage
+  GET /ledgers/{seq}/token_transfers         export_token_transfer
+  GET /ledgers/{seq}/balance_deltas          export_balance_deltas
+  GET /ledgers/{seq}/balance_discrepancies   reconcile
+  GET /ledgers/{seq}/sponsorships            export_sponsorships
+  GET /ledgers/{seq}/changes                 export_ledger_entry_changes
+
+The changes endpoint returns the rows of every resource (accounts, signers,
+trustlines, ...) in resource name order. Pass type=accounts,trustlines to
+restrict it to a subset of resources.
+
+Transform errors are logged and counted in the X-Failed-Transforms response
+header instead of stopping the server. Ledgers missing from the datastore are
+answered with 404, and ledgers that could not be read with 500.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmdLogger.SetLevel(logrus.InfoLevel)
+		commonArgs := utils.MustCommonFlags(cmd.Flags(), cmdLogger)
+		// A single bad ledger should not take the whole server down
+		cmdLogger.StrictExport = false
+		env := utils.GetEnvironmentDetails(commonArgs)
+
+		address, err := cmd.Flags().GetString("address")
+		if err != nil {
+			cmdLogger.Fatal("could not get address: ", err)
+		}
+
+		if commonArgs.UseCaptiveCore {
+			cmdLogger.Fatal("serve reads ledgers out of order and only supports the datastore backend")
+		}
+
+		ctx := context.Background()
+		dataStore, dataStoreConfig, err := utils.CreateDatastore(ctx, env)
+		if err != nil {
+			cmdLogger.Fatal("could not create datastore: ", err)
+		}
+		defer dataStore.Close()
+
+		schema, err := datastore.LoadSchema(ctx, dataStore, dataStoreConfig)
+		if err != nil {
+			cmdLogger.Fatal("could not load datastore schema: ", err)
+		}
+
+		server := &ledgerServer{
+			source: &datastoreLedgerSource{
+				dataStore: dataStore,
+				schema:    schema,
+				config:    utils.GetBufferedStorageBackendConfig(env),
+			},
+			env:   env,
+			extra: commonArgs.Extra,
+		}
+
+		cmdLogger.Infof("Serving ledger data on %s", address)
+		if err := http.ListenAndServe(address, server.routes()); err != nil {
+			cmdLogger.Fatal("server stopped: ", err)
+		}
+	},
+}
+
+// errLedgerNotFound is returned by ledger sources for ledgers they do not
+// hold, as opposed to ledgers they could not read.
+var errLedgerNotFound = errors.New("ledger not found")
+
+// ledgerSource fetches single ledgers by sequence number, in any order.
+// Ledgers it does not hold are reported with an error wrapping
+// errLedgerNotFound.
+type ledgerSource interface {
+	GetLedger(ctx context.Context, seq uint32) (xdr.LedgerCloseMeta, error)
+}
+
+// datastoreLedgerSource keeps the datastore open for the lifetime of the
+// server. BufferedStorageBackend can only move forward through its prepared
+// range, so a short-lived backend prepared for exactly one ledger is created
+// for every request.
+type datastoreLedgerSource struct {
+	dataStore datastore.DataStore
+	schema    datastore.DataStoreSchema
+	config    ledgerbackend.BufferedStorageBackendConfig
+}
+
+func (d *datastoreLedgerSource) GetLedger(ctx context.Context, seq uint32) (xdr.LedgerCloseMeta, error) {
+	// The backend reports missing files like any other failure, so look for
+	// the file holding the ledger first
+	exists, err := d.dataStore.Exists(ctx, d.schema.GetObjectKeyFromSequenceNumber(seq))
+	if err != nil {
+		return xdr.LedgerCloseMeta{}, err
+	}
+	if !exists {
+		return xdr.LedgerCloseMeta{}, fmt.Errorf("ledger %d: %w", seq, errLedgerNotFound)
+	}
+
+	backend, err := ledgerbackend.NewBufferedStorageBackend(d.config, d.dataStore, d.schema)
+	if err != nil {
+		return xdr.LedgerCloseMeta{}, err
+	}
+	defer backend.Close()
+
+	if err := backend.PrepareRange(ctx, ledgerbackend.BoundedRange(seq, seq)); err != nil {
+		return xdr.LedgerCloseMeta{}, err
+	}
+	return backend.GetLedger(ctx, seq)
+}
+
+type ledgerServer struct {
+	source ledgerSource
+	env    utils.EnvironmentDetails
+	extra  map[string]string
+}
+
+func (s *ledgerServer) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /ledgers/{seq}", s.handleProcess(processLedger))
+	mux.HandleFunc("GET /ledgers/{seq}/transactions", s.handleProcess(processTransactions))
+	mux.HandleFunc("GET /ledgers/{seq}/operations", s.handleProcess(processOperations))
+	mux.HandleFunc("GET /ledgers/{seq}/effects", s.handleProcess(processEffects))
+	mux.HandleFunc("GET /ledgers/{seq}/trades", s.handleProcess(processTrades))
+	mux.HandleFunc("GET /ledgers/{seq}/contract_events", s.handleProcess(newContractEventsProcessor(nil)))
+	mux.HandleFunc("GET /ledgers/{seq}/contract_invocations", s.handleProcess(newContractInvocationsProcessor(nil)))
+	mux.HandleFunc("GET /ledgers/{seq}/soroban_auth_entries", s.handleProcess(processSorobanAuthEntries))
+	mux.HandleFunc("GET /ledgers/{seq}/transaction_footprints", s.handleProcess(processTransactionFootprints))
+	mux.HandleFunc("GET /ledgers/{seq}/ttl_extensions", s.handleProcess(processTtlExtensions))
+	mux.HandleFunc("GET /ledgers/{seq}/contract_lineage", s.handleProcess(processContractLineage))
+	mux.HandleFunc("GET /ledgers/{seq}/token_transfers", s.handleProcess(processTokenTransfers))
+	mux.HandleFunc("GET /ledgers/{seq}/balance_deltas", s.handleProcess(processBalanceDeltas))
+	mux.HandleFunc("GET /ledgers/{seq}/balance_discrepancies", s.handleProcess(processBalanceDiscrepancies))
+	mux.HandleFunc("GET /ledgers/{seq}/sponsorships", s.handleProcess(processSponsorships))
+	mux.HandleFunc("GET /ledgers/{seq}/changes", s.handleChanges)
+	return mux
+}
+
+// handleProcess serves the rows that process writes for the requested ledger.
+func (s *ledgerServer) handleProcess(process processLedgerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		lcm, ok := s.getLedger(w, r)
+		if !ok {
+			return
+		}
+
+		var rows bytes.Buffer
+		sink := newWriterSink(&rows, s.extra)
+		attempts, failures := process(lcm, s.env, sink)
+		writeServedRows(w, sink, &rows, attempts, failures)
+	}
+}
+
+// handleChanges serves the ledger entry changes of the requested ledger.
+func (s *ledgerServer) handleChanges(w http.ResponseWriter, r *http.Request) {
+	lcm, ok := s.getLedger(w, r)
+	if !ok {
+		return
+	}
+
+	batch, err := input.ChangesFromLedger(lcm, s.env, cmdLogger)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	exports := map[string]bool{}
+	for flagName := range changeExportMapping {
+		exports[flagName] = true
+	}
+	transformedOutputs := transformChangeBatch(batch, exports, s.env, nil)
+
+	resources := make([]string, 0, len(transformedOutputs))
+	if requested := r.URL.Query().Get("type"); requested != "" {
+		for _, resource := range strings.Split(requested, ",") {
+			if _, ok := transformedOutputs[resource]; !ok {
+				http.Error(w, fmt.Sprintf("unknown change type %q", resource), http.StatusBadRequest)
+				return
+			}
+			resources = append(resources, resource)
+		}
+	} else {
+		for resource := range transformedOutputs {
+			resources = append(resources, resource)
+		}
+	}
+	sort.Strings(resources)
+
+	var rows bytes.Buffer
+	sink := newWriterSink(&rows, s.extra)
+	attempts, failures := 0, 0
+	for _, resource := range resources {
+		for _, o := range transformedOutputs[resource] {
+			attempts++
+			if err := sink.WriteRow(o); err != nil {
+				cmdLogger.LogError(fmt.Errorf("could not export %s change: %v", resource, err))
+				failures++
+			}
+		}
+	}
+	writeServedRows(w, sink, &rows, attempts, failures)
+}
+
+// getLedger parses the ledger sequence out of the request path and fetches it,
+// writing an error response and returning false if either step fails. Missing
+// ledgers are answered with 404, and ledgers that could not be read with 500.
+func (s *ledgerServer) getLedger(w http.ResponseWriter, r *http.Request) (xdr.LedgerCloseMeta, bool) {
+	seq, err := strconv.ParseUint(r.PathValue("seq"), 10, 32)
+	if err != nil || seq == 0 {
+		http.Error(w, fmt.Sprintf("invalid ledger sequence %q", r.PathValue("seq")), http.StatusBadRequest)
+		return xdr.LedgerCloseMeta{}, false
+	}
+
+	lcm, err := s.source.GetLedger(r.Context(), uint32(seq))
+	if errors.Is(err, errLedgerNotFound) {
+		http.Error(w, fmt.Sprintf("could not get ledger %d: %v", seq, err), http.StatusNotFound)
+		return xdr.LedgerCloseMeta{}, false
+	}
+	if err != nil {
+		cmdLogger.Errorf("could not get ledger %d: %v", seq, err)
+		http.Error(w, fmt.Sprintf("could not get ledger %d: %v", seq, err), http.StatusInternalServerError)
+		return xdr.LedgerCloseMeta{}, false
+	}
+	return lcm, true
+}
+
+// writeServedRows flushes sink and copies the rows it wrote into the response.
+func writeServedRows(w http.ResponseWriter, sink Sink, rows io.Reader, attempts, failures int) {
+	if err := sink.CloseBatch(); err != nil {
+		http.Error(w, fmt.Sprintf("could not write rows: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("X-Attempted-Transforms", strconv.Itoa(attempts))
+	w.Header().Set("X-Failed-Transforms", strconv.Itoa(failures))
+	if _, err := io.Copy(w, rows); err != nil {
+		cmdLogger.Errorf("could not write response: %v", err)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+	utils.AddCommonFlags(serveCmd.Flags())
+	serveCmd.Flags().String("address", ":8080", "Address the HTTP server listens on")
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeLedgerSource struct {
+	ledgers map[uint32]xdr.LedgerCloseMeta
+	broken  map[uint32]bool
+}
+
+func (f fakeLedgerSource) GetLedger(_ context.Context, seq uint32) (xdr.LedgerCloseMeta, error) {
+	if f.broken[seq] {
+		return xdr.LedgerCloseMeta{}, fmt.Errorf("could not decode ledger %d", seq)
+	}
+	lcm, ok := f.ledgers[seq]
+	if !ok {
+		return xdr.LedgerCloseMeta{}, fmt.Errorf("ledger %d: %w", seq, errLedgerNotFound)
+	}
+	return lcm, nil
+}
+
+func makeServeTestLedger(seq uint32) xdr.LedgerCloseMeta {
+	return xdr.LedgerCloseMeta{
+		V: 1,
+		V1: &xdr.LedgerCloseMetaV1{
+			LedgerHeader: xdr.LedgerHeaderHistoryEntry{
+				Header: xdr.LedgerHeader{
+					LedgerSeq: xdr.Uint32(seq),
+					ScpValue:  xdr.StellarValue{CloseTime: 1000},
+				},
+			},
+			TxSet: xdr.GeneralizedTransactionSet{
+				V:       1,
+				V1TxSet: &xdr.TransactionSetV1{Phases: []xdr.TransactionPhase{}},
+			},
+			Ext: xdr.LedgerCloseMetaExt{
+				V:  1,
+				V1: &xdr.LedgerCloseMetaExtV1{},
+			},
+		},
+	}
+}
+
+func TestServeLedgerRoutes(t *testing.T) {
+	server := &ledgerServer{
+		source: fakeLedgerSource{ledgers: map[uint32]xdr.LedgerCloseMeta{
+			5: makeServeTestLedger(5),
+		}, broken: map[uint32]bool{7: true}},
+		env:   utils.EnvironmentDetails{NetworkPassphrase: "test passphrase"},
+		extra: map[string]string{"batch_id": "serve"},
+	}
+	handler := server.routes()
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantRows   int
+	}{
+		{"invalid sequence", "/ledgers/abc/transactions", http.StatusBadRequest, 0},
+		{"missing ledger", "/ledgers/6/transactions", http.StatusNotFound, 0},
+		{"backend error", "/ledgers/7/transactions", http.StatusInternalServerError, 0},
+		{"ledger", "/ledgers/5", http.StatusOK, 1},
+		{"empty ledger transactions", "/ledgers/5/transactions", http.StatusOK, 0},
+		{"empty ledger changes", "/ledgers/5/changes", http.StatusOK, 0},
+		{"unknown change type", "/ledgers/5/changes?type=accounts,bogus", http.StatusBadRequest, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, test.path, nil))
+			assert.Equal(t, test.wantStatus, recorder.Code)
+			if test.wantStatus != http.StatusOK {
+				return
+			}
+
+			assert.Equal(t, "application/x-ndjson", recorder.Header().Get("Content-Type"))
+			body := strings.TrimSpace(recorder.Body.String())
+			var rows []string
+			if body != "" {
+				rows = strings.Split(body, "\n")
+			}
+			assert.Len(t, rows, test.wantRows)
+			for _, row := range rows {
+				decoded := map[string]interface{}{}
+				assert.NoError(t, json.Unmarshal([]byte(row), &decoded))
+				assert.Equal(t, "serve", decoded["batch_id"])
+			}
+		})
+	}
+}
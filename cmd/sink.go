@@ -0,0 +1,518 @@
+package cmd
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stellar/stellar-etl/v2/internal/transform"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+)
+
+// Sink is the destination of the rows of a single dataset. The export drivers
+// call OpenBatch before the first row of every batch, WriteRow for each row,
+// CloseBatch once every row of the batch has been written and Commit to hand
+// the closed batch over to its final destination (uploading files, loading
+// tables, ...). Close releases the sink at the end of the run.
+type Sink interface {
+	OpenBatch(start, end uint32) error
+	WriteRow(row interface{}) error
+	CloseBatch() error
+	Commit() error
+	Close() error
+}
+
+// ledgerCommitter is implemented by sinks that deliver the rows of a batch
+// ledger by ledger. CommitLedger is called once all the rows of ledger seq
+// have been written; batch drivers that do not work ledger by ledger only
+// call Commit.
+type ledgerCommitter interface {
+	CommitLedger(seq uint32) error
+}
+
+type jsonField struct {
+	Name string
+	Type reflect.Type
+}
+
+// jsonFields returns the fields that encoding/json writes for a struct of
+// type t, in field order.
+func jsonFields(t reflect.Type) []jsonField {
+	var fields []jsonField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, jsonField{Name: name, Type: field.Type})
+	}
+	return fields
+}
+
+// multiSink fans every call out to all of its sinks, in order.
+type multiSink []Sink
+
+func (m multiSink) OpenBatch(start, end uint32) error {
+	for _, sink := range m {
+		if err := sink.OpenBatch(start, end); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m multiSink) WriteRow(row interface{}) error {
+	for _, sink := range m {
+		if err := sink.WriteRow(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m multiSink) CommitLedger(seq uint32) error {
+	for _, sink := range m {
+		if committer, ok := sink.(ledgerCommitter); ok {
+			if err := committer.CommitLedger(seq); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (m multiSink) CloseBatch() error {
+	for _, sink := range m {
+		if err := sink.CloseBatch(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m multiSink) Commit() error {
+	for _, sink := range m {
+		if err := sink.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m multiSink) Close() error {
+	var firstErr error
+	for _, sink := range m {
+		if err := sink.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// cloudUpload holds the cloud storage flags used to upload committed files.
+type cloudUpload struct {
+	credentials, bucket, provider string
+}
+
+// fileSink writes every batch to its own {start}-{end}-{dataset} file in
+// folder, as newline-delimited JSON or CSV, optionally compressed. Committed
+// files are uploaded to cloud storage if a provider is configured.
+type fileSink struct {
+	folder      string
+	dataset     string
+	format      string
+	compression string
+	extra       map[string]string
+	upload      cloudUpload
+
+	path       string
+	file       *os.File
+	compressor io.WriteCloser
+	buffered   *bufio.Writer
+	csvWriter  *csv.Writer
+	csvColumns []string
+}
+
+func newFileSink(folder, dataset, format, compression string, extra map[string]string, upload cloudUpload) *fileSink {
+	return &fileSink{
+		folder:      folder,
+		dataset:     dataset,
+		format:      format,
+		compression: compression,
+		extra:       extra,
+		upload:      upload,
+	}
+}
+
+func (f *fileSink) extension() string {
+	ext := ".txt"
+	if f.format == "csv" {
+		ext = ".csv"
+	}
+	switch f.compression {
+	case "gzip":
+		ext += ".gz"
+	case "zstd":
+		ext += ".zst"
+	}
+	return ext
+}
+
+func (f *fileSink) OpenBatch(start, end uint32) error {
+	// Filenames are exclusive of the end point, while batches include it
+	name := strings.TrimSuffix(exportFilename(start, end+1, f.dataset), ".txt") + f.extension()
+	f.path = filepath.Join(f.folder, name)
+	f.file = MustOutFile(f.path)
+
+	var w io.Writer = f.file
+	f.compressor = nil
+	switch f.compression {
+	case "gzip":
+		f.compressor = gzip.NewWriter(f.file)
+	case "zstd":
+		encoder, err := zstd.NewWriter(f.file)
+		if err != nil {
+			return fmt.Errorf("could not create zstd writer for %s: %v", f.path, err)
+		}
+		f.compressor = encoder
+	}
+	if f.compressor != nil {
+		w = f.compressor
+	}
+	f.buffered = bufio.NewWriter(w)
+	f.csvWriter = nil
+	f.csvColumns = nil
+	return nil
+}
+
+func (f *fileSink) WriteRow(row interface{}) error {
+	if f.format == "csv" {
+		return f.writeCSVRow(row)
+	}
+
+	encoded, err := encodeRow(row, f.extra)
+	if err != nil {
+		return err
+	}
+	cmdLogger.Debugf("Writing entry to %s", f.path)
+	if _, err := f.buffered.Write(append(encoded, '\n')); err != nil {
+		return fmt.Errorf("could not write to %s: %v", f.path, err)
+	}
+	return nil
+}
+
+// writeCSVRow writes row as a CSV record. The header is taken from the JSON
+// fields of the first row of the batch followed by the extra fields; nested
+// values are written as JSON.
+func (f *fileSink) writeCSVRow(row interface{}) error {
+	fields, err := rowFields(row, f.extra)
+	if err != nil {
+		return err
+	}
+
+	if f.csvWriter == nil {
+		f.csvWriter = csv.NewWriter(f.buffered)
+		f.csvColumns = csvColumns(row, fields, f.extra)
+		if err := f.csvWriter.Write(f.csvColumns); err != nil {
+			return fmt.Errorf("could not write csv header to %s: %v", f.path, err)
+		}
+	}
+
+	record := make([]string, len(f.csvColumns))
+	for i, column := range f.csvColumns {
+		record[i], err = csvValue(fields[column])
+		if err != nil {
+			return fmt.Errorf("invalid value for column %s: %v", column, err)
+		}
+	}
+	if err := f.csvWriter.Write(record); err != nil {
+		return fmt.Errorf("could not write to %s: %v", f.path, err)
+	}
+	return nil
+}
+
+func csvColumns(row interface{}, fields map[string]interface{}, extra map[string]string) []string {
+	var columns []string
+	seen := map[string]bool{}
+	if t := reflect.TypeOf(row); t.Kind() == reflect.Struct {
+		for _, field := range jsonFields(t) {
+			columns = append(columns, field.Name)
+			seen[field.Name] = true
+		}
+	}
+
+	var rest []string
+	for name := range fields {
+		if !seen[name] {
+			if _, isExtra := extra[name]; !isExtra {
+				rest = append(rest, name)
+			}
+		}
+	}
+	sort.Strings(rest)
+	columns = append(columns, rest...)
+
+	extraNames := make([]string, 0, len(extra))
+	for name := range extra {
+		if !seen[name] {
+			extraNames = append(extraNames, name)
+		}
+	}
+	sort.Strings(extraNames)
+	return append(columns, extraNames...)
+}
+
+func csvValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return fmt.Sprint(v), nil
+	default:
+		encoded, err := json.Marshal(v)
+		return string(encoded), err
+	}
+}
+
+func (f *fileSink) CloseBatch() error {
+	if f.csvWriter != nil {
+		f.csvWriter.Flush()
+		if err := f.csvWriter.Error(); err != nil {
+			return fmt.Errorf("could not write to %s: %v", f.path, err)
+		}
+	}
+	if err := f.buffered.Flush(); err != nil {
+		return fmt.Errorf("could not write to %s: %v", f.path, err)
+	}
+	if f.compressor != nil {
+		if err := f.compressor.Close(); err != nil {
+			return fmt.Errorf("could not finish compressing %s: %v", f.path, err)
+		}
+	}
+	return f.file.Close()
+}
+
+func (f *fileSink) Commit() error {
+	MaybeUpload(f.upload.credentials, f.upload.bucket, f.upload.provider, f.path)
+	return nil
+}
+
+func (f *fileSink) Close() error {
+	return nil
+}
+
+// parquetSink collects the rows of a batch and writes them to a
+// {start}-{end}-{dataset}.parquet file in folder when the batch is closed.
+type parquetSink struct {
+	folder  string
+	dataset string
+	schema  interface{}
+	upload  cloudUpload
+
+	path string
+	rows []transform.SchemaParquet
+}
+
+func newParquetSink(folder, dataset string, schema interface{}, upload cloudUpload) *parquetSink {
+	return &parquetSink{folder: folder, dataset: dataset, schema: schema, upload: upload}
+}
+
+func (p *parquetSink) OpenBatch(start, end uint32) error {
+	p.path = filepath.Join(p.folder, exportParquetFilename(start, end+1, p.dataset))
+	p.rows = nil
+	return nil
+}
+
+func (p *parquetSink) WriteRow(row interface{}) error {
+	parquetRow, ok := row.(transform.SchemaParquet)
+	if !ok {
+		return fmt.Errorf("%T cannot be written to parquet", row)
+	}
+	p.rows = append(p.rows, parquetRow)
+	return nil
+}
+
+func (p *parquetSink) CloseBatch() error {
+	WriteParquet(p.rows, p.path, p.schema)
+	p.rows = nil
+	return nil
+}
+
+func (p *parquetSink) Commit() error {
+	MaybeUpload(p.upload.credentials, p.upload.bucket, p.upload.provider, p.path)
+	return nil
+}
+
+func (p *parquetSink) Close() error {
+	return nil
+}
+
+// writerSink writes newline-delimited JSON rows to w as they arrive and has
+// no notion of batches.
+type writerSink struct {
+	w     *bufio.Writer
+	extra map[string]string
+}
+
+func newWriterSink(w io.Writer, extra map[string]string) *writerSink {
+	return &writerSink{w: bufio.NewWriter(w), extra: extra}
+}
+
+func (w *writerSink) OpenBatch(start, end uint32) error {
+	return nil
+}
+
+func (w *writerSink) WriteRow(row interface{}) error {
+	encoded, err := encodeRow(row, w.extra)
+	if err != nil {
+		return err
+	}
+	if _, err := w.w.Write(append(encoded, '\n')); err != nil {
+		return fmt.Errorf("could not write row: %v", err)
+	}
+	return nil
+}
+
+func (w *writerSink) CloseBatch() error {
+	return w.w.Flush()
+}
+
+func (w *writerSink) Commit() error {
+	return nil
+}
+
+func (w *writerSink) Close() error {
+	return w.w.Flush()
+}
+
+// commitLedger commits the rows of ledger seq written to sink, if sink
+// delivers rows ledger by ledger.
+func commitLedger(sink Sink, seq uint32) error {
+	if committer, ok := sink.(ledgerCommitter); ok {
+		return committer.CommitLedger(seq)
+	}
+	return nil
+}
+
+// stdoutDatasetField is added to every row written to stdout by commands that
+// export several datasets, so that the rows can be told apart downstream.
+const stdoutDatasetField = "dataset"
+
+// exportSinks builds the sink of every dataset of an export run out of the
+// output flags of the command, and closes them all at the end of the run.
+// With --stdout, the rows of every dataset are written to stdout in place of
+// the output files.
+type exportSinks struct {
+	outputFolder        string
+	parquetOutputFolder string
+	format              string
+	compression         string
+	writeParquet        bool
+	extra               map[string]string
+	upload              cloudUpload
+	publishers          *kafkaPublishers
+	loader              *PostgresLoader
+	stdout              *bufio.Writer
+	tagDatasets         bool
+	byDataset           map[string]Sink
+}
+
+func newExportSinks(
+	commonArgs utils.CommonFlagValues,
+	outputFolder, parquetOutputFolder string,
+	upload cloudUpload,
+	publishers *kafkaPublishers,
+	loader *PostgresLoader,
+) *exportSinks {
+	var stdout *bufio.Writer
+	if commonArgs.Stdout {
+		stdout = bufio.NewWriter(os.Stdout)
+	}
+
+	return &exportSinks{
+		outputFolder:        outputFolder,
+		parquetOutputFolder: parquetOutputFolder,
+		format:              commonArgs.OutputFormat,
+		compression:         commonArgs.OutputCompression,
+		writeParquet:        commonArgs.WriteParquet,
+		extra:               commonArgs.Extra,
+		upload:              upload,
+		publishers:          publishers,
+		loader:              loader,
+		stdout:              stdout,
+		byDataset:           map[string]Sink{},
+	}
+}
+
+// sink returns the sink that writes the rows of dataset to every enabled
+// destination. parquetSchema is nil if the dataset has no Parquet output.
+func (e *exportSinks) sink(dataset string, parquetSchema interface{}) Sink {
+	if sink, ok := e.byDataset[dataset]; ok {
+		return sink
+	}
+
+	var sinks multiSink
+	if e.stdout != nil {
+		sinks = append(sinks, e.stdoutSink(dataset))
+	} else {
+		sinks = append(sinks, newFileSink(e.outputFolder, dataset, e.format, e.compression, e.extra, e.upload))
+	}
+	if e.writeParquet && parquetSchema != nil {
+		sinks = append(sinks, newParquetSink(e.parquetOutputFolder, dataset, parquetSchema, e.upload))
+	}
+	if kafka := e.publishers.sink(dataset, e.extra); kafka != nil {
+		sinks = append(sinks, kafka)
+	}
+	if e.loader != nil {
+		sinks = append(sinks, newPostgresSink(e.loader, dataset, e.extra))
+	}
+	e.byDataset[dataset] = sinks
+	return sinks
+}
+
+// stdoutSink returns the sink writing the rows of dataset to stdout. Every
+// dataset shares the same buffered writer, which newWriterSink reuses as is,
+// so rows are never interleaved.
+func (e *exportSinks) stdoutSink(dataset string) Sink {
+	extra := e.extra
+	if e.tagDatasets {
+		extra = map[string]string{stdoutDatasetField: dataset}
+		for k, v := range e.extra {
+			extra[k] = v
+		}
+	}
+	return newWriterSink(e.stdout, extra)
+}
+
+func (e *exportSinks) Close() {
+	for dataset, sink := range e.byDataset {
+		if err := sink.Close(); err != nil {
+			cmdLogger.Errorf("could not close %s sink: %v", dataset, err)
+		}
+	}
+	if e.stdout != nil {
+		if err := e.stdout.Flush(); err != nil {
+			cmdLogger.Errorf("could not flush stdout: %v", err)
+		}
+	}
+}
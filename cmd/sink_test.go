@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/reader"
+)
+
+type sinkTestRow struct {
+	ID      int64             `json:"id"`
+	Name    string            `json:"name"`
+	Details map[string]string `json:"details"`
+}
+
+var sinkTestRows = []sinkTestRow{
+	{ID: 1, Name: "first", Details: map[string]string{"a": "b"}},
+	{ID: 2, Name: "second, with comma"},
+}
+
+func writeSinkTestBatch(t *testing.T, sink Sink) {
+	assert.NoError(t, sink.OpenBatch(10, 19))
+	for _, row := range sinkTestRows {
+		assert.NoError(t, sink.WriteRow(row))
+	}
+	assert.NoError(t, sink.CloseBatch())
+	assert.NoError(t, sink.Commit())
+}
+
+type sinkTestParquetRow struct {
+	ID   int64  `parquet:"name=id, type=INT64"`
+	Name string `parquet:"name=name, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+}
+
+func (r sinkTestRow) ToParquet() interface{} {
+	return sinkTestParquetRow{ID: r.ID, Name: r.Name}
+}
+
+func TestFileSinkFormats(t *testing.T) {
+	tests := []struct {
+		name        string
+		format      string
+		compression string
+		wantFile    string
+		decompress  func(io.Reader) (io.Reader, error)
+		wantContent string
+	}{
+		{
+			name:        "json",
+			format:      "json",
+			compression: "none",
+			wantFile:    "10-19-rows.txt",
+			wantContent: "{\"batch_id\":\"test\",\"details\":{\"a\":\"b\"},\"id\":1,\"name\":\"first\"}\n{\"batch_id\":\"test\",\"details\":null,\"id\":2,\"name\":\"second, with comma\"}\n",
+		},
+		{
+			name:        "gzip json",
+			format:      "json",
+			compression: "gzip",
+			wantFile:    "10-19-rows.txt.gz",
+			decompress: func(r io.Reader) (io.Reader, error) {
+				return gzip.NewReader(r)
+			},
+			wantContent: "{\"batch_id\":\"test\",\"details\":{\"a\":\"b\"},\"id\":1,\"name\":\"first\"}\n{\"batch_id\":\"test\",\"details\":null,\"id\":2,\"name\":\"second, with comma\"}\n",
+		},
+		{
+			name:        "zstd csv",
+			format:      "csv",
+			compression: "zstd",
+			wantFile:    "10-19-rows.csv.zst",
+			decompress: func(r io.Reader) (io.Reader, error) {
+				return zstd.NewReader(r)
+			},
+			wantContent: "id,name,details,batch_id\n1,first,\"{\"\"a\"\":\"\"b\"\"}\",test\n2,\"second, with comma\",,test\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			folder := t.TempDir()
+			sink := newFileSink(folder, "rows", test.format, test.compression, map[string]string{"batch_id": "test"}, cloudUpload{})
+			writeSinkTestBatch(t, sink)
+
+			file, err := os.Open(filepath.Join(folder, test.wantFile))
+			assert.NoError(t, err)
+			defer file.Close()
+
+			var r io.Reader = file
+			if test.decompress != nil {
+				r, err = test.decompress(file)
+				assert.NoError(t, err)
+			}
+			content, err := io.ReadAll(r)
+			assert.NoError(t, err)
+			assert.Equal(t, test.wantContent, string(content))
+
+			if test.format == "csv" {
+				records, err := csv.NewReader(strings.NewReader(string(content))).ReadAll()
+				assert.NoError(t, err)
+				assert.Len(t, records, len(sinkTestRows)+1)
+			}
+		})
+	}
+}
+
+func TestParquetSink(t *testing.T) {
+	folder := t.TempDir()
+	sink := newParquetSink(folder, "rows", new(sinkTestParquetRow), cloudUpload{})
+	writeSinkTestBatch(t, sink)
+
+	file, err := local.NewLocalFileReader(filepath.Join(folder, "10-19-rows.parquet"))
+	assert.NoError(t, err)
+	defer file.Close()
+	parquetReader, err := reader.NewParquetReader(file, new(sinkTestParquetRow), 1)
+	assert.NoError(t, err)
+	defer parquetReader.ReadStop()
+
+	rows := make([]sinkTestParquetRow, parquetReader.GetNumRows())
+	assert.NoError(t, parquetReader.Read(&rows))
+	assert.Equal(t, []sinkTestParquetRow{{ID: 1, Name: "first"}, {ID: 2, Name: "second, with comma"}}, rows)
+
+	// Rows without a Parquet representation are rejected
+	assert.NoError(t, sink.OpenBatch(20, 29))
+	assert.Error(t, sink.WriteRow(struct{}{}))
+}
+
+type recordingSink struct {
+	calls    []string
+	writeErr error
+}
+
+func (r *recordingSink) OpenBatch(start, end uint32) error {
+	r.calls = append(r.calls, "open")
+	return nil
+}
+
+func (r *recordingSink) WriteRow(row interface{}) error {
+	r.calls = append(r.calls, "write")
+	return r.writeErr
+}
+
+func (r *recordingSink) CloseBatch() error {
+	r.calls = append(r.calls, "close batch")
+	return nil
+}
+
+func (r *recordingSink) Commit() error {
+	r.calls = append(r.calls, "commit")
+	return nil
+}
+
+func (r *recordingSink) Close() error {
+	r.calls = append(r.calls, "close")
+	return nil
+}
+
+type recordingLedgerSink struct {
+	recordingSink
+}
+
+func (r *recordingLedgerSink) CommitLedger(seq uint32) error {
+	r.calls = append(r.calls, "commit ledger")
+	return nil
+}
+
+func TestMultiSink(t *testing.T) {
+	batchSink, ledgerSink := &recordingSink{}, &recordingLedgerSink{}
+	sink := multiSink{batchSink, ledgerSink}
+
+	assert.NoError(t, sink.OpenBatch(10, 19))
+	assert.NoError(t, sink.WriteRow(sinkTestRows[0]))
+	assert.NoError(t, commitLedger(sink, 10))
+	assert.NoError(t, sink.CloseBatch())
+	assert.NoError(t, sink.Commit())
+	assert.NoError(t, sink.Close())
+
+	assert.Equal(t, []string{"open", "write", "close batch", "commit", "close"}, batchSink.calls)
+	assert.Equal(t, []string{"open", "write", "commit ledger", "close batch", "commit", "close"}, ledgerSink.calls)
+}
+
+func TestWriterSink(t *testing.T) {
+	var out bytes.Buffer
+	sink := newWriterSink(&out, nil)
+	writeSinkTestBatch(t, sink)
+	assert.Equal(t, "{\"details\":{\"a\":\"b\"},\"id\":1,\"name\":\"first\"}\n{\"details\":null,\"id\":2,\"name\":\"second, with comma\"}\n", out.String())
+}
+
+func TestExportSinksStdout(t *testing.T) {
+	var out bytes.Buffer
+	sinks := &exportSinks{
+		extra:       map[string]string{"batch_id": "test"},
+		stdout:      bufio.NewWriter(&out),
+		tagDatasets: true,
+		publishers:  newKafkaPublishers(utils.KafkaFlagValues{}),
+		byDataset:   map[string]Sink{},
+	}
+
+	for _, dataset := range []string{"accounts", "trustlines"} {
+		sink := sinks.sink(dataset, nil)
+		assert.NoError(t, sink.OpenBatch(10, 19))
+		assert.NoError(t, sink.WriteRow(sinkTestRows[1]))
+		assert.NoError(t, sink.CloseBatch())
+		assert.NoError(t, sink.Commit())
+	}
+	sinks.Close()
+
+	assert.Equal(t, "{\"batch_id\":\"test\",\"dataset\":\"accounts\",\"details\":null,\"id\":2,\"name\":\"second, with comma\"}\n"+
+		"{\"batch_id\":\"test\",\"dataset\":\"trustlines\",\"details\":null,\"id\":2,\"name\":\"second, with comma\"}\n", out.String())
+}
+
+func TestExportTransformedDataWriteError(t *testing.T) {
+	failing := &recordingSink{writeErr: errors.New("disk full")}
+	sinks := &exportSinks{byDataset: map[string]Sink{"accounts": failing}}
+
+	err := exportTransformedData(10, 19, map[string][]interface{}{"accounts": {sinkTestRows[0], sinkTestRows[1]}}, sinks)
+	assert.ErrorContains(t, err, "disk full")
+	assert.Equal(t, []string{"open", "write", "close batch"}, failing.calls)
+}
@@ -79,75 +79,83 @@ func PrepareCaptiveCore(execPath string, tomlPath string, start, end uint32, env
 	return captiveBackend, nil
 }
 
+// trackedLedgerEntryTypes are the ledger entry types whose changes are extracted and compacted
+var trackedLedgerEntryTypes = []xdr.LedgerEntryType{
+	xdr.LedgerEntryTypeAccount,
+	xdr.LedgerEntryTypeOffer,
+	xdr.LedgerEntryTypeTrustline,
+	xdr.LedgerEntryTypeLiquidityPool,
+	xdr.LedgerEntryTypeClaimableBalance,
+	xdr.LedgerEntryTypeContractData,
+	xdr.LedgerEntryTypeContractCode,
+	xdr.LedgerEntryTypeConfigSetting,
+	xdr.LedgerEntryTypeTtl}
+
+// compactLedgerChanges reads every change of a single ledger from changeReader and compacts them per ledger entry type
+func compactLedgerChanges(changeReader *ingest.LedgerChangeReader, logger *utils.EtlLogger) (map[xdr.LedgerEntryType]*ingest.ChangeCompactor, error) {
+	changeCompactors := map[xdr.LedgerEntryType]*ingest.ChangeCompactor{}
+	for _, dt := range trackedLedgerEntryTypes {
+		changeCompactors[dt] = ingest.NewChangeCompactor(ingest.ChangeCompactorConfig{SuppressRemoveAfterRestoreChange: false})
+	}
+
+	for {
+		change, err := changeReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		cache, ok := changeCompactors[change.Type]
+		if !ok {
+			// TODO: once LedgerEntryTypeData is tracked as well, all types should be addressed,
+			// so this info log should be a warning.
+			// Skip LedgerEntryTypeData as we are intentionally not processing it
+			if change.Type != xdr.LedgerEntryTypeData {
+				logger.Infof("change type: %v not tracked", change.Type)
+			}
+		} else {
+			cache.AddChange(change)
+		}
+	}
+
+	return changeCompactors, nil
+}
+
+// appendLedgerChanges adds the compacted changes of a single ledger to ledgerChanges, tagging each one with the ledger header
+func appendLedgerChanges(ledgerChanges map[xdr.LedgerEntryType]LedgerChanges, changeCompactors map[xdr.LedgerEntryType]*ingest.ChangeCompactor, header xdr.LedgerHeaderHistoryEntry) {
+	for dataType, compactor := range changeCompactors {
+		for _, change := range compactor.GetChanges() {
+			dataTypeChanges := ledgerChanges[dataType]
+			dataTypeChanges.Changes = append(dataTypeChanges.Changes, change)
+			dataTypeChanges.LedgerHeaders = append(dataTypeChanges.LedgerHeaders, header)
+			ledgerChanges[dataType] = dataTypeChanges
+		}
+	}
+}
+
 // extractBatch gets the changes from the ledgers in the range [batchStart, batchEnd] and compacts them
 func extractBatch(
 	batchStart, batchEnd uint32,
 	backend *ledgerbackend.LedgerBackend,
 	env utils.EnvironmentDetails, logger *utils.EtlLogger) ChangeBatch {
 
-	dataTypes := []xdr.LedgerEntryType{
-		xdr.LedgerEntryTypeAccount,
-		xdr.LedgerEntryTypeOffer,
-		xdr.LedgerEntryTypeTrustline,
-		xdr.LedgerEntryTypeLiquidityPool,
-		xdr.LedgerEntryTypeClaimableBalance,
-		xdr.LedgerEntryTypeContractData,
-		xdr.LedgerEntryTypeContractCode,
-		xdr.LedgerEntryTypeConfigSetting,
-		xdr.LedgerEntryTypeTtl}
-
 	ledgerChanges := map[xdr.LedgerEntryType]LedgerChanges{}
 	ctx := context.Background()
-	for seq := batchStart; seq <= batchEnd; {
-		changeCompactors := map[xdr.LedgerEntryType]*ingest.ChangeCompactor{}
-		for _, dt := range dataTypes {
-			changeCompactors[dt] = ingest.NewChangeCompactor(ingest.ChangeCompactorConfig{SuppressRemoveAfterRestoreChange: false})
-		}
-
-		// if this ledger is available, we process its changes and move on to the next ledger by incrementing seq.
-		// Otherwise, nothing is incremented, and we try again on the next iteration of the loop
-		var header xdr.LedgerHeaderHistoryEntry
-		if seq <= batchEnd {
-			changeReader, err := ingest.NewLedgerChangeReader(ctx, *backend, env.NetworkPassphrase, seq)
-			if err != nil {
-				logger.Fatal(fmt.Sprintf("unable to create change reader for ledger %d: ", seq), err)
-			}
-			header = changeReader.LedgerTransactionReader.GetHeader()
-
-			for {
-				change, err := changeReader.Read()
-				if err == io.EOF {
-					break
-				}
-				if err != nil {
-					logger.Fatal(fmt.Sprintf("unable to read changes from ledger %d: ", seq), err)
-				}
-				cache, ok := changeCompactors[change.Type]
-				if !ok {
-					// TODO: once LedgerEntryTypeData is tracked as well, all types should be addressed,
-					// so this info log should be a warning.
-					// Skip LedgerEntryTypeData as we are intentionally not processing it
-					if change.Type != xdr.LedgerEntryTypeData {
-						logger.Infof("change type: %v not tracked", change.Type)
-					}
-				} else {
-					cache.AddChange(change)
-				}
-			}
-
-			changeReader.Close()
-			seq++
+	for seq := batchStart; seq <= batchEnd; seq++ {
+		changeReader, err := ingest.NewLedgerChangeReader(ctx, *backend, env.NetworkPassphrase, seq)
+		if err != nil {
+			logger.Fatal(fmt.Sprintf("unable to create change reader for ledger %d: ", seq), err)
 		}
+		header := changeReader.LedgerTransactionReader.GetHeader()
 
-		for dataType, compactor := range changeCompactors {
-			for _, change := range compactor.GetChanges() {
-				dataTypeChanges := ledgerChanges[dataType]
-				dataTypeChanges.Changes = append(dataTypeChanges.Changes, change)
-				dataTypeChanges.LedgerHeaders = append(dataTypeChanges.LedgerHeaders, header)
-				ledgerChanges[dataType] = dataTypeChanges
-			}
+		changeCompactors, err := compactLedgerChanges(changeReader, logger)
+		if err != nil {
+			logger.Fatal(fmt.Sprintf("unable to read changes from ledger %d: ", seq), err)
 		}
+		changeReader.Close()
 
+		appendLedgerChanges(ledgerChanges, changeCompactors, header)
 	}
 
 	return ChangeBatch{
@@ -157,6 +165,31 @@ func extractBatch(
 	}
 }
 
+// ChangesFromLedger gets the compacted changes of a single ledger as a batch covering only that ledger.
+// Unlike ExtractBatch, errors are returned to the caller instead of stopping the program.
+func ChangesFromLedger(lcm xdr.LedgerCloseMeta, env utils.EnvironmentDetails, logger *utils.EtlLogger) (ChangeBatch, error) {
+	seq := lcm.LedgerSequence()
+	changeReader, err := ingest.NewLedgerChangeReaderFromLedgerCloseMeta(env.NetworkPassphrase, lcm)
+	if err != nil {
+		return ChangeBatch{}, fmt.Errorf("unable to create change reader for ledger %d: %v", seq, err)
+	}
+	defer changeReader.Close()
+
+	changeCompactors, err := compactLedgerChanges(changeReader, logger)
+	if err != nil {
+		return ChangeBatch{}, fmt.Errorf("unable to read changes from ledger %d: %v", seq, err)
+	}
+
+	ledgerChanges := map[xdr.LedgerEntryType]LedgerChanges{}
+	appendLedgerChanges(ledgerChanges, changeCompactors, changeReader.LedgerTransactionReader.GetHeader())
+
+	return ChangeBatch{
+		Changes:    ledgerChanges,
+		BatchStart: seq,
+		BatchEnd:   seq,
+	}, nil
+}
+
 // StreamChanges reads in ledgers, processes the changes, and send the changes to the channel matching their type
 // Ledgers are processed in batches of size <batchSize>.
 func StreamChanges(backend *ledgerbackend.LedgerBackend, start, end, batchSize uint32, changeChannel chan ChangeBatch, closeChan chan int, env utils.EnvironmentDetails, logger *utils.EtlLogger) {
@@ -23,11 +23,81 @@ func GetLedgerRange(startTime, endTime time.Time, env utils.EnvironmentDetails)
 	}
 
 	ctx := context.Background()
-	ds, dsCfg, err := utils.CreateDatastore(ctx, env)
+	finder, oldestPt, latestPt, err := newLedgerFinder(ctx, env)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer finder.ds.Close()
+
+	if err := checkTimesWithinDatastore(startTime, endTime, latestPt); err != nil {
+		return 0, 0, err
+	}
+
+	// Clamp requested times to the datastore's available range (same semantics as the
+	// history-archive-backed implementation's limitLedgerRange).
+	if startTime.Before(oldestPt.closeTime) {
+		startTime = oldestPt.closeTime
+	} else if startTime.After(latestPt.closeTime) {
+		startTime = latestPt.closeTime
+	}
+	if endTime.After(latestPt.closeTime) {
+		endTime = latestPt.closeTime
+	} else if endTime.Before(oldestPt.closeTime) {
+		endTime = oldestPt.closeTime
+	}
+
+	startLedger, err := finder.findLedgerForTime(ctx, startTime, oldestPt, latestPt)
+	if err != nil {
+		return 0, 0, err
+	}
+	endLedger, err := finder.findLedgerForTime(ctx, endTime, oldestPt, latestPt)
 	if err != nil {
 		return 0, 0, err
 	}
-	defer ds.Close()
+
+	return int64(startLedger), int64(endLedger), nil
+}
+
+// GetIntervalStartLedger returns the first ledger that closed within the interval of the given length, aligned to the
+// Unix epoch, that holds the close time of ledger seq, by binary-searching the GCS datastore configured on
+// env.CommonFlagValues.DatastorePath. It fails if the datastore does not reach back to the start of the interval.
+func GetIntervalStartLedger(seq uint32, interval time.Duration, env utils.EnvironmentDetails) (uint32, error) {
+	ctx := context.Background()
+	finder, oldestPt, latestPt, err := newLedgerFinder(ctx, env)
+	if err != nil {
+		return 0, err
+	}
+	defer finder.ds.Close()
+
+	return finder.findIntervalStart(ctx, seq, interval, oldestPt, latestPt)
+}
+
+// findIntervalStart returns the first ledger in [oldest.seq, latest.seq] that closed at or after the start of the
+// epoch-aligned interval holding the close time of ledger seq
+func (f *ledgerFinder) findIntervalStart(ctx context.Context, seq uint32, interval time.Duration, oldest, latest ledgerPoint) (uint32, error) {
+	if seq < oldest.seq || seq > latest.seq {
+		return 0, fmt.Errorf("ledger %d is outside of the datastore range %d-%d", seq, oldest.seq, latest.seq)
+	}
+	seqPt, err := f.pointAt(ctx, seq)
+	if err != nil {
+		return 0, err
+	}
+	length := interval.Milliseconds()
+	intervalStart := time.UnixMilli(seqPt.closeTime.UnixMilli() / length * length).UTC()
+	// The genesis ledger closed at the epoch, so a datastore starting right after it holds every later interval
+	if oldest.seq > 2 && oldest.closeTime.After(intervalStart) {
+		return 0, fmt.Errorf("the datastore starts at ledger %d, after the interval starting at %s", oldest.seq, intervalStart)
+	}
+	return f.findLedgerForTime(ctx, intervalStart, oldest, seqPt)
+}
+
+// newLedgerFinder opens the GCS datastore configured on env and returns a finder over it along with its oldest and
+// latest ledgers. The caller closes finder.ds.
+func newLedgerFinder(ctx context.Context, env utils.EnvironmentDetails) (*ledgerFinder, ledgerPoint, ledgerPoint, error) {
+	ds, dsCfg, err := utils.CreateDatastore(ctx, env)
+	if err != nil {
+		return nil, ledgerPoint{}, ledgerPoint{}, err
+	}
 
 	// Use the on-disk schema (written by ledgerexporter) so key generation and
 	// FindOldestLedgerSequence agree with the bucket's actual layout. LoadSchema also
@@ -35,7 +105,8 @@ func GetLedgerRange(startTime, endTime time.Time, env utils.EnvironmentDetails)
 	// want to fail on rather than silently paper over with wrong object keys.
 	schema, err := datastore.LoadSchema(ctx, ds, dsCfg)
 	if err != nil {
-		return 0, 0, fmt.Errorf("unable to load datastore schema: %w", err)
+		ds.Close()
+		return nil, ledgerPoint{}, ledgerPoint{}, fmt.Errorf("unable to load datastore schema: %w", err)
 	}
 	dsCfg.Schema = schema
 
@@ -47,49 +118,26 @@ func GetLedgerRange(startTime, endTime time.Time, env utils.EnvironmentDetails)
 
 	oldestSeq, err := datastore.FindOldestLedgerSequence(ctx, ds, dsCfg.Schema)
 	if err != nil {
-		return 0, 0, fmt.Errorf("unable to find oldest ledger in datastore: %w", err)
+		ds.Close()
+		return nil, ledgerPoint{}, ledgerPoint{}, fmt.Errorf("unable to find oldest ledger in datastore: %w", err)
 	}
 	latestSeq, err := datastore.FindLatestLedgerSequence(ctx, ds)
 	if err != nil {
-		return 0, 0, fmt.Errorf("unable to find latest ledger in datastore: %w", err)
+		ds.Close()
+		return nil, ledgerPoint{}, ledgerPoint{}, fmt.Errorf("unable to find latest ledger in datastore: %w", err)
 	}
 
 	oldestPt, err := finder.pointAt(ctx, oldestSeq)
 	if err != nil {
-		return 0, 0, err
+		ds.Close()
+		return nil, ledgerPoint{}, ledgerPoint{}, err
 	}
 	latestPt, err := finder.pointAt(ctx, latestSeq)
 	if err != nil {
-		return 0, 0, err
-	}
-
-	if err := checkTimesWithinDatastore(startTime, endTime, latestPt); err != nil {
-		return 0, 0, err
-	}
-
-	// Clamp requested times to the datastore's available range (same semantics as the
-	// history-archive-backed implementation's limitLedgerRange).
-	if startTime.Before(oldestPt.closeTime) {
-		startTime = oldestPt.closeTime
-	} else if startTime.After(latestPt.closeTime) {
-		startTime = latestPt.closeTime
-	}
-	if endTime.After(latestPt.closeTime) {
-		endTime = latestPt.closeTime
-	} else if endTime.Before(oldestPt.closeTime) {
-		endTime = oldestPt.closeTime
-	}
-
-	startLedger, err := finder.findLedgerForTime(ctx, startTime, oldestPt, latestPt)
-	if err != nil {
-		return 0, 0, err
+		ds.Close()
+		return nil, ledgerPoint{}, ledgerPoint{}, err
 	}
-	endLedger, err := finder.findLedgerForTime(ctx, endTime, oldestPt, latestPt)
-	if err != nil {
-		return 0, 0, err
-	}
-
-	return int64(startLedger), int64(endLedger), nil
+	return finder, oldestPt, latestPt, nil
 }
 
 // maxFutureTolerance bounds how far past the datastore's latest ledger close time a requested
@@ -316,3 +316,30 @@ func TestLedgerFinderFindLedgerForTimePropagatesDatastoreError(t *testing.T) {
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "transient failure")
 }
+
+func TestLedgerFinderFindIntervalStart(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	// Ledger N closes N*20 seconds into the hour, so ledgers 3 to 5 fall in the minute starting at ledger 3
+	closeAt := func(seq uint32) time.Time { return base.Add(time.Duration(seq) * 20 * time.Second) }
+	ds := new(datastore.MockDataStore)
+	for seq := uint32(1); seq <= 8; seq++ {
+		expectGetFile(t, ds, seq, closeAt(seq)).Maybe()
+	}
+	finder := newFinder(ds)
+	oldest := ledgerPoint{seq: 1, closeTime: closeAt(1)}
+	latest := ledgerPoint{seq: 8, closeTime: closeAt(8)}
+
+	seq, err := finder.findIntervalStart(context.Background(), 5, time.Minute, oldest, latest)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(3), seq)
+
+	seq, err = finder.findIntervalStart(context.Background(), 6, time.Minute, oldest, latest)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(6), seq)
+
+	_, err = finder.findIntervalStart(context.Background(), 5, time.Minute, ledgerPoint{seq: 4, closeTime: closeAt(4)}, latest)
+	assert.Error(t, err)
+
+	_, err = finder.findIntervalStart(context.Background(), 9, time.Minute, oldest, latest)
+	assert.Error(t, err)
+}
@@ -0,0 +1,119 @@
+package transform
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/guregu/null"
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+)
+
+// AccountLifecycles holds the lifecycle of the accounts seen so far, keyed by address
+type AccountLifecycles map[string]AccountLifecycleOutput
+
+// AddTransaction updates the lifecycles with a transaction and returns the accounts it changed. The source and fee
+// accounts of the transaction and the sources of its operations are active in it even if it failed, while creations
+// and merges only count once successful. Creating an account that was merged starts its lifecycle over.
+func (l AccountLifecycles) AddTransaction(transaction ingest.LedgerTransaction, lhe xdr.LedgerHeaderHistoryEntry) ([]string, error) {
+	closedAt, err := utils.TimePointToUTCTimeStamp(lhe.Header.ScpValue.CloseTime)
+	if err != nil {
+		return nil, err
+	}
+	ledgerSequence := null.IntFrom(int64(lhe.Header.LedgerSeq))
+	changed := []string{}
+	seen := map[string]bool{}
+	update := func(address string, apply func(*AccountLifecycleOutput)) {
+		lifecycle, ok := l[address]
+		if !ok {
+			lifecycle = AccountLifecycleOutput{AccountID: address}
+		}
+		apply(&lifecycle)
+		l[address] = lifecycle
+		if !seen[address] {
+			seen[address] = true
+			changed = append(changed, address)
+		}
+	}
+	setActive := func(account xdr.MuxedAccount) {
+		update(account.ToAccountId().Address(), func(lifecycle *AccountLifecycleOutput) {
+			lifecycle.LastActivityLedger = ledgerSequence
+			lifecycle.LastActivityAt = null.TimeFrom(closedAt)
+		})
+	}
+
+	if transaction.Envelope.IsFeeBump() {
+		setActive(transaction.Envelope.FeeBumpAccount())
+	}
+	setActive(transaction.Envelope.SourceAccount())
+	for _, operation := range transaction.Envelope.Operations() {
+		setActive(getOperationSourceAccount(operation, transaction))
+	}
+	if !transaction.Result.Successful() {
+		return changed, nil
+	}
+
+	transactionHash := utils.HashToHexString(transaction.Result.TransactionHash)
+	for index, operation := range transaction.Envelope.Operations() {
+		source := getOperationSourceAccount(operation, transaction).ToAccountId().Address()
+		switch operation.Body.Type {
+		case xdr.OperationTypeCreateAccount:
+			op := operation.Body.MustCreateAccountOp()
+			address := op.Destination.Address()
+			changes, err := transaction.GetOperationChanges(uint32(index))
+			if err != nil {
+				return nil, err
+			}
+			var sponsor null.String
+			for _, change := range changes {
+				if change.Type == xdr.LedgerEntryTypeAccount && change.Pre == nil && change.Post != nil &&
+					change.Post.Data.MustAccount().AccountId.Address() == address {
+					sponsor = ledgerEntrySponsorToNullString(*change.Post)
+				}
+			}
+			update(address, func(lifecycle *AccountLifecycleOutput) {
+				*lifecycle = AccountLifecycleOutput{
+					AccountID:              address,
+					CreatedLedger:          ledgerSequence,
+					CreatedAt:              null.TimeFrom(closedAt),
+					Funder:                 null.StringFrom(source),
+					StartingBalance:        null.FloatFrom(utils.ConvertStroopValueToReal(op.StartingBalance)),
+					CreatedTransactionHash: null.StringFrom(transactionHash),
+					CreationSponsor:        sponsor,
+				}
+			})
+
+		case xdr.OperationTypeAccountMerge:
+			destination := operation.Body.MustDestination()
+			update(source, func(lifecycle *AccountLifecycleOutput) {
+				lifecycle.MergedInto = null.StringFrom(destination.ToAccountId().Address())
+				lifecycle.MergedLedger = ledgerSequence
+				lifecycle.MergedAt = null.TimeFrom(closedAt)
+				lifecycle.MergedTransactionHash = null.StringFrom(transactionHash)
+			})
+		}
+	}
+	return changed, nil
+}
+
+// LoadAccountLifecycles reads the JSON lines written by export_account_lifecycle, keeping the last row of every account
+func LoadAccountLifecycles(in io.Reader) (AccountLifecycles, error) {
+	lifecycles := AccountLifecycles{}
+	err := readJSONLines(in, func(line []byte) error {
+		var lifecycle AccountLifecycleOutput
+		if err := json.Unmarshal(line, &lifecycle); err != nil {
+			return err
+		}
+		if lifecycle.AccountID == "" {
+			return fmt.Errorf("account lifecycle without an account_id")
+		}
+		lifecycles[lifecycle.AccountID] = lifecycle
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return lifecycles, nil
+}
@@ -0,0 +1,91 @@
+package transform
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/guregu/null"
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func makeAccountLifecycleTestInput(source xdr.MuxedAccount, operation xdr.Operation, changes xdr.LedgerEntryChanges) ingest.LedgerTransaction {
+	transaction := genericLedgerTransaction
+	envelope := genericBumpOperationEnvelope
+	envelope.Tx.SourceAccount = source
+	envelope.Tx.Operations = []xdr.Operation{operation}
+	transaction.Envelope.V1 = &envelope
+	transaction.UnsafeMeta = xdr.TransactionMeta{V: 1, V1: &xdr.TransactionMetaV1{Operations: []xdr.OperationMeta{{Changes: changes}}}}
+	return transaction
+}
+
+func TestAccountLifecycles(t *testing.T) {
+	lifecycles := AccountLifecycles{}
+	sponsor := testAccount3ID
+	create := makeAccountLifecycleTestInput(testAccount1, xdr.Operation{Body: xdr.OperationBody{
+		Type:            xdr.OperationTypeCreateAccount,
+		CreateAccountOp: &xdr.CreateAccountOp{Destination: testAccount2ID, StartingBalance: 15000000},
+	}}, xdr.LedgerEntryChanges{{
+		Type: xdr.LedgerEntryChangeTypeLedgerEntryCreated,
+		Created: &xdr.LedgerEntry{
+			Data: xdr.LedgerEntryData{Type: xdr.LedgerEntryTypeAccount, Account: &xdr.AccountEntry{AccountId: testAccount2ID, Balance: 15000000}},
+			Ext:  xdr.LedgerEntryExt{V: 1, V1: &xdr.LedgerEntryExtensionV1{SponsoringId: &sponsor}},
+		},
+	}})
+	lhe := xdr.LedgerHeaderHistoryEntry{Header: xdr.LedgerHeader{LedgerSeq: 10, ScpValue: xdr.StellarValue{CloseTime: 1000}}}
+	createdAt, _ := utils.TimePointToUTCTimeStamp(1000)
+	changed, err := lifecycles.AddTransaction(create, lhe)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{testAccount1Address, testAccount2Address}, changed)
+	assert.Equal(t, AccountLifecycleOutput{
+		AccountID:              testAccount2Address,
+		CreatedLedger:          null.IntFrom(10),
+		CreatedAt:              null.TimeFrom(createdAt),
+		Funder:                 null.StringFrom(testAccount1Address),
+		StartingBalance:        null.FloatFrom(1.5),
+		CreatedTransactionHash: null.StringFrom(utils.HashToHexString(create.Result.TransactionHash)),
+		CreationSponsor:        null.StringFrom(testAccount3Address),
+	}, lifecycles[testAccount2Address])
+	assert.Equal(t, null.IntFrom(10), lifecycles[testAccount1Address].LastActivityLedger)
+	assert.False(t, lifecycles[testAccount1Address].CreatedLedger.Valid)
+
+	merge := makeAccountLifecycleTestInput(testAccount2, xdr.Operation{Body: xdr.OperationBody{
+		Type:        xdr.OperationTypeAccountMerge,
+		Destination: &testAccount3,
+	}}, nil)
+	lhe.Header.LedgerSeq, lhe.Header.ScpValue.CloseTime = 20, 2000
+	changed, err = lifecycles.AddTransaction(merge, lhe)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{testAccount2Address}, changed)
+	merged := lifecycles[testAccount2Address]
+	assert.Equal(t, null.IntFrom(10), merged.CreatedLedger)
+	assert.Equal(t, null.StringFrom(testAccount3Address), merged.MergedInto)
+	assert.Equal(t, null.IntFrom(20), merged.MergedLedger)
+	assert.Equal(t, null.TimeFrom(createdAt.Add(1000*time.Second)), merged.MergedAt)
+	assert.Equal(t, null.IntFrom(20), merged.LastActivityLedger)
+
+	// A failed transaction is still activity of its source, but does not merge it
+	failed := makeAccountLifecycleTestInput(testAccount1, xdr.Operation{Body: xdr.OperationBody{
+		Type:        xdr.OperationTypeAccountMerge,
+		Destination: &testAccount3,
+	}}, nil)
+	failed.Result = utils.CreateSampleResultMeta(false, 1).Result
+	_, err = lifecycles.AddTransaction(failed, lhe)
+	assert.NoError(t, err)
+	assert.Equal(t, null.IntFrom(20), lifecycles[testAccount1Address].LastActivityLedger)
+	assert.False(t, lifecycles[testAccount1Address].MergedInto.Valid)
+}
+
+func TestLoadAccountLifecycles(t *testing.T) {
+	lifecycles, err := LoadAccountLifecycles(strings.NewReader(`{"account_id":"GA","created_ledger":10}
+{"account_id":"GA","created_ledger":10,"merged_ledger":20}
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, null.IntFrom(20), lifecycles["GA"].MergedLedger)
+
+	_, err = LoadAccountLifecycles(strings.NewReader(`{"created_ledger":10}`))
+	assert.Error(t, err)
+}
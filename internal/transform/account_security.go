@@ -0,0 +1,75 @@
+package transform
+
+import (
+	"fmt"
+
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+)
+
+// TransformAccountSecurity returns the signing posture of an account after a change, if the change created or removed
+// the account, or changed its signers, their sponsors or its thresholds, which include the weight of the master key.
+// A threshold is reachable without the master key when the other signers weigh at least as much, and at least 1, as
+// a signature is always required.
+func TransformAccountSecurity(ledgerChange ingest.Change, header xdr.LedgerHeaderHistoryEntry) (AccountSecurityOutput, bool, error) {
+	if ledgerChange.Type != xdr.LedgerEntryTypeAccount {
+		return AccountSecurityOutput{}, false, fmt.Errorf("could not extract account security from ledger entry of type: %+v", ledgerChange.Type)
+	}
+	thresholdsChanged := ledgerChange.Pre != nil && ledgerChange.Post != nil &&
+		ledgerChange.Pre.Data.MustAccount().Thresholds != ledgerChange.Post.Data.MustAccount().Thresholds
+	if !thresholdsChanged && !utils.AccountSignersChanged(ledgerChange) {
+		return AccountSecurityOutput{}, false, nil
+	}
+
+	ledgerEntry, changeType, outputDeleted, err := utils.ExtractEntryFromChange(ledgerChange)
+	if err != nil {
+		return AccountSecurityOutput{}, false, err
+	}
+	account := ledgerEntry.Data.MustAccount()
+	closedAt, err := utils.TimePointToUTCTimeStamp(header.Header.ScpValue.CloseTime)
+	if err != nil {
+		return AccountSecurityOutput{}, false, err
+	}
+
+	output := AccountSecurityOutput{
+		AccountID:          account.AccountId.Address(),
+		MasterWeight:       int32(account.MasterKeyWeight()),
+		ThresholdLow:       int32(account.ThresholdLow()),
+		ThresholdMedium:    int32(account.ThresholdMedium()),
+		ThresholdHigh:      int32(account.ThresholdHigh()),
+		MasterKeyDisabled:  account.MasterKeyWeight() == 0,
+		NumSigners:         int32(len(account.Signers)),
+		Sponsor:            ledgerEntrySponsorToNullString(ledgerEntry),
+		LastModifiedLedger: uint32(ledgerEntry.LastModifiedLedgerSeq),
+		LedgerEntryChange:  uint32(changeType),
+		Deleted:            outputDeleted,
+		ClosedAt:           closedAt,
+		LedgerSequence:     uint32(header.Header.LedgerSeq),
+	}
+	var signersWeight int32
+	for _, signer := range account.Signers {
+		signersWeight += int32(signer.Weight)
+		switch signer.Key.Type {
+		case xdr.SignerKeyTypeSignerKeyTypeEd25519:
+			output.NumEd25519Signers++
+		case xdr.SignerKeyTypeSignerKeyTypePreAuthTx:
+			output.NumPreAuthTxSigners++
+		case xdr.SignerKeyTypeSignerKeyTypeHashX:
+			output.NumHashXSigners++
+		case xdr.SignerKeyTypeSignerKeyTypeEd25519SignedPayload:
+			output.NumSignedPayloadSigners++
+		}
+	}
+	for _, sponsor := range account.SignerSponsoringIDs() {
+		if sponsor != nil {
+			output.NumSponsoredSigners++
+		}
+	}
+	output.SignersWeight = signersWeight
+	output.TotalWeight = signersWeight + output.MasterWeight
+	output.LowReachableWithoutMaster = signersWeight >= max(output.ThresholdLow, 1)
+	output.MediumReachableWithoutMaster = signersWeight >= max(output.ThresholdMedium, 1)
+	output.HighReachableWithoutMaster = signersWeight >= max(output.ThresholdHigh, 1)
+	return output, true, nil
+}
@@ -0,0 +1,86 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/guregu/null"
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func makeAccountSecurityTestEntry(thresholds xdr.Thresholds, signers []xdr.Signer) *xdr.LedgerEntry {
+	return &xdr.LedgerEntry{
+		LastModifiedLedgerSeq: 10,
+		Data: xdr.LedgerEntryData{Type: xdr.LedgerEntryTypeAccount, Account: &xdr.AccountEntry{
+			AccountId:  testAccount1ID,
+			Thresholds: thresholds,
+			Signers:    signers,
+		}},
+	}
+}
+
+func TestTransformAccountSecurity(t *testing.T) {
+	hashX := xdr.SignerKey{Type: xdr.SignerKeyTypeSignerKeyTypeHashX, HashX: &xdr.Uint256{1}}
+	signers := []xdr.Signer{
+		{Key: xdr.MustSigner(testAccount2Address), Weight: 2},
+		{Key: xdr.MustSigner(testAccount3Address), Weight: 1},
+		{Key: hashX, Weight: 1},
+	}
+	header := xdr.LedgerHeaderHistoryEntry{Header: xdr.LedgerHeader{LedgerSeq: 10, ScpValue: xdr.StellarValue{CloseTime: 1000}}}
+	closedAt, _ := utils.TimePointToUTCTimeStamp(1000)
+
+	// Disabling the master key of a multisig account
+	change := ingest.Change{
+		Type:       xdr.LedgerEntryTypeAccount,
+		ChangeType: xdr.LedgerEntryChangeTypeLedgerEntryUpdated,
+		Pre:        makeAccountSecurityTestEntry(xdr.Thresholds{1, 0, 2, 5}, signers),
+		Post:       makeAccountSecurityTestEntry(xdr.Thresholds{0, 0, 2, 5}, signers),
+	}
+	security, ok, err := TransformAccountSecurity(change, header)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, AccountSecurityOutput{
+		AccountID:                    testAccount1Address,
+		MasterWeight:                 0,
+		ThresholdLow:                 0,
+		ThresholdMedium:              2,
+		ThresholdHigh:                5,
+		MasterKeyDisabled:            true,
+		NumSigners:                   3,
+		NumEd25519Signers:            2,
+		NumHashXSigners:              1,
+		SignersWeight:                4,
+		TotalWeight:                  4,
+		LowReachableWithoutMaster:    true,
+		MediumReachableWithoutMaster: true,
+		HighReachableWithoutMaster:   false,
+		LastModifiedLedger:           10,
+		LedgerEntryChange:            uint32(xdr.LedgerEntryChangeTypeLedgerEntryUpdated),
+		ClosedAt:                     closedAt,
+		LedgerSequence:               10,
+	}, security)
+
+	// Changes that leave the signers and thresholds alone are skipped
+	change.Pre = change.Post
+	_, ok, err = TransformAccountSecurity(change, header)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// A new account is only reachable with its master key
+	sponsor := testAccount2ID
+	created := makeAccountSecurityTestEntry(xdr.Thresholds{1, 0, 0, 0}, nil)
+	created.Ext = xdr.LedgerEntryExt{V: 1, V1: &xdr.LedgerEntryExtensionV1{SponsoringId: &sponsor}}
+	security, ok, err = TransformAccountSecurity(ingest.Change{
+		Type:       xdr.LedgerEntryTypeAccount,
+		ChangeType: xdr.LedgerEntryChangeTypeLedgerEntryCreated,
+		Post:       created,
+	}, header)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.False(t, security.MasterKeyDisabled)
+	assert.False(t, security.LowReachableWithoutMaster)
+	assert.Equal(t, int32(1), security.TotalWeight)
+	assert.Equal(t, null.StringFrom(testAccount2Address), security.Sponsor)
+}
@@ -0,0 +1,176 @@
+package transform
+
+import (
+	"fmt"
+
+	"github.com/guregu/null"
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+)
+
+// trackedTtl is the latest TTL seen for a contract data or code entry
+type trackedTtl struct {
+	key       xdr.LedgerKey
+	liveUntil uint32
+}
+
+// ArchivalTracker keeps the TTLs of the contract data and code entries written by the ledgers it has seen, so that
+// their expiration can be reported in the ledger right after their TTL passes. Entries are dropped once they are
+// deleted, evicted, or have expired and are temporary, as expired temporary entries can never be restored.
+type ArchivalTracker struct {
+	ttls map[string]trackedTtl
+	// expiring maps a live until ledger to the key hashes of the entries whose TTL ends at that ledger
+	expiring map[uint32][]string
+}
+
+// NewArchivalTracker returns a tracker that has not seen any TTL yet
+func NewArchivalTracker() *ArchivalTracker {
+	return &ArchivalTracker{ttls: map[string]trackedTtl{}, expiring: map[uint32][]string{}}
+}
+
+// TransformArchivalEvents converts the archival signals of a ledger into events: the tracked entries whose TTL passed
+// in the previous ledger (expired), the entries restored by its transactions (restored) and the entries it evicted
+// from the live bucket list (evicted). The TTLs written by the transactions are kept in the tracker.
+func (t *ArchivalTracker) TransformArchivalEvents(lcm xdr.LedgerCloseMeta, transactions []ingest.LedgerTransaction) ([]ArchivalEventOutput, error) {
+	lhe := lcm.LedgerHeaderHistoryEntry()
+	ledgerSequence := uint32(lhe.Header.LedgerSeq)
+	closedAt, err := utils.TimePointToUTCTimeStamp(lhe.Header.ScpValue.CloseTime)
+	if err != nil {
+		return []ArchivalEventOutput{}, fmt.Errorf("for ledger %d: %v", ledgerSequence, err)
+	}
+
+	newEvent := func(eventType string, key xdr.LedgerKey) (ArchivalEventOutput, error) {
+		ledgerKey, err := xdr.MarshalBase64(key)
+		if err != nil {
+			return ArchivalEventOutput{}, fmt.Errorf("for ledger %d: could not encode ledger key: %v", ledgerSequence, err)
+		}
+		event := ArchivalEventOutput{
+			LedgerSequence:  ledgerSequence,
+			ClosedAt:        closedAt,
+			EventType:       eventType,
+			LedgerKey:       ledgerKey,
+			LedgerKeyHash:   utils.LedgerKeyToLedgerKeyHash(key),
+			LedgerEntryType: key.Type.String(),
+		}
+		switch key.Type {
+		case xdr.LedgerEntryTypeContractData:
+			event.ContractId = contractIdFromContractData(key)
+			event.ContractDurability = key.ContractData.Durability.String()
+		case xdr.LedgerEntryTypeContractCode:
+			event.ContractCodeHash = key.ContractCode.Hash.HexString()
+		}
+		return event, nil
+	}
+
+	events := []ArchivalEventOutput{}
+	if ledgerSequence > 0 {
+		for _, keyHash := range t.expiring[ledgerSequence-1] {
+			tracked, ok := t.ttls[keyHash]
+			if !ok || tracked.liveUntil != ledgerSequence-1 {
+				continue
+			}
+			event, err := newEvent("expired", tracked.key)
+			if err != nil {
+				return []ArchivalEventOutput{}, err
+			}
+			event.LiveUntilLedgerSeq = null.IntFrom(int64(tracked.liveUntil))
+			events = append(events, event)
+			if tracked.key.Type == xdr.LedgerEntryTypeContractData && tracked.key.ContractData.Durability == xdr.ContractDataDurabilityTemporary {
+				delete(t.ttls, keyHash)
+			}
+		}
+		delete(t.expiring, ledgerSequence-1)
+	}
+
+	for _, transaction := range transactions {
+		if !transaction.IsSorobanTx() || !transaction.Result.Successful() {
+			continue
+		}
+		restored, err := t.trackTransaction(transaction, ledgerSequence)
+		if err != nil {
+			return []ArchivalEventOutput{}, fmt.Errorf("for ledger %d; transaction %d: %v", ledgerSequence, transaction.Index, err)
+		}
+		var operationType string
+		if operations := transaction.Envelope.Operations(); len(operations) > 0 {
+			operationType, err = mapOperationType(operations[0])
+			if err != nil {
+				return []ArchivalEventOutput{}, err
+			}
+		}
+		for _, tracked := range restored {
+			event, err := newEvent("restored", tracked.key)
+			if err != nil {
+				return []ArchivalEventOutput{}, err
+			}
+			event.LiveUntilLedgerSeq = null.IntFrom(int64(tracked.liveUntil))
+			event.TransactionHash = utils.HashToHexString(transaction.Result.TransactionHash)
+			event.OperationType = operationType
+			events = append(events, event)
+		}
+	}
+
+	evictedKeys, err := lcm.EvictedLedgerKeys()
+	if err != nil {
+		return []ArchivalEventOutput{}, fmt.Errorf("for ledger %d: %v", ledgerSequence, err)
+	}
+	for _, key := range evictedKeys {
+		// The TTL entries evicted along with their entries do not need events of their own
+		if key.Type == xdr.LedgerEntryTypeTtl {
+			continue
+		}
+		event, err := newEvent("evicted", key)
+		if err != nil {
+			return []ArchivalEventOutput{}, err
+		}
+		if tracked, ok := t.ttls[event.LedgerKeyHash]; ok {
+			event.LiveUntilLedgerSeq = null.IntFrom(int64(tracked.liveUntil))
+			delete(t.ttls, event.LedgerKeyHash)
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// trackTransaction keeps the TTLs written by transaction and returns the entries it restored, either explicitly or
+// automatically. Entries restored before protocol 23 are recognised by a TTL that had already passed.
+func (t *ArchivalTracker) trackTransaction(transaction ingest.LedgerTransaction, ledgerSequence uint32) ([]trackedTtl, error) {
+	sorobanData, ok := getTransactionV1Envelope(transaction.Envelope).Tx.Ext.GetSorobanData()
+	if !ok {
+		return nil, fmt.Errorf("not a soroban transaction")
+	}
+	footprintKeys := map[string]xdr.LedgerKey{}
+	for _, key := range append(sorobanData.Resources.Footprint.ReadOnly, sorobanData.Resources.Footprint.ReadWrite...) {
+		footprintKeys[utils.LedgerKeyToLedgerKeyHash(key)] = key
+	}
+
+	changes, err := transaction.GetChanges()
+	if err != nil {
+		return nil, err
+	}
+	restored := []trackedTtl{}
+	for _, change := range changes {
+		if change.Type != xdr.LedgerEntryTypeTtl {
+			continue
+		}
+		if change.Post == nil {
+			delete(t.ttls, change.Pre.Data.MustTtl().KeyHash.HexString())
+			continue
+		}
+		ttl := change.Post.Data.MustTtl()
+		keyHash := ttl.KeyHash.HexString()
+		key, ok := footprintKeys[keyHash]
+		if !ok {
+			continue
+		}
+		tracked := trackedTtl{key: key, liveUntil: uint32(ttl.LiveUntilLedgerSeq)}
+		if change.ChangeType == xdr.LedgerEntryChangeTypeLedgerEntryRestored ||
+			(change.Pre != nil && uint32(change.Pre.Data.MustTtl().LiveUntilLedgerSeq) < ledgerSequence) {
+			restored = append(restored, tracked)
+		}
+		t.ttls[keyHash] = tracked
+		t.expiring[tracked.liveUntil] = append(t.expiring[tracked.liveUntil], keyHash)
+	}
+	return restored, nil
+}
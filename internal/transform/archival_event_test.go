@@ -0,0 +1,107 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/guregu/null"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+)
+
+func makeArchivalTestLedger(sequence uint32, evictedKeys ...xdr.LedgerKey) xdr.LedgerCloseMeta {
+	return xdr.LedgerCloseMeta{V: 1, V1: &xdr.LedgerCloseMetaV1{
+		LedgerHeader: xdr.LedgerHeaderHistoryEntry{Header: xdr.LedgerHeader{LedgerSeq: xdr.Uint32(sequence), ScpValue: xdr.StellarValue{CloseTime: 1000}}},
+		EvictedKeys:  evictedKeys,
+	}}
+}
+
+func TestTransformArchivalEvents(t *testing.T) {
+	var contractId xdr.ContractId
+	var codeHash xdr.Hash
+	contractId[0], codeHash[0] = 1, 2
+	dataKey := xdr.LedgerKey{
+		Type: xdr.LedgerEntryTypeContractData,
+		ContractData: &xdr.LedgerKeyContractData{
+			Contract:   xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeContract, ContractId: &contractId},
+			Key:        scSymbol("balance"),
+			Durability: xdr.ContractDataDurabilityPersistent,
+		},
+	}
+	codeKey := xdr.LedgerKey{Type: xdr.LedgerEntryTypeContractCode, ContractCode: &xdr.LedgerKeyContractCode{Hash: codeHash}}
+	makeTransaction := func(changes ...xdr.LedgerEntryChange) ingest.LedgerTransaction {
+		transaction, _ := makeContractInvocationTestInput(t, nil)
+		transaction.Envelope.V1.Tx.Ext = xdr.TransactionExt{V: 1, SorobanData: &xdr.SorobanTransactionData{
+			Resources: xdr.SorobanResources{Footprint: xdr.LedgerFootprint{ReadWrite: []xdr.LedgerKey{dataKey}}},
+		}}
+		transaction.UnsafeMeta.V3.Operations = []xdr.OperationMeta{{Changes: changes}}
+		return transaction
+	}
+
+	tracker := NewArchivalTracker()
+	events, err := tracker.TransformArchivalEvents(makeArchivalTestLedger(10), []ingest.LedgerTransaction{makeTransaction(
+		xdr.LedgerEntryChange{Type: xdr.LedgerEntryChangeTypeLedgerEntryCreated, Created: makeTtlTestEntry(t, dataKey, 11)},
+	)})
+	assert.NoError(t, err)
+	assert.Empty(t, events)
+
+	// The TTL of the entry ended at ledger 11
+	events, err = tracker.TransformArchivalEvents(makeArchivalTestLedger(12), nil)
+	assert.NoError(t, err)
+	closedAt, _ := utils.TimePointToUTCTimeStamp(1000)
+	ledgerKey, _ := xdr.MarshalBase64(dataKey)
+	assert.Equal(t, []ArchivalEventOutput{{
+		LedgerSequence:     12,
+		ClosedAt:           closedAt,
+		EventType:          "expired",
+		LedgerKey:          ledgerKey,
+		LedgerKeyHash:      utils.LedgerKeyToLedgerKeyHash(dataKey),
+		LedgerEntryType:    "LedgerEntryTypeContractData",
+		ContractId:         "CAAQAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABDQF",
+		ContractDurability: "ContractDataDurabilityPersistent",
+		LiveUntilLedgerSeq: null.IntFrom(11),
+	}}, events)
+
+	restore := makeTransaction(
+		xdr.LedgerEntryChange{Type: xdr.LedgerEntryChangeTypeLedgerEntryState, State: makeTtlTestEntry(t, dataKey, 11)},
+		xdr.LedgerEntryChange{Type: xdr.LedgerEntryChangeTypeLedgerEntryUpdated, Updated: makeTtlTestEntry(t, dataKey, 100)},
+	)
+	restore.Envelope.V1.Tx.Operations[0].Body = xdr.OperationBody{Type: xdr.OperationTypeRestoreFootprint, RestoreFootprintOp: &xdr.RestoreFootprintOp{}}
+	events, err = tracker.TransformArchivalEvents(makeArchivalTestLedger(13), []ingest.LedgerTransaction{restore})
+	assert.NoError(t, err)
+	assert.Len(t, events, 1)
+	assert.Equal(t, "restored", events[0].EventType)
+	assert.Equal(t, "restore_footprint", events[0].OperationType)
+	assert.Equal(t, utils.HashToHexString(restore.Result.TransactionHash), events[0].TransactionHash)
+	assert.Equal(t, null.IntFrom(100), events[0].LiveUntilLedgerSeq)
+
+	// The restored entry does not expire at its old TTL, and only the code is evicted
+	events, err = tracker.TransformArchivalEvents(makeArchivalTestLedger(14, codeKey, xdr.LedgerKey{Type: xdr.LedgerEntryTypeTtl, Ttl: &xdr.LedgerKeyTtl{}}), nil)
+	assert.NoError(t, err)
+	assert.Len(t, events, 1)
+	assert.Equal(t, "evicted", events[0].EventType)
+	assert.Equal(t, codeHash.HexString(), events[0].ContractCodeHash)
+	assert.False(t, events[0].LiveUntilLedgerSeq.Valid)
+
+	// Temporary entries are dropped once expired, as they cannot be restored
+	temporaryKey := xdr.LedgerKey{
+		Type: xdr.LedgerEntryTypeContractData,
+		ContractData: &xdr.LedgerKeyContractData{
+			Contract:   dataKey.ContractData.Contract,
+			Key:        scSymbol("nonce"),
+			Durability: xdr.ContractDataDurabilityTemporary,
+		},
+	}
+	temporary := makeTransaction(xdr.LedgerEntryChange{Type: xdr.LedgerEntryChangeTypeLedgerEntryCreated, Created: makeTtlTestEntry(t, temporaryKey, 15)})
+	temporary.Envelope.V1.Tx.Ext.SorobanData.Resources.Footprint.ReadWrite = []xdr.LedgerKey{temporaryKey}
+	_, err = tracker.TransformArchivalEvents(makeArchivalTestLedger(15, dataKey), []ingest.LedgerTransaction{temporary})
+	assert.NoError(t, err)
+	assert.Len(t, tracker.ttls, 1)
+	events, err = tracker.TransformArchivalEvents(makeArchivalTestLedger(16), nil)
+	assert.NoError(t, err)
+	assert.Len(t, events, 1)
+	assert.Equal(t, "expired", events[0].EventType)
+	assert.Empty(t, tracker.ttls)
+}
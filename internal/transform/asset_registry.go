@@ -0,0 +1,168 @@
+package transform
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-etl/v2/internal/toid"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+)
+
+// assetSource is an asset used by an operation along with how it was used
+type assetSource struct {
+	asset      xdr.Asset
+	sourceType string
+}
+
+// TransformAssetRegistry returns every asset used by a successful transaction, once per asset, with the first
+// operation that used it and how. Assets are read from the operations themselves, which is the only place the paths of
+// path payments appear, and from the trustlines, offers, claimable balances, liquidity pools and Stellar Asset
+// Contract instances the operations changed.
+func TransformAssetRegistry(transaction ingest.LedgerTransaction, lhe xdr.LedgerHeaderHistoryEntry, passphrase string) ([]AssetRegistryOutput, error) {
+	if !transaction.Result.Successful() {
+		return nil, nil
+	}
+	closedAt, err := utils.TimePointToUTCTimeStamp(lhe.Header.ScpValue.CloseTime)
+	if err != nil {
+		return nil, err
+	}
+	ledgerSequence := uint32(lhe.Header.LedgerSeq)
+	transactionHash := utils.HashToHexString(transaction.Result.TransactionHash)
+
+	outputs := []AssetRegistryOutput{}
+	seen := map[int64]bool{}
+	for index, operation := range transaction.Envelope.Operations() {
+		changes, err := transaction.GetOperationChanges(uint32(index))
+		if err != nil {
+			return nil, err
+		}
+		sources := append(operationAssetSources(operation), changeAssetSources(changes, passphrase)...)
+		for _, source := range sources {
+			output, err := transformSingleAsset(source.asset)
+			if err != nil {
+				return nil, err
+			}
+			if seen[output.AssetID] {
+				continue
+			}
+			seen[output.AssetID] = true
+			contractId, err := source.asset.ContractID(passphrase)
+			if err != nil {
+				return nil, err
+			}
+			outputs = append(outputs, AssetRegistryOutput{
+				AssetCode:       output.AssetCode,
+				AssetIssuer:     output.AssetIssuer,
+				AssetType:       output.AssetType,
+				AssetID:         output.AssetID,
+				ContractId:      strkey.MustEncode(strkey.VersionByteContract, contractId[:]),
+				SourceType:      source.sourceType,
+				FirstSeenLedger: ledgerSequence,
+				TransactionHash: transactionHash,
+				OperationID:     toid.New(int32(ledgerSequence), int32(transaction.Index), int32(index)+1).ToInt64(),
+				ClosedAt:        closedAt,
+			})
+		}
+	}
+	return outputs, nil
+}
+
+// operationAssetSources returns the assets named in the body of an operation
+func operationAssetSources(operation xdr.Operation) []assetSource {
+	sources := []assetSource{}
+	add := func(sourceType string, assets ...xdr.Asset) {
+		for _, asset := range assets {
+			sources = append(sources, assetSource{asset: asset, sourceType: sourceType})
+		}
+	}
+	body := operation.Body
+	switch body.Type {
+	case xdr.OperationTypePayment:
+		add("payment", body.MustPaymentOp().Asset)
+	case xdr.OperationTypePathPaymentStrictReceive:
+		op := body.MustPathPaymentStrictReceiveOp()
+		add("payment", op.SendAsset, op.DestAsset)
+		add("path_payment", op.Path...)
+	case xdr.OperationTypePathPaymentStrictSend:
+		op := body.MustPathPaymentStrictSendOp()
+		add("payment", op.SendAsset, op.DestAsset)
+		add("path_payment", op.Path...)
+	case xdr.OperationTypeManageSellOffer:
+		op := body.MustManageSellOfferOp()
+		add("offer", op.Selling, op.Buying)
+	case xdr.OperationTypeManageBuyOffer:
+		op := body.MustManageBuyOfferOp()
+		add("offer", op.Selling, op.Buying)
+	case xdr.OperationTypeCreatePassiveSellOffer:
+		op := body.MustCreatePassiveSellOfferOp()
+		add("offer", op.Selling, op.Buying)
+	case xdr.OperationTypeChangeTrust:
+		line := body.MustChangeTrustOp().Line
+		if line.Type == xdr.AssetTypeAssetTypePoolShare {
+			params := line.LiquidityPool.ConstantProduct
+			add("liquidity_pool", params.AssetA, params.AssetB)
+		} else {
+			add("trustline", line.ToAsset())
+		}
+	case xdr.OperationTypeCreateClaimableBalance:
+		add("claimable_balance", body.MustCreateClaimableBalanceOp().Asset)
+	}
+	return sources
+}
+
+// changeAssetSources returns the assets held by the ledger entries that changed, from both before and after the change
+func changeAssetSources(changes []ingest.Change, passphrase string) []assetSource {
+	sources := []assetSource{}
+	for _, change := range changes {
+		for _, entry := range []*xdr.LedgerEntry{change.Pre, change.Post} {
+			if entry == nil {
+				continue
+			}
+			switch entry.Data.Type {
+			case xdr.LedgerEntryTypeTrustline:
+				if asset := entry.Data.MustTrustLine().Asset; asset.Type != xdr.AssetTypeAssetTypePoolShare {
+					sources = append(sources, assetSource{asset: asset.ToAsset(), sourceType: "trustline"})
+				}
+			case xdr.LedgerEntryTypeOffer:
+				offer := entry.Data.MustOffer()
+				sources = append(sources, assetSource{asset: offer.Selling, sourceType: "offer"}, assetSource{asset: offer.Buying, sourceType: "offer"})
+			case xdr.LedgerEntryTypeClaimableBalance:
+				sources = append(sources, assetSource{asset: entry.Data.MustClaimableBalance().Asset, sourceType: "claimable_balance"})
+			case xdr.LedgerEntryTypeLiquidityPool:
+				params := entry.Data.MustLiquidityPool().Body.MustConstantProduct().Params
+				sources = append(sources, assetSource{asset: params.AssetA, sourceType: "liquidity_pool"}, assetSource{asset: params.AssetB, sourceType: "liquidity_pool"})
+			case xdr.LedgerEntryTypeContractData:
+				if asset := AssetFromContractData(*entry, passphrase); asset != nil {
+					sources = append(sources, assetSource{asset: *asset, sourceType: "sac_deployment"})
+				}
+			}
+		}
+	}
+	return sources
+}
+
+// LoadAssetRegistry reads the JSON lines written by export_asset_registry, keeping the earliest row of every asset
+func LoadAssetRegistry(in io.Reader) (map[int64]AssetRegistryOutput, error) {
+	registry := map[int64]AssetRegistryOutput{}
+	err := readJSONLines(in, func(line []byte) error {
+		var asset AssetRegistryOutput
+		if err := json.Unmarshal(line, &asset); err != nil {
+			return err
+		}
+		if asset.AssetType == "" {
+			return fmt.Errorf("asset without an asset_type")
+		}
+		if previous, ok := registry[asset.AssetID]; !ok || asset.FirstSeenLedger < previous.FirstSeenLedger {
+			registry[asset.AssetID] = asset
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return registry, nil
+}
@@ -0,0 +1,91 @@
+package transform
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stellar/go-stellar-sdk/network"
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-etl/v2/internal/toid"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransformAssetRegistry(t *testing.T) {
+	claimableAsset := xdr.MustNewCreditAsset("CBA", testAccount1Address)
+	trustedAsset := xdr.MustNewCreditAsset("NEW", testAccount2Address)
+
+	balanceId := xdr.ClaimableBalanceId{Type: xdr.ClaimableBalanceIdTypeClaimableBalanceIdTypeV0, V0: &xdr.Hash{1}}
+
+	transaction := genericLedgerTransaction
+	envelope := genericBumpOperationEnvelope
+	envelope.Tx.Operations = []xdr.Operation{
+		{Body: xdr.OperationBody{Type: xdr.OperationTypePayment, PaymentOp: &xdr.PaymentOp{Asset: usdtAsset}}},
+		{Body: xdr.OperationBody{Type: xdr.OperationTypePathPaymentStrictSend, PathPaymentStrictSendOp: &xdr.PathPaymentStrictSendOp{
+			SendAsset: usdtAsset,
+			DestAsset: nativeAsset,
+			Path:      []xdr.Asset{ethAsset},
+		}}},
+		{Body: xdr.OperationBody{Type: xdr.OperationTypeChangeTrust, ChangeTrustOp: &xdr.ChangeTrustOp{Line: trustedAsset.ToChangeTrustAsset()}}},
+	}
+	transaction.Envelope.V1 = &envelope
+	// The payment also releases a claimable balance, which is only seen in the changes
+	transaction.UnsafeMeta = xdr.TransactionMeta{V: 1, V1: &xdr.TransactionMetaV1{Operations: []xdr.OperationMeta{
+		{Changes: xdr.LedgerEntryChanges{
+			{Type: xdr.LedgerEntryChangeTypeLedgerEntryState, State: &xdr.LedgerEntry{Data: xdr.LedgerEntryData{
+				Type:             xdr.LedgerEntryTypeClaimableBalance,
+				ClaimableBalance: &xdr.ClaimableBalanceEntry{BalanceId: balanceId, Asset: claimableAsset, Amount: 10},
+			}}},
+			{Type: xdr.LedgerEntryChangeTypeLedgerEntryRemoved, Removed: &xdr.LedgerKey{
+				Type:             xdr.LedgerEntryTypeClaimableBalance,
+				ClaimableBalance: &xdr.LedgerKeyClaimableBalance{BalanceId: balanceId},
+			}},
+		}},
+		{},
+		{},
+	}}}
+	lhe := xdr.LedgerHeaderHistoryEntry{Header: xdr.LedgerHeader{LedgerSeq: 30, ScpValue: xdr.StellarValue{CloseTime: 1000}}}
+
+	assets, err := TransformAssetRegistry(transaction, lhe, network.TestNetworkPassphrase)
+	assert.NoError(t, err)
+	type registered struct {
+		code, sourceType string
+		operationIndex   int32
+	}
+	got := []registered{}
+	for _, asset := range assets {
+		got = append(got, registered{asset.AssetCode, asset.SourceType, toid.Parse(asset.OperationID).OperationOrder})
+		assert.Equal(t, uint32(30), asset.FirstSeenLedger)
+		assert.Equal(t, utils.HashToHexString(transaction.Result.TransactionHash), asset.TransactionHash)
+	}
+	assert.Equal(t, []registered{
+		{"USDT", "payment", 1},
+		{"CBA", "claimable_balance", 1},
+		{"", "payment", 2},
+		{"ETH", "path_payment", 2},
+		{"NEW", "trustline", 3},
+	}, got)
+	contractId, _ := trustedAsset.ContractID(network.TestNetworkPassphrase)
+	assert.Equal(t, strkey.MustEncode(strkey.VersionByteContract, contractId[:]), assets[4].ContractId)
+	assert.Equal(t, "native", assets[2].AssetType)
+
+	transaction.Result = utils.CreateSampleResultMeta(false, 3).Result
+	assets, err = TransformAssetRegistry(transaction, lhe, network.TestNetworkPassphrase)
+	assert.NoError(t, err)
+	assert.Empty(t, assets)
+}
+
+func TestLoadAssetRegistry(t *testing.T) {
+	registry, err := LoadAssetRegistry(strings.NewReader(`{"asset_type":"native","asset_id":1,"first_seen_ledger":20}
+
+{"asset_type":"native","asset_id":1,"first_seen_ledger":10}
+{"asset_type":"native","asset_id":1,"first_seen_ledger":15}
+`))
+	assert.NoError(t, err)
+	assert.Len(t, registry, 1)
+	assert.Equal(t, uint32(10), registry[1].FirstSeenLedger)
+
+	_, err = LoadAssetRegistry(strings.NewReader(`{"asset_id":1}`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,351 @@
+package transform
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+)
+
+// The trustlines of an asset are counted by their authorization
+const (
+	trustlineAuthorized = iota
+	trustlineAuthorizedToMaintainLiabilities
+	trustlineUnauthorized
+)
+
+// assetStat holds the totals of an asset. Amounts are kept in stroops, as they can add up beyond an int64.
+type assetStat struct {
+	asset                   xdr.Asset
+	contractId              string
+	accounts                [3]int64
+	balances                [3]*big.Int
+	claimableBalances       int64
+	claimableBalancesAmount *big.Int
+	liquidityPools          int64
+	liquidityPoolsAmount    *big.Int
+}
+
+// contractStat holds the balances of a Stellar Asset Contract that belong to contracts. Accounts hold the asset of
+// the contract in trustlines instead.
+type contractStat struct {
+	holders int64
+	amount  *big.Int
+}
+
+// trackedContractBalance is what a Stellar Asset Contract balance entry contributes to the totals of its contract. The
+// tracker keeps them by the ledger key hash of the entry.
+type trackedContractBalance struct {
+	contractId string
+	amount     *big.Int
+}
+
+// AssetStatsTracker keeps the supply and holders of every credit asset up to date from the ledger entry changes it is
+// given, which can come from a checkpoint as well as from ledgers. Only the assets touched since the last call to
+// Stats are returned by it.
+type AssetStatsTracker struct {
+	passphrase       string
+	assets           map[string]*assetStat
+	contractAssets   map[string]string
+	contracts        map[string]*contractStat
+	contractBalances map[string]trackedContractBalance
+	issuerFlags      map[string]xdr.AccountFlags
+	touched          map[string]bool
+	touchedContracts map[string]bool
+	touchedIssuers   map[string]bool
+}
+
+// NewAssetStatsTracker returns a tracker with no assets, for the network with the given passphrase
+func NewAssetStatsTracker(passphrase string) *AssetStatsTracker {
+	return &AssetStatsTracker{
+		passphrase:       passphrase,
+		assets:           map[string]*assetStat{},
+		contractAssets:   map[string]string{},
+		contracts:        map[string]*contractStat{},
+		contractBalances: map[string]trackedContractBalance{},
+		issuerFlags:      map[string]xdr.AccountFlags{},
+		touched:          map[string]bool{},
+		touchedContracts: map[string]bool{},
+		touchedIssuers:   map[string]bool{},
+	}
+}
+
+// AddChange removes what the entry before the change contributed to the totals, and adds what the entry after it does
+func (t *AssetStatsTracker) AddChange(change ingest.Change) error {
+	switch change.Type {
+	case xdr.LedgerEntryTypeAccount:
+		return t.addAccountChange(change)
+	case xdr.LedgerEntryTypeContractData:
+		return t.addContractDataChange(change)
+	}
+	if change.Pre != nil {
+		if err := t.addEntry(change.Pre, -1); err != nil {
+			return err
+		}
+	}
+	if change.Post != nil {
+		if err := t.addEntry(change.Post, 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addAccountChange keeps the flags of the accounts that have any, which are the issuers that restrict their assets
+func (t *AssetStatsTracker) addAccountChange(change ingest.Change) error {
+	var preFlags, postFlags xdr.AccountFlags
+	var address string
+	var err error
+	if change.Pre != nil {
+		account := change.Pre.Data.MustAccount()
+		preFlags = xdr.AccountFlags(account.Flags)
+		address, err = account.AccountId.GetAddress()
+	}
+	if change.Post != nil {
+		account := change.Post.Data.MustAccount()
+		postFlags = xdr.AccountFlags(account.Flags)
+		address, err = account.AccountId.GetAddress()
+	}
+	if err != nil {
+		return err
+	}
+	if preFlags == postFlags {
+		return nil
+	}
+	if postFlags == 0 {
+		delete(t.issuerFlags, address)
+	} else {
+		t.issuerFlags[address] = postFlags
+	}
+	t.touchedIssuers[address] = true
+	return nil
+}
+
+// addContractDataChange replaces what a Stellar Asset Contract balance entry contributed with what it does after the
+// change. Contributions are kept by ledger key rather than taken from the entry before the change, as restored entries
+// come without one and evicted entries are only known by their key.
+func (t *AssetStatsTracker) addContractDataChange(change ingest.Change) error {
+	entry := change.Post
+	if entry == nil {
+		entry = change.Pre
+	}
+	balance, ok, err := contractBalanceFromData(entry.Data.MustContractData())
+	if err != nil || !ok || balance.TokenType != "sac" {
+		return err
+	}
+	key, err := entry.LedgerKey()
+	if err != nil {
+		return err
+	}
+	keyHash := utils.LedgerKeyToLedgerKeyHash(key)
+	t.removeContractBalance(keyHash)
+	if change.Post == nil {
+		return nil
+	}
+
+	amount, ok := new(big.Int).SetString(balance.Amount, 10)
+	if !ok {
+		return fmt.Errorf("invalid balance amount %s of contract %s", balance.Amount, balance.ContractId)
+	}
+	contract, ok := t.contracts[balance.ContractId]
+	if !ok {
+		contract = &contractStat{amount: new(big.Int)}
+		t.contracts[balance.ContractId] = contract
+	}
+	contract.holders++
+	contract.amount.Add(contract.amount, amount)
+	t.contractBalances[keyHash] = trackedContractBalance{contractId: balance.ContractId, amount: amount}
+	t.touchedContracts[balance.ContractId] = true
+	return nil
+}
+
+// EvictLedgerKey removes the entry evicted from the live bucket list with the given key from the totals. Only Stellar
+// Asset Contract balances can be evicted among the entries counted.
+func (t *AssetStatsTracker) EvictLedgerKey(key xdr.LedgerKey) {
+	if key.Type == xdr.LedgerEntryTypeContractData {
+		t.removeContractBalance(utils.LedgerKeyToLedgerKeyHash(key))
+	}
+}
+
+// removeContractBalance removes what the balance entry with the given key hash contributes, if it is counted
+func (t *AssetStatsTracker) removeContractBalance(keyHash string) {
+	tracked, ok := t.contractBalances[keyHash]
+	if !ok {
+		return
+	}
+	contract := t.contracts[tracked.contractId]
+	contract.holders--
+	contract.amount.Sub(contract.amount, tracked.amount)
+	delete(t.contractBalances, keyHash)
+	t.touchedContracts[tracked.contractId] = true
+}
+
+// addEntry adds sign times what entry contributes to the totals of the assets it holds
+func (t *AssetStatsTracker) addEntry(entry *xdr.LedgerEntry, sign int64) error {
+	switch entry.Data.Type {
+	case xdr.LedgerEntryTypeTrustline:
+		trustline := entry.Data.MustTrustLine()
+		if trustline.Asset.Type == xdr.AssetTypeAssetTypePoolShare {
+			return nil
+		}
+		stat, err := t.stat(trustline.Asset.ToAsset())
+		if err != nil {
+			return err
+		}
+		authorization := trustlineUnauthorized
+		if trustline.Flags&xdr.Uint32(xdr.TrustLineFlagsAuthorizedFlag) != 0 {
+			authorization = trustlineAuthorized
+		} else if trustline.Flags&xdr.Uint32(xdr.TrustLineFlagsAuthorizedToMaintainLiabilitiesFlag) != 0 {
+			authorization = trustlineAuthorizedToMaintainLiabilities
+		}
+		stat.accounts[authorization] += sign
+		stat.balances[authorization].Add(stat.balances[authorization], big.NewInt(sign*int64(trustline.Balance)))
+
+	case xdr.LedgerEntryTypeClaimableBalance:
+		claimableBalance := entry.Data.MustClaimableBalance()
+		if claimableBalance.Asset.Type == xdr.AssetTypeAssetTypeNative {
+			return nil
+		}
+		stat, err := t.stat(claimableBalance.Asset)
+		if err != nil {
+			return err
+		}
+		stat.claimableBalances += sign
+		stat.claimableBalancesAmount.Add(stat.claimableBalancesAmount, big.NewInt(sign*int64(claimableBalance.Amount)))
+
+	case xdr.LedgerEntryTypeLiquidityPool:
+		pool := entry.Data.MustLiquidityPool().Body.MustConstantProduct()
+		for _, reserve := range []struct {
+			asset  xdr.Asset
+			amount xdr.Int64
+		}{{pool.Params.AssetA, pool.ReserveA}, {pool.Params.AssetB, pool.ReserveB}} {
+			if reserve.asset.Type == xdr.AssetTypeAssetTypeNative {
+				continue
+			}
+			stat, err := t.stat(reserve.asset)
+			if err != nil {
+				return err
+			}
+			stat.liquidityPools += sign
+			stat.liquidityPoolsAmount.Add(stat.liquidityPoolsAmount, big.NewInt(sign*int64(reserve.amount)))
+		}
+
+	}
+	return nil
+}
+
+// stat returns the totals of asset, creating them if needed, and marks it as touched
+func (t *AssetStatsTracker) stat(asset xdr.Asset) (*assetStat, error) {
+	key := asset.StringCanonical()
+	stat, ok := t.assets[key]
+	if !ok {
+		contractId, err := asset.ContractID(t.passphrase)
+		if err != nil {
+			return nil, err
+		}
+		stat = &assetStat{
+			asset:                   asset,
+			contractId:              strkey.MustEncode(strkey.VersionByteContract, contractId[:]),
+			balances:                [3]*big.Int{new(big.Int), new(big.Int), new(big.Int)},
+			claimableBalancesAmount: new(big.Int),
+			liquidityPoolsAmount:    new(big.Int),
+		}
+		t.assets[key] = stat
+		t.contractAssets[stat.contractId] = key
+	}
+	t.touched[key] = true
+	return stat, nil
+}
+
+// Stats returns the statistics of the assets touched since the last call, sorted by asset, as of the given ledger.
+// Assets that are no longer held by anyone are reported one last time and then forgotten.
+func (t *AssetStatsTracker) Stats(header xdr.LedgerHeaderHistoryEntry) ([]AssetStatOutput, error) {
+	closedAt, err := utils.TimePointToUTCTimeStamp(header.Header.ScpValue.CloseTime)
+	if err != nil {
+		return nil, err
+	}
+
+	for contractId := range t.touchedContracts {
+		if key, ok := t.contractAssets[contractId]; ok {
+			t.touched[key] = true
+		}
+	}
+	if len(t.touchedIssuers) > 0 {
+		for key, stat := range t.assets {
+			if t.touchedIssuers[stat.asset.GetIssuer()] {
+				t.touched[key] = true
+			}
+		}
+	}
+	keys := make([]string, 0, len(t.touched))
+	for key := range t.touched {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	t.touched, t.touchedContracts, t.touchedIssuers = map[string]bool{}, map[string]bool{}, map[string]bool{}
+
+	outputs := make([]AssetStatOutput, 0, len(keys))
+	for _, key := range keys {
+		stat, ok := t.assets[key]
+		if !ok {
+			continue
+		}
+		var assetType, code, issuer string
+		if err := stat.asset.Extract(&assetType, &code, &issuer); err != nil {
+			return nil, err
+		}
+		output := AssetStatOutput{
+			AssetCode:             code,
+			AssetIssuer:           issuer,
+			AssetType:             assetType,
+			AssetID:               FarmHashAsset(code, issuer, assetType),
+			ContractId:            stat.contractId,
+			NumAccountsAuthorized: stat.accounts[trustlineAuthorized],
+			NumAccountsAuthorizedToMaintainLiabilities: stat.accounts[trustlineAuthorizedToMaintainLiabilities],
+			NumAccountsUnauthorized:                    stat.accounts[trustlineUnauthorized],
+			BalanceAuthorized:                          bigStroopsToReal(stat.balances[trustlineAuthorized]),
+			BalanceAuthorizedToMaintainLiabilities:     bigStroopsToReal(stat.balances[trustlineAuthorizedToMaintainLiabilities]),
+			BalanceUnauthorized:                        bigStroopsToReal(stat.balances[trustlineUnauthorized]),
+			NumClaimableBalances:                       stat.claimableBalances,
+			ClaimableBalancesAmount:                    bigStroopsToReal(stat.claimableBalancesAmount),
+			NumLiquidityPools:                          stat.liquidityPools,
+			LiquidityPoolsAmount:                       bigStroopsToReal(stat.liquidityPoolsAmount),
+			ClosedAt:                                   closedAt,
+			LedgerSequence:                             uint32(header.Header.LedgerSeq),
+		}
+		supply := new(big.Int).Add(stat.balances[trustlineAuthorized], stat.balances[trustlineAuthorizedToMaintainLiabilities])
+		supply.Add(supply, stat.balances[trustlineUnauthorized])
+		supply.Add(supply, stat.claimableBalancesAmount)
+		supply.Add(supply, stat.liquidityPoolsAmount)
+		output.NumHolders = stat.accounts[trustlineAuthorized] + stat.accounts[trustlineAuthorizedToMaintainLiabilities] + stat.accounts[trustlineUnauthorized]
+		if contract, ok := t.contracts[stat.contractId]; ok {
+			output.NumContracts = contract.holders
+			output.ContractsAmount = bigStroopsToReal(contract.amount)
+			output.NumHolders += contract.holders
+			supply.Add(supply, contract.amount)
+		}
+		output.TotalSupply = bigStroopsToReal(supply)
+
+		flags := t.issuerFlags[issuer]
+		output.AuthRequired = flags.IsAuthRequired()
+		output.AuthRevocable = flags.IsAuthRevocable()
+		output.AuthImmutable = flags.IsAuthImmutable()
+		output.AuthClawbackEnabled = flags.IsAuthClawbackEnabled()
+		outputs = append(outputs, output)
+
+		if output.NumHolders == 0 && stat.claimableBalances == 0 && stat.liquidityPools == 0 {
+			delete(t.assets, key)
+		}
+	}
+	return outputs, nil
+}
+
+// bigStroopsToReal converts an amount of stroops that may not fit in an int64 to units of the asset
+func bigStroopsToReal(stroops *big.Int) float64 {
+	value, _ := new(big.Rat).SetFrac(stroops, big.NewInt(10000000)).Float64()
+	return value
+}
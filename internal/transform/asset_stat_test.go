@@ -0,0 +1,133 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/network"
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stretchr/testify/assert"
+)
+
+func makeAssetStatTestTrustline(account xdr.AccountId, asset xdr.Asset, balance xdr.Int64, flags xdr.TrustLineFlags) *xdr.LedgerEntry {
+	return &xdr.LedgerEntry{Data: xdr.LedgerEntryData{Type: xdr.LedgerEntryTypeTrustline, TrustLine: &xdr.TrustLineEntry{
+		AccountId: account,
+		Asset:     asset.ToTrustLineAsset(),
+		Balance:   balance,
+		Flags:     xdr.Uint32(flags),
+	}}}
+}
+
+func TestAssetStatsTracker(t *testing.T) {
+	tracker := NewAssetStatsTracker(network.TestNetworkPassphrase)
+	asset := xdr.MustNewCreditAsset("USDT", testAccount1Address)
+	sacId, _ := asset.ContractID(network.TestNetworkPassphrase)
+	holderContractId := xdr.ContractId{9}
+
+	issuer := &xdr.LedgerEntry{Data: xdr.LedgerEntryData{Type: xdr.LedgerEntryTypeAccount, Account: &xdr.AccountEntry{
+		AccountId: testAccount1ID,
+		Flags:     xdr.Uint32(xdr.AccountFlagsAuthRequiredFlag | xdr.AccountFlagsAuthRevocableFlag),
+	}}}
+	authorized := makeAssetStatTestTrustline(testAccount2ID, asset, 1000000000, xdr.TrustLineFlagsAuthorizedFlag)
+	claimableBalance := &xdr.LedgerEntry{Data: xdr.LedgerEntryData{Type: xdr.LedgerEntryTypeClaimableBalance, ClaimableBalance: &xdr.ClaimableBalanceEntry{
+		Asset:  asset,
+		Amount: 100000000,
+	}}}
+	pool := makePoolMetricTestEntry(300000000, 200000000, 100)
+	pool.Data.LiquidityPool.Body.ConstantProduct.Params.AssetB = asset
+	contractBalance := makeContractBalanceTestEntry(sacId, scVec(scSymbol("Balance"), xdr.ScVal{
+		Type:    xdr.ScValTypeScvAddress,
+		Address: &xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeContract, ContractId: &holderContractId},
+	}), scMap(
+		xdr.ScMapEntry{Key: scSymbol("amount"), Val: scI128(70000000)},
+		xdr.ScMapEntry{Key: scSymbol("authorized"), Val: xdr.ScVal{Type: xdr.ScValTypeScvBool, B: new(bool)}},
+		xdr.ScMapEntry{Key: scSymbol("clawback"), Val: xdr.ScVal{Type: xdr.ScValTypeScvBool, B: new(bool)}},
+	))
+	for _, entry := range []*xdr.LedgerEntry{
+		issuer,
+		contractBalance,
+		authorized,
+		makeAssetStatTestTrustline(testAccount3ID, asset, 50000000, 0),
+		claimableBalance,
+		pool,
+	} {
+		assert.NoError(t, tracker.AddChange(ingest.Change{Type: entry.Data.Type, Post: entry}))
+	}
+
+	header := xdr.LedgerHeaderHistoryEntry{Header: xdr.LedgerHeader{LedgerSeq: 64, ScpValue: xdr.StellarValue{CloseTime: 1000}}}
+	stats, err := tracker.Stats(header)
+	assert.NoError(t, err)
+	assert.Len(t, stats, 1)
+	stat := stats[0]
+	assert.Equal(t, "USDT", stat.AssetCode)
+	assert.Equal(t, testAccount1Address, stat.AssetIssuer)
+	assert.Equal(t, strkey.MustEncode(strkey.VersionByteContract, sacId[:]), stat.ContractId)
+	assert.Equal(t, int64(1), stat.NumAccountsAuthorized)
+	assert.Equal(t, int64(1), stat.NumAccountsUnauthorized)
+	assert.Equal(t, 100.0, stat.BalanceAuthorized)
+	assert.Equal(t, 5.0, stat.BalanceUnauthorized)
+	assert.Equal(t, int64(1), stat.NumClaimableBalances)
+	assert.Equal(t, 10.0, stat.ClaimableBalancesAmount)
+	assert.Equal(t, int64(1), stat.NumLiquidityPools)
+	assert.Equal(t, 20.0, stat.LiquidityPoolsAmount)
+	assert.Equal(t, int64(1), stat.NumContracts)
+	assert.Equal(t, 7.0, stat.ContractsAmount)
+	assert.Equal(t, int64(3), stat.NumHolders)
+	assert.Equal(t, 142.0, stat.TotalSupply)
+	assert.True(t, stat.AuthRequired)
+	assert.True(t, stat.AuthRevocable)
+	assert.False(t, stat.AuthClawbackEnabled)
+	assert.Equal(t, uint32(64), stat.LedgerSequence)
+
+	// Only the assets touched since the last call are returned
+	stats, err = tracker.Stats(header)
+	assert.NoError(t, err)
+	assert.Empty(t, stats)
+
+	assert.NoError(t, tracker.AddChange(ingest.Change{
+		Type: xdr.LedgerEntryTypeTrustline,
+		Pre:  authorized,
+		Post: makeAssetStatTestTrustline(testAccount2ID, asset, 1000000000, xdr.TrustLineFlagsAuthorizedToMaintainLiabilitiesFlag),
+	}))
+	assert.NoError(t, tracker.AddChange(ingest.Change{Type: xdr.LedgerEntryTypeClaimableBalance, Pre: claimableBalance}))
+	stats, err = tracker.Stats(header)
+	assert.NoError(t, err)
+	assert.Len(t, stats, 1)
+	assert.Equal(t, int64(0), stats[0].NumAccountsAuthorized)
+	assert.Equal(t, int64(1), stats[0].NumAccountsAuthorizedToMaintainLiabilities)
+	assert.Equal(t, 100.0, stats[0].BalanceAuthorizedToMaintainLiabilities)
+	assert.Equal(t, int64(0), stats[0].NumClaimableBalances)
+	assert.Equal(t, 132.0, stats[0].TotalSupply)
+
+	// An evicted balance leaves the totals, and restoring it, which comes without the entry before the change, adds it
+	// back once
+	contractBalanceKey, err := contractBalance.LedgerKey()
+	assert.NoError(t, err)
+	tracker.EvictLedgerKey(contractBalanceKey)
+	stats, err = tracker.Stats(header)
+	assert.NoError(t, err)
+	assert.Len(t, stats, 1)
+	assert.Equal(t, int64(0), stats[0].NumContracts)
+	assert.Equal(t, 125.0, stats[0].TotalSupply)
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, tracker.AddChange(ingest.Change{
+			Type:       xdr.LedgerEntryTypeContractData,
+			ChangeType: xdr.LedgerEntryChangeTypeLedgerEntryRestored,
+			Post:       contractBalance,
+		}))
+	}
+	stats, err = tracker.Stats(header)
+	assert.NoError(t, err)
+	assert.Len(t, stats, 1)
+	assert.Equal(t, int64(1), stats[0].NumContracts)
+	assert.Equal(t, 7.0, stats[0].ContractsAmount)
+	assert.Equal(t, 132.0, stats[0].TotalSupply)
+
+	// Changing the flags of the issuer touches its assets
+	assert.NoError(t, tracker.AddChange(ingest.Change{Type: xdr.LedgerEntryTypeAccount, Pre: issuer}))
+	stats, err = tracker.Stats(header)
+	assert.NoError(t, err)
+	assert.Len(t, stats, 1)
+	assert.False(t, stats[0].AuthRequired)
+}
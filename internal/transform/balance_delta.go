@@ -0,0 +1,232 @@
+package transform
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-etl/v2/internal/toid"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+)
+
+// The components a balance change is attributed to
+const (
+	balanceComponentFee = iota
+	balanceComponentTransfer
+	balanceComponentTrade
+	balanceComponentLiquidityPool
+	balanceComponentClaimableBalance
+	balanceComponentClawback
+	balanceComponentOther
+	balanceComponentCount
+)
+
+// balanceDelta is the change of a balance of a holder within a transaction, split by component
+type balanceDelta struct {
+	output     BalanceDeltaOutput
+	components [balanceComponentCount]*big.Int
+}
+
+// balanceChange is the change of a single balance read from a ledger entry change
+type balanceChange struct {
+	holder  string
+	asset   *xdr.Asset
+	tokenId string
+	amount  *big.Int
+}
+
+// TransformBalanceDeltas returns the net change of every balance of an account or contract in a transaction, from the
+// account, trustline and contract balance entries it changed. The fee charged and refunded, including the changes of
+// the transaction as a whole, is kept apart from the changes of each operation, which are attributed to a component by
+// operation type. In path payments, only the balances of the source and destination are transfers, while those of
+// the offers crossed are trades.
+func TransformBalanceDeltas(transaction ingest.LedgerTransaction, lhe xdr.LedgerHeaderHistoryEntry, passphrase string) ([]BalanceDeltaOutput, error) {
+	closedAt, err := utils.TimePointToUTCTimeStamp(lhe.Header.ScpValue.CloseTime)
+	if err != nil {
+		return nil, err
+	}
+	ledgerSequence := uint32(lhe.Header.LedgerSeq)
+
+	changes, err := transaction.GetChanges()
+	if err != nil {
+		return nil, err
+	}
+	changes = append(append(transaction.GetFeeChanges(), changes...), transaction.GetPostApplyFeeChanges()...)
+	operations := transaction.Envelope.Operations()
+
+	deltas := []*balanceDelta{}
+	byKey := map[string]*balanceDelta{}
+	for _, change := range changes {
+		balanceChanges, err := balanceChangesOf(change, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		for _, balance := range balanceChanges {
+			component := balanceComponentFee
+			if change.Reason == ingest.LedgerEntryChangeReasonOperation {
+				operation := operations[change.OperationIndex]
+				component = operationBalanceComponent(operation, transaction, balance.holder)
+			}
+
+			key := balance.holder + "/" + balance.tokenId
+			delta, ok := byKey[key]
+			if !ok {
+				delta = &balanceDelta{output: BalanceDeltaOutput{
+					TransactionHash: utils.HashToHexString(transaction.Result.TransactionHash),
+					TransactionID:   toid.New(int32(ledgerSequence), int32(transaction.Index), 0).ToInt64(),
+					Successful:      transaction.Result.Successful(),
+					Address:         balance.holder,
+					AddressType:     "account",
+					AssetContractId: balance.tokenId,
+					LedgerSequence:  ledgerSequence,
+					ClosedAt:        closedAt,
+				}}
+				if balance.holder[0] == 'C' {
+					delta.output.AddressType = "contract"
+				}
+				if balance.asset != nil {
+					if err := balance.asset.Extract(&delta.output.AssetType, &delta.output.AssetCode, &delta.output.AssetIssuer); err != nil {
+						return nil, err
+					}
+				}
+				for i := range delta.components {
+					delta.components[i] = new(big.Int)
+				}
+				byKey[key] = delta
+				deltas = append(deltas, delta)
+			}
+			delta.components[component].Add(delta.components[component], balance.amount)
+		}
+	}
+
+	outputs := []BalanceDeltaOutput{}
+	for _, delta := range deltas {
+		total := new(big.Int)
+		moved := false
+		for _, amount := range delta.components {
+			total.Add(total, amount)
+			moved = moved || amount.Sign() != 0
+		}
+		if !moved {
+			continue
+		}
+		output := delta.output
+		output.BalanceDelta = total.String()
+		output.FeeDelta = delta.components[balanceComponentFee].String()
+		output.TransferDelta = delta.components[balanceComponentTransfer].String()
+		output.TradeDelta = delta.components[balanceComponentTrade].String()
+		output.LiquidityPoolDelta = delta.components[balanceComponentLiquidityPool].String()
+		output.ClaimableBalanceDelta = delta.components[balanceComponentClaimableBalance].String()
+		output.ClawbackDelta = delta.components[balanceComponentClawback].String()
+		output.OtherDelta = delta.components[balanceComponentOther].String()
+		outputs = append(outputs, output)
+	}
+	return outputs, nil
+}
+
+// balanceChangesOf returns the balances changed by a ledger entry change, along with how much they changed by. The
+// assets of accounts and trustlines are identified by the ID of their Stellar Asset Contract, and the assets of
+// contract balances by their token contract.
+func balanceChangesOf(change ingest.Change, passphrase string) ([]balanceChange, error) {
+	changed := []balanceChange{}
+	for _, side := range []struct {
+		entry *xdr.LedgerEntry
+		sign  int64
+	}{{change.Pre, -1}, {change.Post, 1}} {
+		if side.entry == nil {
+			continue
+		}
+		balance, ok, err := entryBalance(*side.entry, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		balance.amount.Mul(balance.amount, big.NewInt(side.sign))
+		if len(changed) > 0 && changed[0].holder == balance.holder && changed[0].tokenId == balance.tokenId {
+			changed[0].amount.Add(changed[0].amount, balance.amount)
+		} else {
+			changed = append(changed, balance)
+		}
+	}
+
+	nonZero := []balanceChange{}
+	for _, balance := range changed {
+		if balance.amount.Sign() != 0 {
+			nonZero = append(nonZero, balance)
+		}
+	}
+	return nonZero, nil
+}
+
+// entryBalance returns the balance held in a ledger entry, if it holds one
+func entryBalance(entry xdr.LedgerEntry, passphrase string) (balanceChange, bool, error) {
+	var balance balanceChange
+	switch entry.Data.Type {
+	case xdr.LedgerEntryTypeAccount:
+		account := entry.Data.MustAccount()
+		native := xdr.MustNewNativeAsset()
+		balance = balanceChange{holder: account.AccountId.Address(), asset: &native, amount: big.NewInt(int64(account.Balance))}
+	case xdr.LedgerEntryTypeTrustline:
+		trustline := entry.Data.MustTrustLine()
+		if trustline.Asset.Type == xdr.AssetTypeAssetTypePoolShare {
+			return balance, false, nil
+		}
+		asset := trustline.Asset.ToAsset()
+		balance = balanceChange{holder: trustline.AccountId.Address(), asset: &asset, amount: big.NewInt(int64(trustline.Balance))}
+	case xdr.LedgerEntryTypeContractData:
+		contractBalance, ok, err := contractBalanceFromData(entry.Data.MustContractData())
+		if err != nil || !ok {
+			return balance, false, err
+		}
+		amount, ok := new(big.Int).SetString(contractBalance.Amount, 10)
+		if !ok {
+			return balance, false, fmt.Errorf("invalid balance amount %s of contract %s", contractBalance.Amount, contractBalance.ContractId)
+		}
+		return balanceChange{holder: contractBalance.Holder, tokenId: contractBalance.ContractId, amount: amount}, true, nil
+	default:
+		return balance, false, nil
+	}
+
+	contractId, err := balance.asset.ContractID(passphrase)
+	if err != nil {
+		return balance, false, err
+	}
+	balance.tokenId = strkey.MustEncode(strkey.VersionByteContract, contractId[:])
+	return balance, true, nil
+}
+
+// operationBalanceComponent returns the component the balance changes of holder in operation are attributed to
+func operationBalanceComponent(operation xdr.Operation, transaction ingest.LedgerTransaction, holder string) int {
+	switch operation.Body.Type {
+	case xdr.OperationTypePathPaymentStrictReceive, xdr.OperationTypePathPaymentStrictSend:
+		destination := operation.Body.PathPaymentStrictReceiveOp
+		var destinationAccount xdr.MuxedAccount
+		if destination != nil {
+			destinationAccount = destination.Destination
+		} else {
+			destinationAccount = operation.Body.MustPathPaymentStrictSendOp().Destination
+		}
+		source := getOperationSourceAccount(operation, transaction)
+		if holder == source.ToAccountId().Address() || holder == destinationAccount.ToAccountId().Address() {
+			return balanceComponentTransfer
+		}
+		return balanceComponentTrade
+	case xdr.OperationTypePayment, xdr.OperationTypeCreateAccount, xdr.OperationTypeAccountMerge, xdr.OperationTypeInflation,
+		xdr.OperationTypeInvokeHostFunction:
+		return balanceComponentTransfer
+	case xdr.OperationTypeManageSellOffer, xdr.OperationTypeManageBuyOffer, xdr.OperationTypeCreatePassiveSellOffer:
+		return balanceComponentTrade
+	case xdr.OperationTypeLiquidityPoolDeposit, xdr.OperationTypeLiquidityPoolWithdraw:
+		return balanceComponentLiquidityPool
+	case xdr.OperationTypeCreateClaimableBalance, xdr.OperationTypeClaimClaimableBalance:
+		return balanceComponentClaimableBalance
+	case xdr.OperationTypeClawback, xdr.OperationTypeClawbackClaimableBalance:
+		return balanceComponentClawback
+	default:
+		return balanceComponentOther
+	}
+}
@@ -0,0 +1,108 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/stellar/go-stellar-sdk/network"
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func makeBalanceDeltaTestAccountUpdate(account xdr.AccountId, pre, post xdr.Int64) xdr.LedgerEntryChanges {
+	entry := func(balance xdr.Int64) *xdr.LedgerEntry {
+		return &xdr.LedgerEntry{Data: xdr.LedgerEntryData{Type: xdr.LedgerEntryTypeAccount, Account: &xdr.AccountEntry{AccountId: account, Balance: balance}}}
+	}
+	return xdr.LedgerEntryChanges{
+		{Type: xdr.LedgerEntryChangeTypeLedgerEntryState, State: entry(pre)},
+		{Type: xdr.LedgerEntryChangeTypeLedgerEntryUpdated, Updated: entry(post)},
+	}
+}
+
+func makeBalanceDeltaTestTrustlineUpdate(account xdr.AccountId, asset xdr.Asset, pre, post xdr.Int64) xdr.LedgerEntryChanges {
+	entry := func(balance xdr.Int64) *xdr.LedgerEntry {
+		return &xdr.LedgerEntry{Data: xdr.LedgerEntryData{Type: xdr.LedgerEntryTypeTrustline, TrustLine: &xdr.TrustLineEntry{
+			AccountId: account,
+			Asset:     asset.ToTrustLineAsset(),
+			Balance:   balance,
+		}}}
+	}
+	return xdr.LedgerEntryChanges{
+		{Type: xdr.LedgerEntryChangeTypeLedgerEntryState, State: entry(pre)},
+		{Type: xdr.LedgerEntryChangeTypeLedgerEntryUpdated, Updated: entry(post)},
+	}
+}
+
+func TestTransformBalanceDeltas(t *testing.T) {
+	// testAccount1 pays native through an offer of testAccount3 so that testAccount2 receives USDT
+	transaction := genericLedgerTransaction
+	envelope := genericBumpOperationEnvelope
+	envelope.Tx.SourceAccount = testAccount1
+	envelope.Tx.Operations = []xdr.Operation{{Body: xdr.OperationBody{
+		Type: xdr.OperationTypePathPaymentStrictSend,
+		PathPaymentStrictSendOp: &xdr.PathPaymentStrictSendOp{
+			SendAsset:   nativeAsset,
+			SendAmount:  500,
+			Destination: testAccount2,
+			DestAsset:   usdtAsset,
+			DestMin:     100,
+		},
+	}}}
+	transaction.Envelope.V1 = &envelope
+	transaction.FeeChanges = makeBalanceDeltaTestAccountUpdate(testAccount1ID, 10000, 9800)
+	transaction.PostTxApplyFeeChanges = makeBalanceDeltaTestAccountUpdate(testAccount1ID, 9300, 9350)
+	opChanges := append(makeBalanceDeltaTestAccountUpdate(testAccount1ID, 9800, 9300), makeBalanceDeltaTestAccountUpdate(testAccount3ID, 0, 500)...)
+	opChanges = append(opChanges, makeBalanceDeltaTestTrustlineUpdate(testAccount3ID, usdtAsset, 1000, 800)...)
+	opChanges = append(opChanges, makeBalanceDeltaTestTrustlineUpdate(testAccount2ID, usdtAsset, 0, 200)...)
+	transaction.UnsafeMeta = xdr.TransactionMeta{V: 1, V1: &xdr.TransactionMetaV1{Operations: []xdr.OperationMeta{{Changes: opChanges}}}}
+
+	lhe := xdr.LedgerHeaderHistoryEntry{Header: xdr.LedgerHeader{LedgerSeq: 10, ScpValue: xdr.StellarValue{CloseTime: 1000}}}
+	closedAt, _ := utils.TimePointToUTCTimeStamp(1000)
+	contractId := func(asset xdr.Asset) string {
+		id, _ := asset.ContractID(network.TestNetworkPassphrase)
+		return strkey.MustEncode(strkey.VersionByteContract, id[:])
+	}
+	row := func(address string, asset xdr.Asset, total, fee, transfer, trade string) BalanceDeltaOutput {
+		output := BalanceDeltaOutput{
+			LedgerSequence:        10,
+			ClosedAt:              closedAt,
+			TransactionHash:       utils.HashToHexString(transaction.Result.TransactionHash),
+			TransactionID:         42949677056,
+			Successful:            true,
+			Address:               address,
+			AddressType:           "account",
+			AssetContractId:       contractId(asset),
+			BalanceDelta:          total,
+			FeeDelta:              fee,
+			TransferDelta:         transfer,
+			TradeDelta:            trade,
+			LiquidityPoolDelta:    "0",
+			ClaimableBalanceDelta: "0",
+			ClawbackDelta:         "0",
+			OtherDelta:            "0",
+		}
+		asset.Extract(&output.AssetType, &output.AssetCode, &output.AssetIssuer)
+		return output
+	}
+
+	deltas, err := TransformBalanceDeltas(transaction, lhe, network.TestNetworkPassphrase)
+	assert.NoError(t, err)
+	assert.Equal(t, []BalanceDeltaOutput{
+		row(testAccount1Address, nativeAsset, "-650", "-150", "-500", "0"),
+		row(testAccount3Address, nativeAsset, "500", "0", "0", "500"),
+		row(testAccount2Address, usdtAsset, "200", "0", "200", "0"),
+		row(testAccount3Address, usdtAsset, "-200", "0", "0", "-200"),
+	}, deltas)
+
+	// A failed transaction only charges its fee
+	transaction.Result = utils.CreateSampleResultMeta(false, 1).Result
+	transaction.UnsafeMeta = xdr.TransactionMeta{V: 1, V1: &xdr.TransactionMetaV1{Operations: []xdr.OperationMeta{}}}
+	transaction.PostTxApplyFeeChanges = nil
+	deltas, err = TransformBalanceDeltas(transaction, lhe, network.TestNetworkPassphrase)
+	assert.NoError(t, err)
+	assert.Len(t, deltas, 1)
+	assert.Equal(t, "-200", deltas[0].BalanceDelta)
+	assert.Equal(t, "-200", deltas[0].FeeDelta)
+	assert.False(t, deltas[0].Successful)
+}
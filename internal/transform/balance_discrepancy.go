@@ -0,0 +1,95 @@
+package transform
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+// balanceReconciliation is the sum of the token transfers and of the balance changes of a holder and asset within a
+// transaction
+type balanceReconciliation struct {
+	output    BalanceDiscrepancyOutput
+	transfers *big.Int
+	delta     *big.Int
+}
+
+// ReconcileBalances compares the token transfers of a ledger with the balance deltas of its transactions, and returns
+// the holders and assets of every transaction for which the transfers do not add up to the change of the balance.
+// Transfers from and to liquidity pools and claimable balances are left out, as their balances are not held by an
+// account or contract. The discrepancies are sorted by transaction.
+func ReconcileBalances(transfers []TokenTransferOutput, deltas []BalanceDeltaOutput) ([]BalanceDiscrepancyOutput, error) {
+	reconciliations := []*balanceReconciliation{}
+	byKey := map[string]*balanceReconciliation{}
+	reconciliation := func(transactionID int64, address, contractId string) *balanceReconciliation {
+		key := fmt.Sprintf("%d/%s/%s", transactionID, address, contractId)
+		current, ok := byKey[key]
+		if !ok {
+			current = &balanceReconciliation{
+				output:    BalanceDiscrepancyOutput{TransactionID: transactionID, Address: address, AssetContractId: contractId},
+				transfers: new(big.Int),
+				delta:     new(big.Int),
+			}
+			byKey[key] = current
+			reconciliations = append(reconciliations, current)
+		}
+		return current
+	}
+
+	for _, delta := range deltas {
+		amount, ok := new(big.Int).SetString(delta.BalanceDelta, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid balance delta %s of %s in transaction %s", delta.BalanceDelta, delta.Address, delta.TransactionHash)
+		}
+		current := reconciliation(delta.TransactionID, delta.Address, delta.AssetContractId)
+		current.output.LedgerSequence = delta.LedgerSequence
+		current.output.ClosedAt = delta.ClosedAt
+		current.output.TransactionHash = delta.TransactionHash
+		current.output.AssetType = delta.AssetType
+		current.output.AssetCode = delta.AssetCode
+		current.output.AssetIssuer = delta.AssetIssuer
+		current.delta.Add(current.delta, amount)
+	}
+
+	for _, transfer := range transfers {
+		amount, ok := new(big.Int).SetString(transfer.AmountRaw, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid amount %s of %s event in transaction %s", transfer.AmountRaw, transfer.EventTopic, transfer.TransactionHash)
+		}
+		for _, side := range []struct {
+			address string
+			sign    int64
+		}{{transfer.From.String, -1}, {transfer.To.String, 1}} {
+			if side.address == "" || (side.address[0] != 'G' && side.address[0] != 'C') {
+				continue
+			}
+			current := reconciliation(transfer.TransactionID, side.address, transfer.ContractID)
+			if current.output.TransactionHash == "" {
+				current.output.LedgerSequence = transfer.LedgerSequence
+				current.output.ClosedAt = transfer.ClosedAt
+				current.output.TransactionHash = transfer.TransactionHash
+				current.output.AssetType = transfer.AssetType
+				current.output.AssetCode = transfer.AssetCode.String
+				current.output.AssetIssuer = transfer.AssetIssuer.String
+			}
+			current.output.EventCount++
+			current.transfers.Add(current.transfers, new(big.Int).Mul(amount, big.NewInt(side.sign)))
+		}
+	}
+
+	discrepancies := []BalanceDiscrepancyOutput{}
+	for _, current := range reconciliations {
+		if current.transfers.Cmp(current.delta) == 0 {
+			continue
+		}
+		output := current.output
+		output.TransferAmount = current.transfers.String()
+		output.BalanceDelta = current.delta.String()
+		output.Difference = new(big.Int).Sub(current.delta, current.transfers).String()
+		discrepancies = append(discrepancies, output)
+	}
+	sort.SliceStable(discrepancies, func(i, j int) bool {
+		return discrepancies[i].TransactionID < discrepancies[j].TransactionID
+	})
+	return discrepancies, nil
+}
@@ -0,0 +1,39 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/guregu/null"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReconcileBalances(t *testing.T) {
+	transfers := []TokenTransferOutput{
+		{TransactionHash: "a", TransactionID: 1, EventTopic: "fee", From: null.StringFrom(testAccount1Address), AmountRaw: "100", ContractID: "CNATIVE", AssetType: "native"},
+		{TransactionHash: "a", TransactionID: 1, EventTopic: "transfer", From: null.StringFrom(testAccount1Address), To: null.StringFrom(testAccount2Address), AmountRaw: "500", ContractID: "CNATIVE", AssetType: "native"},
+		// Pools do not hold their reserves in accounts or contracts
+		{TransactionHash: "b", TransactionID: 2, EventTopic: "transfer", From: null.StringFrom(testAccount2Address), To: null.StringFrom("LPOOL"), AmountRaw: "300", ContractID: "CNATIVE", AssetType: "native"},
+	}
+	deltas := []BalanceDeltaOutput{
+		{TransactionHash: "a", TransactionID: 1, Address: testAccount1Address, AssetContractId: "CNATIVE", AssetType: "native", BalanceDelta: "-600"},
+		{TransactionHash: "a", TransactionID: 1, Address: testAccount2Address, AssetContractId: "CNATIVE", AssetType: "native", BalanceDelta: "400"},
+		{TransactionHash: "b", TransactionID: 2, Address: testAccount2Address, AssetContractId: "CNATIVE", AssetType: "native", BalanceDelta: "-300"},
+		{TransactionHash: "b", TransactionID: 2, Address: testAccount3Address, AssetContractId: "CUSDT", BalanceDelta: "7"},
+	}
+
+	discrepancies, err := ReconcileBalances(transfers, deltas)
+	assert.NoError(t, err)
+	assert.Equal(t, []BalanceDiscrepancyOutput{
+		{
+			TransactionHash: "a", TransactionID: 1, Address: testAccount2Address, AssetType: "native", AssetContractId: "CNATIVE",
+			EventCount: 1, TransferAmount: "500", BalanceDelta: "400", Difference: "-100",
+		},
+		{
+			TransactionHash: "b", TransactionID: 2, Address: testAccount3Address, AssetContractId: "CUSDT",
+			TransferAmount: "0", BalanceDelta: "7", Difference: "7",
+		},
+	}, discrepancies)
+
+	_, err = ReconcileBalances([]TokenTransferOutput{{AmountRaw: "1.5"}}, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,296 @@
+package transform
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/guregu/null"
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-etl/v2/internal/toid"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+)
+
+// Predicate times at or after the end of year 9999 cannot be written as timestamps, and are treated as never
+const maxPredicateTime = 253402300799
+
+// claimWindow is a time range, in seconds, during which a claimant can claim a balance. It starts at from and ends
+// before until, which is math.MaxInt64 for windows that never end.
+type claimWindow struct {
+	from, until int64
+}
+
+// predicateWindows returns the windows, from the creation of a balance on, during which predicate holds. Relative
+// predicates are resolved against createdAt, the close time of the ledger that created the balance.
+func predicateWindows(predicate xdr.ClaimPredicate, createdAt int64) ([]claimWindow, error) {
+	before := func(t int64) []claimWindow {
+		if t >= maxPredicateTime {
+			return []claimWindow{{createdAt, math.MaxInt64}}
+		}
+		if t <= createdAt {
+			return []claimWindow{}
+		}
+		return []claimWindow{{createdAt, t}}
+	}
+
+	switch predicate.Type {
+	case xdr.ClaimPredicateTypeClaimPredicateUnconditional:
+		return []claimWindow{{createdAt, math.MaxInt64}}, nil
+	case xdr.ClaimPredicateTypeClaimPredicateBeforeAbsoluteTime:
+		return before(int64(*predicate.AbsBefore)), nil
+	case xdr.ClaimPredicateTypeClaimPredicateBeforeRelativeTime:
+		relative := int64(*predicate.RelBefore)
+		if relative > math.MaxInt64-createdAt {
+			return before(math.MaxInt64), nil
+		}
+		return before(createdAt + relative), nil
+	case xdr.ClaimPredicateTypeClaimPredicateNot:
+		if predicate.NotPredicate == nil || *predicate.NotPredicate == nil {
+			return nil, fmt.Errorf("not predicate without a predicate")
+		}
+		windows, err := predicateWindows(**predicate.NotPredicate, createdAt)
+		if err != nil {
+			return nil, err
+		}
+		complement := []claimWindow{}
+		start := createdAt
+		for _, window := range windows {
+			if window.from > start {
+				complement = append(complement, claimWindow{start, window.from})
+			}
+			start = window.until
+		}
+		if start != math.MaxInt64 {
+			complement = append(complement, claimWindow{start, math.MaxInt64})
+		}
+		return complement, nil
+	case xdr.ClaimPredicateTypeClaimPredicateAnd, xdr.ClaimPredicateTypeClaimPredicateOr:
+		predicates := predicate.AndPredicates
+		if predicate.Type == xdr.ClaimPredicateTypeClaimPredicateOr {
+			predicates = predicate.OrPredicates
+		}
+		if predicates == nil || len(*predicates) != 2 {
+			return nil, fmt.Errorf("%s predicate without two predicates", predicate.Type)
+		}
+		left, err := predicateWindows((*predicates)[0], createdAt)
+		if err != nil {
+			return nil, err
+		}
+		right, err := predicateWindows((*predicates)[1], createdAt)
+		if err != nil {
+			return nil, err
+		}
+		if predicate.Type == xdr.ClaimPredicateTypeClaimPredicateOr {
+			return unionWindows(append(left, right...)), nil
+		}
+		intersection := []claimWindow{}
+		for _, l := range left {
+			for _, r := range right {
+				from, until := max(l.from, r.from), min(l.until, r.until)
+				if from < until {
+					intersection = append(intersection, claimWindow{from, until})
+				}
+			}
+		}
+		return unionWindows(intersection), nil
+	default:
+		return nil, fmt.Errorf("unknown claim predicate type %d", predicate.Type)
+	}
+}
+
+// unionWindows sorts windows and merges the ones that overlap or touch
+func unionWindows(windows []claimWindow) []claimWindow {
+	sort.Slice(windows, func(i, j int) bool { return windows[i].from < windows[j].from })
+	merged := []claimWindow{}
+	for _, window := range windows {
+		if last := len(merged) - 1; last >= 0 && window.from <= merged[last].until {
+			merged[last].until = max(merged[last].until, window.until)
+			continue
+		}
+		merged = append(merged, window)
+	}
+	return merged
+}
+
+// windowTime converts the end of a window to a timestamp, which is null for windows that never end
+func windowTime(seconds int64) null.Time {
+	if seconds == math.MaxInt64 {
+		return null.Time{}
+	}
+	return null.TimeFrom(time.Unix(seconds, 0).UTC())
+}
+
+// transformClaimantWindows returns the windows during which each claimant can claim a balance created at createdAt,
+// along with the time after which no claimant can claim it anymore, which is null if one of them always can
+func transformClaimantWindows(claimants []xdr.Claimant, createdAt int64) ([]ClaimantWindowOutput, null.Time, error) {
+	outputs := []ClaimantWindowOutput{}
+	expiresAt := createdAt
+	for _, claimant := range claimants {
+		v0 := claimant.MustV0()
+		windows, err := predicateWindows(v0.Predicate, createdAt)
+		if err != nil {
+			return nil, null.Time{}, err
+		}
+		output := ClaimantWindowOutput{Destination: v0.Destination.Address(), Windows: []ClaimWindowOutput{}}
+		for _, window := range windows {
+			output.Windows = append(output.Windows, ClaimWindowOutput{From: time.Unix(window.from, 0).UTC(), Until: windowTime(window.until)})
+		}
+		if len(windows) > 0 {
+			output.ClaimableFrom = null.TimeFrom(output.Windows[0].From)
+			output.ClaimableUntil = output.Windows[len(windows)-1].Until
+			expiresAt = max(expiresAt, windows[len(windows)-1].until)
+		}
+		outputs = append(outputs, output)
+	}
+	return outputs, windowTime(expiresAt), nil
+}
+
+// ClaimableBalanceLifecycles holds the last row of the claimable balances seen so far that have not been claimed or
+// clawed back yet, keyed by balance ID. Balances whose last row is their creation can still expire.
+type ClaimableBalanceLifecycles map[string]ClaimableBalanceEventOutput
+
+// AddTransaction returns the creations, claims and clawbacks of claimable balances in a successful transaction, and
+// keeps track of the balances that are still in the ledger. Every row holds the claimants of the balance with the
+// windows during which they can claim it, and the time it expires at. Balances are also created by revoking the authorization of liquidity pool
+// trustlines, in which case the source of the operation is the issuer that revoked it.
+func (l ClaimableBalanceLifecycles) AddTransaction(transaction ingest.LedgerTransaction, lhe xdr.LedgerHeaderHistoryEntry) ([]ClaimableBalanceEventOutput, error) {
+	if !transaction.Result.Successful() {
+		return nil, nil
+	}
+	closedAt, err := utils.TimePointToUTCTimeStamp(lhe.Header.ScpValue.CloseTime)
+	if err != nil {
+		return nil, err
+	}
+	ledgerSequence := uint32(lhe.Header.LedgerSeq)
+	transactionHash := utils.HashToHexString(transaction.Result.TransactionHash)
+
+	outputs := []ClaimableBalanceEventOutput{}
+	for index, operation := range transaction.Envelope.Operations() {
+		changes, err := transaction.GetOperationChanges(uint32(index))
+		if err != nil {
+			return nil, err
+		}
+		for _, change := range changes {
+			if change.Type != xdr.LedgerEntryTypeClaimableBalance || (change.Pre != nil && change.Post != nil) {
+				continue
+			}
+			eventType := "create"
+			entry := change.Post
+			if entry == nil {
+				entry = change.Pre
+				switch operation.Body.Type {
+				case xdr.OperationTypeClaimClaimableBalance:
+					eventType = "claim"
+				case xdr.OperationTypeClawbackClaimableBalance:
+					eventType = "clawback"
+				default:
+					return nil, fmt.Errorf("claimable balance removed by operation %d of type %s", index, operation.Body.Type)
+				}
+			}
+			balance := entry.Data.MustClaimableBalance()
+			balanceID, err := xdr.MarshalHex(balance.BalanceId)
+			if err != nil {
+				return nil, err
+			}
+			asset, err := transformSingleAsset(balance.Asset)
+			if err != nil {
+				return nil, err
+			}
+			// The windows of claimed and clawed back balances start at their creation, which is only known if it was
+			// seen. The ledger holds their predicates as absolute times, so only new balances can hold relative ones.
+			claimants, expiresAt, err := transformClaimantWindows(balance.Claimants, closedAt.Unix())
+			if err != nil {
+				return nil, err
+			}
+			if previous, ok := l[balanceID]; ok && eventType != "create" {
+				claimants, expiresAt = previous.Claimants, previous.ExpiresAt
+			}
+			output := ClaimableBalanceEventOutput{
+				BalanceID:       balanceID,
+				EventType:       eventType,
+				Account:         null.StringFrom(getOperationSourceAccount(operation, transaction).ToAccountId().Address()),
+				AssetCode:       asset.AssetCode,
+				AssetIssuer:     asset.AssetIssuer,
+				AssetType:       asset.AssetType,
+				AssetID:         asset.AssetID,
+				AssetAmount:     utils.ConvertStroopValueToReal(balance.Amount),
+				Sponsor:         ledgerEntrySponsorToNullString(*entry),
+				Claimants:       claimants,
+				ExpiresAt:       expiresAt,
+				TransactionHash: null.StringFrom(transactionHash),
+				OperationID:     null.IntFrom(toid.New(int32(ledgerSequence), int32(transaction.Index), int32(index)+1).ToInt64()),
+				LedgerSequence:  ledgerSequence,
+				ClosedAt:        closedAt,
+			}
+			if eventType == "create" {
+				l[balanceID] = output
+			} else {
+				delete(l, balanceID)
+			}
+			outputs = append(outputs, output)
+		}
+	}
+	return outputs, nil
+}
+
+// Expired returns the expiry rows of the balances that no claimant can claim anymore as of the close of the given
+// ledger, sorted by expiry time and balance ID. Expired balances stay in the ledger until they are clawed back, if
+// ever, but only expire once.
+func (l ClaimableBalanceLifecycles) Expired(lhe xdr.LedgerHeaderHistoryEntry) ([]ClaimableBalanceEventOutput, error) {
+	closedAt, err := utils.TimePointToUTCTimeStamp(lhe.Header.ScpValue.CloseTime)
+	if err != nil {
+		return nil, err
+	}
+	outputs := []ClaimableBalanceEventOutput{}
+	for balanceID, created := range l {
+		if created.EventType != "create" || !created.ExpiresAt.Valid || created.ExpiresAt.Time.After(closedAt) {
+			continue
+		}
+		output := created
+		output.EventType = "expire"
+		output.Account = null.String{}
+		output.TransactionHash = null.String{}
+		output.OperationID = null.Int{}
+		output.LedgerSequence = uint32(lhe.Header.LedgerSeq)
+		output.ClosedAt = closedAt
+		outputs = append(outputs, output)
+		l[balanceID] = output
+	}
+	sort.Slice(outputs, func(i, j int) bool {
+		if !outputs[i].ExpiresAt.Time.Equal(outputs[j].ExpiresAt.Time) {
+			return outputs[i].ExpiresAt.Time.Before(outputs[j].ExpiresAt.Time)
+		}
+		return outputs[i].BalanceID < outputs[j].BalanceID
+	})
+	return outputs, nil
+}
+
+// LoadClaimableBalanceLifecycles reads the JSON lines written by export_claimable_balance_lifecycle, keeping the last
+// row of every balance that was not claimed or clawed back
+func LoadClaimableBalanceLifecycles(in io.Reader) (ClaimableBalanceLifecycles, error) {
+	lifecycles := ClaimableBalanceLifecycles{}
+	err := readJSONLines(in, func(line []byte) error {
+		var event ClaimableBalanceEventOutput
+		if err := json.Unmarshal(line, &event); err != nil {
+			return err
+		}
+		if event.BalanceID == "" {
+			return fmt.Errorf("claimable balance event without a balance_id")
+		}
+		switch event.EventType {
+		case "claim", "clawback":
+			delete(lifecycles, event.BalanceID)
+		default:
+			lifecycles[event.BalanceID] = event
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return lifecycles, nil
+}
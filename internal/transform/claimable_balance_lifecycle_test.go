@@ -0,0 +1,141 @@
+package transform
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/guregu/null"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func beforeAbsolute(t xdr.Int64) xdr.ClaimPredicate {
+	return xdr.ClaimPredicate{Type: xdr.ClaimPredicateTypeClaimPredicateBeforeAbsoluteTime, AbsBefore: &t}
+}
+
+func notPredicate(predicate xdr.ClaimPredicate) xdr.ClaimPredicate {
+	inner := &predicate
+	return xdr.ClaimPredicate{Type: xdr.ClaimPredicateTypeClaimPredicateNot, NotPredicate: &inner}
+}
+
+func TestPredicateWindows(t *testing.T) {
+	relative := xdr.Int64(3000)
+	between := xdr.ClaimPredicate{Type: xdr.ClaimPredicateTypeClaimPredicateAnd, AndPredicates: &[]xdr.ClaimPredicate{
+		notPredicate(beforeAbsolute(2000)),
+		{Type: xdr.ClaimPredicateTypeClaimPredicateBeforeRelativeTime, RelBefore: &relative},
+	}}
+	windows, err := predicateWindows(between, 1000)
+	assert.NoError(t, err)
+	assert.Equal(t, []claimWindow{{2000, 4000}}, windows)
+
+	outside := xdr.ClaimPredicate{Type: xdr.ClaimPredicateTypeClaimPredicateOr, OrPredicates: &[]xdr.ClaimPredicate{
+		beforeAbsolute(1500),
+		notPredicate(beforeAbsolute(3000)),
+	}}
+	windows, err = predicateWindows(outside, 1000)
+	assert.NoError(t, err)
+	assert.Equal(t, []claimWindow{{1000, 1500}, {3000, math.MaxInt64}}, windows)
+
+	windows, err = predicateWindows(beforeAbsolute(500), 1000)
+	assert.NoError(t, err)
+	assert.Empty(t, windows)
+
+	windows, err = predicateWindows(beforeAbsolute(math.MaxInt64), 1000)
+	assert.NoError(t, err)
+	assert.Equal(t, []claimWindow{{1000, math.MaxInt64}}, windows)
+}
+
+func makeClaimableBalanceLifecycleTestEntry() *xdr.LedgerEntry {
+	return &xdr.LedgerEntry{Data: xdr.LedgerEntryData{Type: xdr.LedgerEntryTypeClaimableBalance, ClaimableBalance: &xdr.ClaimableBalanceEntry{
+		BalanceId: xdr.ClaimableBalanceId{Type: xdr.ClaimableBalanceIdTypeClaimableBalanceIdTypeV0, V0: &xdr.Hash{1}},
+		Claimants: []xdr.Claimant{
+			{Type: xdr.ClaimantTypeClaimantTypeV0, V0: &xdr.ClaimantV0{Destination: testAccount2ID, Predicate: beforeAbsolute(3000)}},
+			{Type: xdr.ClaimantTypeClaimantTypeV0, V0: &xdr.ClaimantV0{Destination: testAccount3ID, Predicate: beforeAbsolute(500)}},
+		},
+		Asset:  nativeAsset,
+		Amount: 10000000,
+	}}}
+}
+
+func TestClaimableBalanceLifecycles(t *testing.T) {
+	lifecycles := ClaimableBalanceLifecycles{}
+	create := makeAccountLifecycleTestInput(testAccount1, xdr.Operation{Body: xdr.OperationBody{
+		Type:                     xdr.OperationTypeCreateClaimableBalance,
+		CreateClaimableBalanceOp: &xdr.CreateClaimableBalanceOp{Asset: nativeAsset, Amount: 10000000},
+	}}, xdr.LedgerEntryChanges{{Type: xdr.LedgerEntryChangeTypeLedgerEntryCreated, Created: makeClaimableBalanceLifecycleTestEntry()}})
+	lhe := xdr.LedgerHeaderHistoryEntry{Header: xdr.LedgerHeader{LedgerSeq: 10, ScpValue: xdr.StellarValue{CloseTime: 1000}}}
+	createdAt, _ := utils.TimePointToUTCTimeStamp(1000)
+
+	events, err := lifecycles.AddTransaction(create, lhe)
+	assert.NoError(t, err)
+	assert.Len(t, events, 1)
+	created := events[0]
+	assert.Equal(t, "create", created.EventType)
+	assert.Equal(t, null.StringFrom(testAccount1Address), created.Account)
+	assert.Equal(t, 1.0, created.AssetAmount)
+	assert.Equal(t, null.IntFrom(42949677057), created.OperationID)
+	expiresAt := createdAt.Add(2000 * time.Second)
+	assert.Equal(t, null.TimeFrom(expiresAt), created.ExpiresAt)
+	assert.Equal(t, []ClaimantWindowOutput{
+		{
+			Destination:    testAccount2Address,
+			ClaimableFrom:  null.TimeFrom(createdAt),
+			ClaimableUntil: null.TimeFrom(expiresAt),
+			Windows:        []ClaimWindowOutput{{From: createdAt, Until: null.TimeFrom(expiresAt)}},
+		},
+		{Destination: testAccount3Address, Windows: []ClaimWindowOutput{}},
+	}, created.Claimants)
+
+	expired, err := lifecycles.Expired(lhe)
+	assert.NoError(t, err)
+	assert.Empty(t, expired)
+
+	lhe.Header.LedgerSeq, lhe.Header.ScpValue.CloseTime = 20, 3000
+	expired, err = lifecycles.Expired(lhe)
+	assert.NoError(t, err)
+	assert.Len(t, expired, 1)
+	assert.Equal(t, "expire", expired[0].EventType)
+	assert.Equal(t, uint32(20), expired[0].LedgerSequence)
+	assert.False(t, expired[0].OperationID.Valid)
+	expired, err = lifecycles.Expired(lhe)
+	assert.NoError(t, err)
+	assert.Empty(t, expired)
+
+	// The windows of a clawback are those of the creation
+	clawback := makeAccountLifecycleTestInput(testAccount4, xdr.Operation{Body: xdr.OperationBody{
+		Type:                       xdr.OperationTypeClawbackClaimableBalance,
+		ClawbackClaimableBalanceOp: &xdr.ClawbackClaimableBalanceOp{BalanceId: makeClaimableBalanceLifecycleTestEntry().Data.ClaimableBalance.BalanceId},
+	}}, xdr.LedgerEntryChanges{
+		{Type: xdr.LedgerEntryChangeTypeLedgerEntryState, State: makeClaimableBalanceLifecycleTestEntry()},
+		{Type: xdr.LedgerEntryChangeTypeLedgerEntryRemoved, Removed: &xdr.LedgerKey{
+			Type:             xdr.LedgerEntryTypeClaimableBalance,
+			ClaimableBalance: &xdr.LedgerKeyClaimableBalance{BalanceId: makeClaimableBalanceLifecycleTestEntry().Data.ClaimableBalance.BalanceId},
+		}},
+	})
+	events, err = lifecycles.AddTransaction(clawback, lhe)
+	assert.NoError(t, err)
+	assert.Len(t, events, 1)
+	assert.Equal(t, "clawback", events[0].EventType)
+	assert.Equal(t, null.StringFrom(testAccount4Address), events[0].Account)
+	assert.Equal(t, created.Claimants, events[0].Claimants)
+	assert.Empty(t, lifecycles)
+}
+
+func TestLoadClaimableBalanceLifecycles(t *testing.T) {
+	lifecycles, err := LoadClaimableBalanceLifecycles(strings.NewReader(`{"balance_id":"a","event_type":"create"}
+{"balance_id":"b","event_type":"create"}
+{"balance_id":"b","event_type":"claim"}
+{"balance_id":"c","event_type":"create"}
+{"balance_id":"c","event_type":"expire"}
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, "create", lifecycles["a"].EventType)
+	assert.NotContains(t, lifecycles, "b")
+	assert.Equal(t, "expire", lifecycles["c"].EventType)
+
+	_, err = LoadClaimableBalanceLifecycles(strings.NewReader(`{"event_type":"create"}`))
+	assert.Error(t, err)
+}
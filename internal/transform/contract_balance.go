@@ -0,0 +1,128 @@
+package transform
+
+import (
+	"github.com/guregu/null"
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+)
+
+// TransformContractBalance converts a contract data change into a balance if its entry is keyed by the Balance
+// variant of a DataKey enum and the holder address. The balance of a Stellar Asset Contract is a map with the amount
+// and the authorized and clawback flags, while SEP-41 tokens built on the example token layout only store the amount.
+// The boolean is false for any other contract data.
+func TransformContractBalance(ledgerChange ingest.Change, header xdr.LedgerHeaderHistoryEntry) (ContractBalanceOutput, bool, error) {
+	ledgerEntry, changeType, outputDeleted, err := utils.ExtractEntryFromChange(ledgerChange)
+	if err != nil {
+		return ContractBalanceOutput{}, false, err
+	}
+
+	contractData, ok := ledgerEntry.Data.GetContractData()
+	if !ok {
+		return ContractBalanceOutput{}, false, nil
+	}
+	balance, ok, err := contractBalanceFromData(contractData)
+	if err != nil || !ok {
+		return ContractBalanceOutput{}, ok, err
+	}
+
+	closedAt, err := utils.TimePointToUTCTimeStamp(header.Header.ScpValue.CloseTime)
+	if err != nil {
+		return ContractBalanceOutput{}, false, err
+	}
+
+	balance.LedgerKeyHash = utils.LedgerEntryToLedgerKeyHash(ledgerEntry)
+	balance.LastModifiedLedger = uint32(ledgerEntry.LastModifiedLedgerSeq)
+	balance.LedgerEntryChange = uint32(changeType)
+	balance.Deleted = outputDeleted
+	balance.ClosedAt = closedAt
+	balance.LedgerSequence = uint32(header.Header.LedgerSeq)
+	return balance, true, nil
+}
+
+// contractBalanceFromData returns the token contract, holder, amount and flags of a balance entry, or false if the
+// contract data is not a balance
+func contractBalanceFromData(contractData xdr.ContractDataEntry) (ContractBalanceOutput, bool, error) {
+	contractId, ok := contractData.Contract.GetContractId()
+	if !ok {
+		return ContractBalanceOutput{}, false, nil
+	}
+
+	keyVec, ok := contractData.Key.GetVec()
+	if !ok || keyVec == nil || len(*keyVec) != 2 {
+		return ContractBalanceOutput{}, false, nil
+	}
+	if sym, ok := (*keyVec)[0].GetSym(); !ok || sym != balanceMetadataSym {
+		return ContractBalanceOutput{}, false, nil
+	}
+	holderAddress, ok := (*keyVec)[1].GetAddress()
+	if !ok {
+		return ContractBalanceOutput{}, false, nil
+	}
+	holder, err := holderAddress.String()
+	if err != nil {
+		return ContractBalanceOutput{}, false, err
+	}
+
+	balance := ContractBalanceOutput{
+		ContractId: strkey.MustEncode(strkey.VersionByteContract, contractId[:]),
+		Holder:     holder,
+	}
+	if !addContractBalanceValue(&balance, contractData.Val) {
+		return ContractBalanceOutput{}, false, nil
+	}
+	return balance, true, nil
+}
+
+// addContractBalanceValue sets the amount and flags of balance from a balance value in one of the known layouts
+func addContractBalanceValue(balance *ContractBalanceOutput, val xdr.ScVal) bool {
+	if amount, ok := val.GetI128(); ok {
+		if int64(amount.Hi) < 0 {
+			return false
+		}
+		balance.TokenType = "sep41"
+		balance.Amount = val.String()
+		return true
+	}
+
+	balanceMap, ok := val.GetMap()
+	if !ok || balanceMap == nil || len(*balanceMap) != 3 {
+		return false
+	}
+	var amount xdr.ScVal
+	for _, entry := range *balanceMap {
+		key, ok := entry.Key.GetSym()
+		if !ok {
+			return false
+		}
+		switch key {
+		case "amount":
+			i128, ok := entry.Val.GetI128()
+			if !ok || int64(i128.Hi) < 0 {
+				return false
+			}
+			amount = entry.Val
+		case "authorized":
+			authorized, ok := entry.Val.GetB()
+			if !ok {
+				return false
+			}
+			balance.Authorized = null.BoolFrom(authorized)
+		case "clawback":
+			clawback, ok := entry.Val.GetB()
+			if !ok {
+				return false
+			}
+			balance.Clawback = null.BoolFrom(clawback)
+		default:
+			return false
+		}
+	}
+	if amount.Type != xdr.ScValTypeScvI128 || !balance.Authorized.Valid || !balance.Clawback.Valid {
+		return false
+	}
+	balance.TokenType = "sac"
+	balance.Amount = amount.String()
+	return true
+}
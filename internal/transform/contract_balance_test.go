@@ -0,0 +1,95 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/guregu/null"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+)
+
+func makeContractBalanceTestEntry(contractId xdr.ContractId, key, val xdr.ScVal) *xdr.LedgerEntry {
+	return &xdr.LedgerEntry{
+		LastModifiedLedgerSeq: 5,
+		Data: xdr.LedgerEntryData{
+			Type: xdr.LedgerEntryTypeContractData,
+			ContractData: &xdr.ContractDataEntry{
+				Contract:   xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeContract, ContractId: &contractId},
+				Key:        key,
+				Durability: xdr.ContractDataDurabilityPersistent,
+				Val:        val,
+			},
+		},
+	}
+}
+
+func TestTransformContractBalance(t *testing.T) {
+	holder := "GAOEOQMXDDXPVJC3HDFX6LZFKANJ4OOLQOD2MNXJ7PGAY5FEO4BRRAQU"
+	header := xdr.LedgerHeaderHistoryEntry{Header: xdr.LedgerHeader{LedgerSeq: 10, ScpValue: xdr.StellarValue{CloseTime: 1000}}}
+	closedAt, _ := utils.TimePointToUTCTimeStamp(header.Header.ScpValue.CloseTime)
+	var contractId, holderContractId xdr.ContractId
+	contractId[0], holderContractId[0] = 1, 2
+	contract := strkey.MustEncode(strkey.VersionByteContract, contractId[:])
+	holderContract := strkey.MustEncode(strkey.VersionByteContract, holderContractId[:])
+
+	sacEntry := makeContractBalanceTestEntry(contractId, scVec(scSymbol("Balance"), scAccount(t, holder)), scMap(
+		xdr.ScMapEntry{Key: scSymbol("amount"), Val: scI128(1000)},
+		xdr.ScMapEntry{Key: scSymbol("authorized"), Val: xdr.ScVal{Type: xdr.ScValTypeScvBool, B: new(bool)}},
+		xdr.ScMapEntry{Key: scSymbol("clawback"), Val: xdr.ScVal{Type: xdr.ScValTypeScvBool, B: new(bool)}},
+	))
+	balance, ok, err := TransformContractBalance(ingest.Change{
+		Type:       xdr.LedgerEntryTypeContractData,
+		ChangeType: xdr.LedgerEntryChangeTypeLedgerEntryCreated,
+		Post:       sacEntry,
+	}, header)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, ContractBalanceOutput{
+		ContractId:         contract,
+		TokenType:          "sac",
+		Holder:             holder,
+		Amount:             "1000",
+		Authorized:         null.BoolFrom(false),
+		Clawback:           null.BoolFrom(false),
+		LedgerKeyHash:      utils.LedgerEntryToLedgerKeyHash(*sacEntry),
+		LastModifiedLedger: 5,
+		LedgerEntryChange:  uint32(xdr.LedgerEntryChangeTypeLedgerEntryCreated),
+		ClosedAt:           closedAt,
+		LedgerSequence:     10,
+	}, balance)
+
+	holderAddress := xdr.ScVal{Type: xdr.ScValTypeScvAddress, Address: &xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeContract, ContractId: &holderContractId}}
+	sep41Entry := makeContractBalanceTestEntry(contractId, scVec(scSymbol("Balance"), holderAddress), scI128(25))
+	balance, ok, err = TransformContractBalance(ingest.Change{
+		Type:       xdr.LedgerEntryTypeContractData,
+		ChangeType: xdr.LedgerEntryChangeTypeLedgerEntryRemoved,
+		Pre:        sep41Entry,
+	}, header)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "sep41", balance.TokenType)
+	assert.Equal(t, holderContract, balance.Holder)
+	assert.Equal(t, "25", balance.Amount)
+	assert.False(t, balance.Authorized.Valid)
+	assert.True(t, balance.Deleted)
+
+	for _, entry := range []*xdr.LedgerEntry{
+		makeContractBalanceTestEntry(contractId, scVec(scSymbol("Allowance"), scAccount(t, holder)), scI128(25)),
+		makeContractBalanceTestEntry(contractId, scVec(scSymbol("Balance"), scAccount(t, holder)), scU32(25)),
+		makeContractBalanceTestEntry(contractId, scVec(scSymbol("Balance"), scAccount(t, holder)), scMap(
+			xdr.ScMapEntry{Key: scSymbol("amount"), Val: scI128(1000)},
+		)),
+	} {
+		_, ok, err = TransformContractBalance(ingest.Change{
+			Type:       xdr.LedgerEntryTypeContractData,
+			ChangeType: xdr.LedgerEntryChangeTypeLedgerEntryCreated,
+			Post:       entry,
+		}, header)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	}
+}
@@ -0,0 +1,277 @@
+package transform
+
+import (
+	"fmt"
+
+	"github.com/guregu/null"
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-etl/v2/internal/toid"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+)
+
+// invocationFrame is a (sub)invocation reconstructed from the fn_call and fn_return diagnostic events
+type invocationFrame struct {
+	index       uint32
+	parent      *invocationFrame
+	depth       uint32
+	caller      string
+	contractId  string
+	function    string
+	args        []xdr.ScVal
+	returnValue *xdr.ScVal
+	returned    bool
+}
+
+// TransformContractInvocations converts the host function invoked by a Soroban transaction into one row per
+// (sub)invocation of its call tree. Cross-contract calls are rebuilt from the fn_call/fn_return diagnostic
+// events, so only the top level invocation is returned when the ledger was closed without diagnostic events.
+func TransformContractInvocations(transaction ingest.LedgerTransaction, lhe xdr.LedgerHeaderHistoryEntry) ([]ContractInvocationOutput, error) {
+	operations := transaction.Envelope.Operations()
+	if len(operations) != 1 || operations[0].Body.Type != xdr.OperationTypeInvokeHostFunction {
+		return []ContractInvocationOutput{}, nil
+	}
+	operation := operations[0]
+	hostFunction := operation.Body.MustInvokeHostFunctionOp().HostFunction
+
+	ledgerSequence := uint32(lhe.Header.LedgerSeq)
+	transactionID := toid.New(int32(ledgerSequence), int32(transaction.Index), 0).ToInt64()
+	// operationIndex needs +1 increment to stay in sync with ingest package
+	operationID := toid.New(int32(ledgerSequence), int32(transaction.Index), 1).ToInt64()
+
+	closedAt, err := utils.TimePointToUTCTimeStamp(lhe.Header.ScpValue.CloseTime)
+	if err != nil {
+		return []ContractInvocationOutput{}, fmt.Errorf("for ledger %d; transaction %d (transaction id=%d): %v", ledgerSequence, transaction.Index, transactionID, err)
+	}
+
+	sourceAccount := getOperationSourceAccount(operation, transaction)
+	source, err := utils.GetAccountAddressFromMuxedAccount(sourceAccount)
+	if err != nil {
+		return []ContractInvocationOutput{}, err
+	}
+
+	diagnosticEvents, err := transaction.GetDiagnosticEvents()
+	if err != nil {
+		return []ContractInvocationOutput{}, err
+	}
+	frames := invocationFramesFromEvents(diagnosticEvents, source)
+	fromEvents := len(frames) > 0
+	if !fromEvents {
+		frames = []*invocationFrame{rootInvocationFrame(hostFunction, source)}
+	}
+
+	successful := transaction.Result.Successful()
+	if successful {
+		// The return value of the host function is recorded in the meta even without diagnostic events.
+		// When the top level frame is a constructor called by create_contract_v2 it is the new contract
+		// address rather than what the frame returned.
+		if !fromEvents || hostFunction.Type == xdr.HostFunctionTypeHostFunctionTypeInvokeContract {
+			if returnValue, ok := sorobanReturnValue(transaction.UnsafeMeta); ok {
+				frames[0].returnValue = &returnValue
+			}
+		}
+		frames[0].returned = true
+	}
+
+	resourceFee, _ := transaction.SorobanResourceFee()
+	instructions, _ := transaction.SorobanResourcesInstructions()
+	diskReadBytes, _ := transaction.SorobanResourcesDiskReadBytes()
+	writeBytes, _ := transaction.SorobanResourcesWriteBytes()
+	nonRefundableFee, refundableFee, rentFee := sorobanFeesCharged(transaction.UnsafeMeta)
+
+	transformedInvocations := make([]ContractInvocationOutput, 0, len(frames))
+	for _, frame := range frames {
+		args, argsDecoded, err := serializeScValArray(frame.args)
+		if err != nil {
+			return []ContractInvocationOutput{}, err
+		}
+
+		var returnValue, returnValueDecoded interface{}
+		if frame.returnValue != nil {
+			returnValue, returnValueDecoded, err = serializeScVal(*frame.returnValue)
+			if err != nil {
+				return []ContractInvocationOutput{}, err
+			}
+		}
+
+		var parentIndex null.Int
+		if frame.parent != nil {
+			parentIndex = null.IntFrom(int64(frame.parent.index))
+		}
+
+		transformedInvocations = append(transformedInvocations, ContractInvocationOutput{
+			TransactionHash:                 utils.HashToHexString(transaction.Result.TransactionHash),
+			TransactionID:                   transactionID,
+			OperationID:                     operationID,
+			LedgerSequence:                  ledgerSequence,
+			ClosedAt:                        closedAt,
+			InvocationIndex:                 frame.index,
+			ParentIndex:                     parentIndex,
+			Depth:                           frame.depth,
+			HostFunctionType:                hostFunctionTypeName(hostFunction.Type),
+			Caller:                          frame.caller,
+			ContractId:                      frame.contractId,
+			FunctionName:                    frame.function,
+			Args:                            args,
+			ArgsDecoded:                     argsDecoded,
+			ReturnValue:                     returnValue,
+			ReturnValueDecoded:              returnValueDecoded,
+			Successful:                      frame.returned && successful,
+			TransactionSuccessful:           successful,
+			ResourceFee:                     resourceFee,
+			SorobanResourcesInstructions:    instructions,
+			SorobanResourcesDiskReadBytes:   diskReadBytes,
+			SorobanResourcesWriteBytes:      writeBytes,
+			NonRefundableResourceFeeCharged: nonRefundableFee,
+			RefundableResourceFeeCharged:    refundableFee,
+			RentFeeCharged:                  rentFee,
+		})
+	}
+
+	return transformedInvocations, nil
+}
+
+// invocationFramesFromEvents rebuilds the call tree in the order the calls were made. Each fn_call opens a frame
+// and the matching fn_return closes it; frames closed by the return of an outer frame failed without returning.
+func invocationFramesFromEvents(events []xdr.DiagnosticEvent, source string) []*invocationFrame {
+	var frames, stack []*invocationFrame
+	for _, diagnosticEvent := range events {
+		event := diagnosticEvent.Event
+		if event.Type != xdr.ContractEventTypeDiagnostic {
+			continue
+		}
+		body, ok := event.Body.GetV0()
+		if !ok || len(body.Topics) == 0 {
+			continue
+		}
+		name, ok := body.Topics[0].GetSym()
+		if !ok {
+			continue
+		}
+
+		switch name {
+		case "fn_call":
+			if len(body.Topics) < 3 {
+				continue
+			}
+			frame := &invocationFrame{
+				index:      uint32(len(frames)),
+				caller:     source,
+				contractId: contractIdFromScVal(body.Topics[1]),
+				function:   scValSymbol(body.Topics[2]),
+				args:       []xdr.ScVal{body.Data},
+			}
+			if vec, ok := body.Data.GetVec(); ok && vec != nil {
+				frame.args = *vec
+			}
+			if len(stack) > 0 {
+				frame.parent = stack[len(stack)-1]
+				frame.depth = frame.parent.depth + 1
+				frame.caller = frame.parent.contractId
+			}
+			frames = append(frames, frame)
+			stack = append(stack, frame)
+		case "fn_return":
+			if len(body.Topics) < 2 || event.ContractId == nil {
+				continue
+			}
+			contractId := strkey.MustEncode(strkey.VersionByteContract, event.ContractId[:])
+			function := scValSymbol(body.Topics[1])
+			for i := len(stack) - 1; i >= 0; i-- {
+				if stack[i].contractId == contractId && stack[i].function == function {
+					returnValue := body.Data
+					stack[i].returnValue = &returnValue
+					stack[i].returned = true
+					stack = stack[:i]
+					break
+				}
+			}
+		}
+	}
+	return frames
+}
+
+// rootInvocationFrame describes the host function itself, used when there are no diagnostic events
+func rootInvocationFrame(hostFunction xdr.HostFunction, source string) *invocationFrame {
+	frame := &invocationFrame{caller: source, args: []xdr.ScVal{}}
+	if invokeArgs, ok := hostFunction.GetInvokeContract(); ok {
+		frame.contractId, _ = invokeArgs.ContractAddress.String()
+		frame.function = string(invokeArgs.FunctionName)
+		frame.args = invokeArgs.Args
+	}
+	return frame
+}
+
+func contractIdFromScVal(val xdr.ScVal) string {
+	switch val.Type {
+	case xdr.ScValTypeScvBytes:
+		if len(*val.Bytes) == 32 {
+			return strkey.MustEncode(strkey.VersionByteContract, *val.Bytes)
+		}
+	case xdr.ScValTypeScvAddress:
+		address, _ := val.Address.String()
+		return address
+	}
+	return ""
+}
+
+func scValSymbol(val xdr.ScVal) string {
+	if sym, ok := val.GetSym(); ok {
+		return string(sym)
+	}
+	return ""
+}
+
+func sorobanReturnValue(meta xdr.TransactionMeta) (xdr.ScVal, bool) {
+	switch meta.V {
+	case 3:
+		if meta.V3.SorobanMeta != nil {
+			return meta.V3.SorobanMeta.ReturnValue, true
+		}
+	case 4:
+		if meta.V4.SorobanMeta != nil && meta.V4.SorobanMeta.ReturnValue != nil {
+			return *meta.V4.SorobanMeta.ReturnValue, true
+		}
+	}
+	return xdr.ScVal{}, false
+}
+
+// sorobanFeesCharged returns the non refundable, refundable and rent fees charged, which are only recorded in the
+// soroban meta extension
+func sorobanFeesCharged(meta xdr.TransactionMeta) (int64, int64, int64) {
+	var ext *xdr.SorobanTransactionMetaExt
+	switch meta.V {
+	case 3:
+		if meta.V3.SorobanMeta != nil {
+			ext = &meta.V3.SorobanMeta.Ext
+		}
+	case 4:
+		if meta.V4.SorobanMeta != nil {
+			ext = &meta.V4.SorobanMeta.Ext
+		}
+	}
+	if ext == nil {
+		return 0, 0, 0
+	}
+	extV1, ok := ext.GetV1()
+	if !ok {
+		return 0, 0, 0
+	}
+	return int64(extV1.TotalNonRefundableResourceFeeCharged), int64(extV1.TotalRefundableResourceFeeCharged), int64(extV1.RentFeeCharged)
+}
+
+func hostFunctionTypeName(hostFunctionType xdr.HostFunctionType) string {
+	switch hostFunctionType {
+	case xdr.HostFunctionTypeHostFunctionTypeInvokeContract:
+		return "invoke_contract"
+	case xdr.HostFunctionTypeHostFunctionTypeCreateContract:
+		return "create_contract"
+	case xdr.HostFunctionTypeHostFunctionTypeUploadContractWasm:
+		return "upload_wasm"
+	case xdr.HostFunctionTypeHostFunctionTypeCreateContractV2:
+		return "create_contract_v2"
+	default:
+		return hostFunctionType.String()
+	}
+}
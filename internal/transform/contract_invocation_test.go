@@ -0,0 +1,159 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/guregu/null"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+func makeContractInvocationTestEvent(contractId xdr.ContractId, topics []xdr.ScVal, data xdr.ScVal) xdr.DiagnosticEvent {
+	return xdr.DiagnosticEvent{
+		InSuccessfulContractCall: true,
+		Event: xdr.ContractEvent{
+			ContractId: &contractId,
+			Type:       xdr.ContractEventTypeDiagnostic,
+			Body:       xdr.ContractEventBody{V: 0, V0: &xdr.ContractEventV0{Topics: topics, Data: data}},
+		},
+	}
+}
+
+func scContractBytes(contractId xdr.ContractId) xdr.ScVal {
+	b := xdr.ScBytes(contractId[:])
+	return xdr.ScVal{Type: xdr.ScValTypeScvBytes, Bytes: &b}
+}
+
+func makeContractInvocationTestInput(t *testing.T, diagnosticEvents []xdr.DiagnosticEvent) (ingest.LedgerTransaction, xdr.LedgerHeaderHistoryEntry) {
+	var root xdr.ContractId
+	root[0] = 1
+	source := xdr.MustMuxedAddress("GAOEOQMXDDXPVJC3HDFX6LZFKANJ4OOLQOD2MNXJ7PGAY5FEO4BRRAQU")
+
+	transaction := ingest.LedgerTransaction{
+		Index: 1,
+		Envelope: xdr.TransactionEnvelope{
+			Type: xdr.EnvelopeTypeEnvelopeTypeTx,
+			V1: &xdr.TransactionV1Envelope{
+				Tx: xdr.Transaction{
+					SourceAccount: source,
+					Operations: []xdr.Operation{
+						{
+							Body: xdr.OperationBody{
+								Type: xdr.OperationTypeInvokeHostFunction,
+								InvokeHostFunctionOp: &xdr.InvokeHostFunctionOp{
+									HostFunction: xdr.HostFunction{
+										Type: xdr.HostFunctionTypeHostFunctionTypeInvokeContract,
+										InvokeContract: &xdr.InvokeContractArgs{
+											ContractAddress: xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeContract, ContractId: &root},
+											FunctionName:    "swap",
+											Args:            []xdr.ScVal{scU32(1)},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		Result: xdr.TransactionResultPair{
+			TransactionHash: xdr.Hash{0xaa},
+			Result: xdr.TransactionResult{
+				Result: xdr.TransactionResultResult{
+					Code:    xdr.TransactionResultCodeTxSuccess,
+					Results: &[]xdr.OperationResult{},
+				},
+			},
+		},
+		UnsafeMeta: xdr.TransactionMeta{
+			V: 3,
+			V3: &xdr.TransactionMetaV3{
+				SorobanMeta: &xdr.SorobanTransactionMeta{
+					ReturnValue:      scI128(7),
+					DiagnosticEvents: diagnosticEvents,
+				},
+			},
+		},
+	}
+
+	lhe := xdr.LedgerHeaderHistoryEntry{
+		Header: xdr.LedgerHeader{
+			LedgerSeq: 10,
+			ScpValue:  xdr.StellarValue{CloseTime: 1000},
+		},
+	}
+	return transaction, lhe
+}
+
+func TestTransformContractInvocations(t *testing.T) {
+	var root, token, oracle xdr.ContractId
+	root[0], token[0], oracle[0] = 1, 2, 3
+	rootAddress := strkey.MustEncode(strkey.VersionByteContract, root[:])
+	tokenAddress := strkey.MustEncode(strkey.VersionByteContract, token[:])
+	oracleAddress := strkey.MustEncode(strkey.VersionByteContract, oracle[:])
+	source := "GAOEOQMXDDXPVJC3HDFX6LZFKANJ4OOLQOD2MNXJ7PGAY5FEO4BRRAQU"
+
+	// swap calls transfer on the token, then a price lookup on the oracle that fails and is caught
+	events := []xdr.DiagnosticEvent{
+		makeContractInvocationTestEvent(root, []xdr.ScVal{scSymbol("fn_call"), scContractBytes(root), scSymbol("swap")}, scVec(scU32(1))),
+		makeContractInvocationTestEvent(root, []xdr.ScVal{scSymbol("fn_call"), scContractBytes(token), scSymbol("transfer")}, scVec(scI128(5))),
+		makeContractInvocationTestEvent(token, []xdr.ScVal{scSymbol("fn_return"), scSymbol("transfer")}, xdr.ScVal{Type: xdr.ScValTypeScvVoid}),
+		makeContractInvocationTestEvent(root, []xdr.ScVal{scSymbol("fn_call"), scContractBytes(oracle), scSymbol("price")}, scVec()),
+		makeContractInvocationTestEvent(root, []xdr.ScVal{scSymbol("fn_return"), scSymbol("swap")}, scI128(7)),
+	}
+
+	transaction, lhe := makeContractInvocationTestInput(t, events)
+	output, err := TransformContractInvocations(transaction, lhe)
+	assert.NoError(t, err)
+	assert.Len(t, output, 3)
+
+	type invocation struct {
+		index      uint32
+		parent     null.Int
+		depth      uint32
+		caller     string
+		contractId string
+		function   string
+		args       []interface{}
+		returned   interface{}
+		successful bool
+	}
+	expected := []invocation{
+		{0, null.Int{}, 0, source, rootAddress, "swap", []interface{}{"AAAAAwAAAAE="}, "AAAACgAAAAAAAAAAAAAAAAAAAAc=", true},
+		{1, null.IntFrom(0), 1, rootAddress, tokenAddress, "transfer", []interface{}{"AAAACgAAAAAAAAAAAAAAAAAAAAU="}, "AAAAAQ==", true},
+		{2, null.IntFrom(0), 1, rootAddress, oracleAddress, "price", []interface{}{}, nil, false},
+	}
+	for i, want := range expected {
+		got := output[i]
+		assert.Equal(t, want, invocation{got.InvocationIndex, got.ParentIndex, got.Depth, got.Caller, got.ContractId, got.FunctionName, got.Args, got.ReturnValue, got.Successful})
+		assert.Equal(t, "invoke_contract", got.HostFunctionType)
+		assert.Equal(t, uint32(10), got.LedgerSequence)
+		assert.True(t, got.TransactionSuccessful)
+	}
+
+	// Without diagnostic events only the top level invocation is known
+	transaction, lhe = makeContractInvocationTestInput(t, nil)
+	output, err = TransformContractInvocations(transaction, lhe)
+	assert.NoError(t, err)
+	assert.Len(t, output, 1)
+	assert.Equal(t, invocation{0, null.Int{}, 0, source, rootAddress, "swap", []interface{}{"AAAAAwAAAAE="}, "AAAACgAAAAAAAAAAAAAAAAAAAAc=", true},
+		invocation{output[0].InvocationIndex, output[0].ParentIndex, output[0].Depth, output[0].Caller, output[0].ContractId, output[0].FunctionName, output[0].Args, output[0].ReturnValue, output[0].Successful})
+}
+
+func TestDecodeContractInvocationWithSpec(t *testing.T) {
+	spec := makeContractSpecDecodeTestSpec(t)
+	account := "GAOEOQMXDDXPVJC3HDFX6LZFKANJ4OOLQOD2MNXJ7PGAY5FEO4BRRAQU"
+
+	arg, _, err := serializeScVal(scAccount(t, account))
+	assert.NoError(t, err)
+	returnValue, _, err := serializeScVal(scI128(100))
+	assert.NoError(t, err)
+
+	output := ContractInvocationOutput{FunctionName: "balance", Args: []interface{}{arg}, ReturnValue: returnValue}
+	assert.NoError(t, DecodeContractInvocationWithSpec(&output, spec))
+	assert.Equal(t, []interface{}{account}, output.ArgsDecoded)
+	assert.Equal(t, "100", output.ReturnValueDecoded)
+}
@@ -0,0 +1,227 @@
+package transform
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-etl/v2/internal/toid"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+)
+
+// contractCreation is how a contract created by a transaction was requested, either by its host function or by a
+// create contract node of one of its authorization entries
+type contractCreation struct {
+	preimage        xdr.ContractIdPreimage
+	constructorArgs []xdr.ScVal
+}
+
+// TransformContractLineage converts the contract instances created by a transaction, and the instances whose
+// executable it changed, into lineage rows. The preimage and constructor arguments of a created contract are only
+// known when the contract was created by the host function or an authorized create contract invocation of the
+// transaction. A contract deploying another one with its own address needs no authorization entry, and the meta does
+// not record the preimage, so the rows of such deployments have no preimage details.
+func TransformContractLineage(transaction ingest.LedgerTransaction, lhe xdr.LedgerHeaderHistoryEntry, passphrase string) ([]ContractLineageOutput, error) {
+	ledgerSequence := uint32(lhe.Header.LedgerSeq)
+	transactionID := toid.New(int32(ledgerSequence), int32(transaction.Index), 0).ToInt64()
+
+	closedAt, err := utils.TimePointToUTCTimeStamp(lhe.Header.ScpValue.CloseTime)
+	if err != nil {
+		return []ContractLineageOutput{}, fmt.Errorf("for ledger %d; transaction %d (transaction id=%d): %v", ledgerSequence, transaction.Index, transactionID, err)
+	}
+
+	creations, err := contractCreationsFromTransaction(transaction, passphrase)
+	if err != nil {
+		return []ContractLineageOutput{}, err
+	}
+
+	changes, err := transaction.GetChanges()
+	if err != nil {
+		return []ContractLineageOutput{}, err
+	}
+
+	operations := transaction.Envelope.Operations()
+	var transformedLineage []ContractLineageOutput
+	for _, change := range changes {
+		if change.Type != xdr.LedgerEntryTypeContractData || change.Post == nil {
+			continue
+		}
+		instance, contractId, ok := contractInstanceFromEntry(*change.Post)
+		if !ok {
+			continue
+		}
+
+		output := ContractLineageOutput{
+			ContractId:      contractId,
+			TransactionHash: utils.HashToHexString(transaction.Result.TransactionHash),
+			TransactionID:   transactionID,
+			LedgerSequence:  ledgerSequence,
+			ClosedAt:        closedAt,
+		}
+		output.ExecutableType, output.WasmHash = contractExecutableDetails(instance.Executable)
+
+		switch change.ChangeType {
+		case xdr.LedgerEntryChangeTypeLedgerEntryCreated:
+			output.EventType = "created"
+			if creation, ok := creations[contractId]; ok {
+				if err := addContractCreationDetails(&output, creation); err != nil {
+					return []ContractLineageOutput{}, err
+				}
+			}
+		case xdr.LedgerEntryChangeTypeLedgerEntryUpdated:
+			previous, _, ok := contractInstanceFromEntry(*change.Pre)
+			if !ok || previous.Executable.Equals(instance.Executable) {
+				continue
+			}
+			output.EventType = "upgraded"
+			output.PreviousExecutableType, output.PreviousWasmHash = contractExecutableDetails(previous.Executable)
+		default:
+			continue
+		}
+
+		if change.Reason == ingest.LedgerEntryChangeReasonOperation && int(change.OperationIndex) < len(operations) {
+			// operationIndex needs +1 increment to stay in sync with ingest package
+			output.OperationID = toid.New(int32(ledgerSequence), int32(transaction.Index), int32(change.OperationIndex+1)).ToInt64()
+			sourceAccount := getOperationSourceAccount(operations[change.OperationIndex], transaction)
+			output.SourceAccount, err = utils.GetAccountAddressFromMuxedAccount(sourceAccount)
+			if err != nil {
+				return []ContractLineageOutput{}, err
+			}
+		}
+
+		transformedLineage = append(transformedLineage, output)
+	}
+
+	return transformedLineage, nil
+}
+
+// contractCreationsFromTransaction maps the ID of every contract the host functions and authorization entries of a
+// transaction request to create to how it was requested
+func contractCreationsFromTransaction(transaction ingest.LedgerTransaction, passphrase string) (map[string]contractCreation, error) {
+	creations := map[string]contractCreation{}
+	add := func(creation contractCreation) error {
+		contractId, err := contractIdFromPreimage(creation.preimage, passphrase)
+		if err != nil {
+			return err
+		}
+		creations[contractId] = creation
+		return nil
+	}
+
+	var addInvocation func(invocation xdr.SorobanAuthorizedInvocation) error
+	addInvocation = func(invocation xdr.SorobanAuthorizedInvocation) error {
+		switch invocation.Function.Type {
+		case xdr.SorobanAuthorizedFunctionTypeSorobanAuthorizedFunctionTypeCreateContractHostFn:
+			if err := add(contractCreation{preimage: invocation.Function.CreateContractHostFn.ContractIdPreimage}); err != nil {
+				return err
+			}
+		case xdr.SorobanAuthorizedFunctionTypeSorobanAuthorizedFunctionTypeCreateContractV2HostFn:
+			args := invocation.Function.CreateContractV2HostFn
+			if err := add(contractCreation{preimage: args.ContractIdPreimage, constructorArgs: args.ConstructorArgs}); err != nil {
+				return err
+			}
+		}
+		for _, subInvocation := range invocation.SubInvocations {
+			if err := addInvocation(subInvocation); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, operation := range transaction.Envelope.Operations() {
+		op, ok := operation.Body.GetInvokeHostFunctionOp()
+		if !ok {
+			continue
+		}
+		for _, entry := range op.Auth {
+			if err := addInvocation(entry.RootInvocation); err != nil {
+				return nil, err
+			}
+		}
+		switch op.HostFunction.Type {
+		case xdr.HostFunctionTypeHostFunctionTypeCreateContract:
+			if err := add(contractCreation{preimage: op.HostFunction.CreateContract.ContractIdPreimage}); err != nil {
+				return nil, err
+			}
+		case xdr.HostFunctionTypeHostFunctionTypeCreateContractV2:
+			args := op.HostFunction.CreateContractV2
+			if err := add(contractCreation{preimage: args.ContractIdPreimage, constructorArgs: args.ConstructorArgs}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return creations, nil
+}
+
+func addContractCreationDetails(output *ContractLineageOutput, creation contractCreation) error {
+	switch creation.preimage.Type {
+	case xdr.ContractIdPreimageTypeContractIdPreimageFromAddress:
+		fromAddress := creation.preimage.MustFromAddress()
+		deployer, err := fromAddress.Address.String()
+		if err != nil {
+			return err
+		}
+		output.PreimageType = "address"
+		output.Deployer = deployer
+		output.Salt = utils.HashToHexString(xdr.Hash(fromAddress.Salt))
+	case xdr.ContractIdPreimageTypeContractIdPreimageFromAsset:
+		output.PreimageType = "asset"
+		output.Asset = creation.preimage.MustFromAsset().StringCanonical()
+	}
+
+	if len(creation.constructorArgs) > 0 {
+		var err error
+		output.ConstructorArgs, output.ConstructorArgsDecoded, err = serializeScValArray(creation.constructorArgs)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// contractIdFromPreimage derives the ID of the contract created from preimage on the network
+func contractIdFromPreimage(preimage xdr.ContractIdPreimage, passphrase string) (string, error) {
+	hashIdPreimage := xdr.HashIdPreimage{
+		Type: xdr.EnvelopeTypeEnvelopeTypeContractId,
+		ContractId: &xdr.HashIdPreimageContractId{
+			NetworkId:          xdr.Hash(sha256.Sum256([]byte(passphrase))),
+			ContractIdPreimage: preimage,
+		},
+	}
+	preimageBytes, err := hashIdPreimage.MarshalBinary()
+	if err != nil {
+		return "", err
+	}
+	contractId := sha256.Sum256(preimageBytes)
+	return strkey.Encode(strkey.VersionByteContract, contractId[:])
+}
+
+func contractInstanceFromEntry(ledgerEntry xdr.LedgerEntry) (xdr.ScContractInstance, string, bool) {
+	contractData, ok := ledgerEntry.Data.GetContractData()
+	if !ok || contractData.Key.Type != xdr.ScValTypeScvLedgerKeyContractInstance {
+		return xdr.ScContractInstance{}, "", false
+	}
+	instance, ok := contractData.Val.GetInstance()
+	if !ok {
+		return xdr.ScContractInstance{}, "", false
+	}
+	contractId, ok := contractData.Contract.GetContractId()
+	if !ok {
+		return xdr.ScContractInstance{}, "", false
+	}
+	return instance, strkey.MustEncode(strkey.VersionByteContract, contractId[:]), true
+}
+
+func contractExecutableDetails(executable xdr.ContractExecutable) (string, string) {
+	switch executable.Type {
+	case xdr.ContractExecutableTypeContractExecutableWasm:
+		return "wasm", executable.WasmHash.HexString()
+	case xdr.ContractExecutableTypeContractExecutableStellarAsset:
+		return "stellar_asset", ""
+	default:
+		return executable.Type.String(), ""
+	}
+}
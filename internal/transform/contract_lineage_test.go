@@ -0,0 +1,109 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/stellar/go-stellar-sdk/network"
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+func makeContractInstanceTestEntry(contractId xdr.ContractId, executable xdr.ContractExecutable) xdr.LedgerEntry {
+	return xdr.LedgerEntry{Data: xdr.LedgerEntryData{
+		Type: xdr.LedgerEntryTypeContractData,
+		ContractData: &xdr.ContractDataEntry{
+			Contract:   xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeContract, ContractId: &contractId},
+			Key:        xdr.ScVal{Type: xdr.ScValTypeScvLedgerKeyContractInstance},
+			Durability: xdr.ContractDataDurabilityPersistent,
+			Val:        xdr.ScVal{Type: xdr.ScValTypeScvContractInstance, Instance: &xdr.ScContractInstance{Executable: executable}},
+		},
+	}}
+}
+
+func TestTransformContractLineage(t *testing.T) {
+	deployer := "GAOEOQMXDDXPVJC3HDFX6LZFKANJ4OOLQOD2MNXJ7PGAY5FEO4BRRAQU"
+	deployerAccount := xdr.MustAddress(deployer)
+	var wasmHash, newWasmHash xdr.Hash
+	wasmHash[0], newWasmHash[0] = 1, 2
+	wasm := xdr.ContractExecutable{Type: xdr.ContractExecutableTypeContractExecutableWasm, WasmHash: &wasmHash}
+	newWasm := xdr.ContractExecutable{Type: xdr.ContractExecutableTypeContractExecutableWasm, WasmHash: &newWasmHash}
+
+	preimage := xdr.ContractIdPreimage{
+		Type: xdr.ContractIdPreimageTypeContractIdPreimageFromAddress,
+		FromAddress: &xdr.ContractIdPreimageFromAddress{
+			Address: xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeAccount, AccountId: &deployerAccount},
+			Salt:    xdr.Uint256{7},
+		},
+	}
+	created, err := contractIdFromPreimage(preimage, network.TestNetworkPassphrase)
+	assert.NoError(t, err)
+	createdId := xdr.ContractId(strkey.MustDecode(strkey.VersionByteContract, created))
+
+	// Creating a contract with a constructor
+	transaction, lhe := makeContractInvocationTestInput(t, nil)
+	transaction.Envelope.V1.Tx.Operations[0].Body.InvokeHostFunctionOp.HostFunction = xdr.HostFunction{
+		Type: xdr.HostFunctionTypeHostFunctionTypeCreateContractV2,
+		CreateContractV2: &xdr.CreateContractArgsV2{
+			ContractIdPreimage: preimage,
+			Executable:         wasm,
+			ConstructorArgs:    []xdr.ScVal{scU32(1)},
+		},
+	}
+	createdEntry := makeContractInstanceTestEntry(createdId, wasm)
+	transaction.UnsafeMeta.V3.Operations = []xdr.OperationMeta{
+		{Changes: xdr.LedgerEntryChanges{{Type: xdr.LedgerEntryChangeTypeLedgerEntryCreated, Created: &createdEntry}}},
+	}
+
+	output, err := TransformContractLineage(transaction, lhe, network.TestNetworkPassphrase)
+	assert.NoError(t, err)
+	assert.Len(t, output, 1)
+	assert.Equal(t, created, output[0].ContractId)
+	assert.Equal(t, "created", output[0].EventType)
+	assert.Equal(t, "wasm", output[0].ExecutableType)
+	assert.Equal(t, wasmHash.HexString(), output[0].WasmHash)
+	assert.Equal(t, "address", output[0].PreimageType)
+	assert.Equal(t, deployer, output[0].Deployer)
+	assert.Equal(t, "0700000000000000000000000000000000000000000000000000000000000000", output[0].Salt)
+	assert.Equal(t, []interface{}{"AAAAAwAAAAE="}, output[0].ConstructorArgs)
+	assert.Equal(t, deployer, output[0].SourceAccount)
+	assert.Equal(t, int64(42949677057), output[0].OperationID)
+
+	// Upgrading its WASM; instance storage updates that keep the executable are not lineage events
+	transaction, lhe = makeContractInvocationTestInput(t, nil)
+	upgradedEntry := makeContractInstanceTestEntry(createdId, newWasm)
+	var otherId xdr.ContractId
+	otherId[0] = 9
+	otherBefore := makeContractInstanceTestEntry(otherId, wasm)
+	otherAfter := makeContractInstanceTestEntry(otherId, wasm)
+	otherAfter.LastModifiedLedgerSeq = 10
+	transaction.UnsafeMeta.V3.Operations = []xdr.OperationMeta{
+		{Changes: xdr.LedgerEntryChanges{
+			{Type: xdr.LedgerEntryChangeTypeLedgerEntryState, State: &createdEntry},
+			{Type: xdr.LedgerEntryChangeTypeLedgerEntryUpdated, Updated: &upgradedEntry},
+			{Type: xdr.LedgerEntryChangeTypeLedgerEntryState, State: &otherBefore},
+			{Type: xdr.LedgerEntryChangeTypeLedgerEntryUpdated, Updated: &otherAfter},
+		}},
+	}
+
+	output, err = TransformContractLineage(transaction, lhe, network.TestNetworkPassphrase)
+	assert.NoError(t, err)
+	assert.Len(t, output, 1)
+	assert.Equal(t, created, output[0].ContractId)
+	assert.Equal(t, "upgraded", output[0].EventType)
+	assert.Equal(t, newWasmHash.HexString(), output[0].WasmHash)
+	assert.Equal(t, "wasm", output[0].PreviousExecutableType)
+	assert.Equal(t, wasmHash.HexString(), output[0].PreviousWasmHash)
+	assert.Equal(t, "", output[0].PreimageType)
+}
+
+func TestContractIdFromPreimage(t *testing.T) {
+	asset := xdr.MustNewCreditAsset("USDC", "GAOEOQMXDDXPVJC3HDFX6LZFKANJ4OOLQOD2MNXJ7PGAY5FEO4BRRAQU")
+	expected, err := asset.ContractID(network.PublicNetworkPassphrase)
+	assert.NoError(t, err)
+
+	contractId, err := contractIdFromPreimage(xdr.ContractIdPreimage{Type: xdr.ContractIdPreimageTypeContractIdPreimageFromAsset, FromAsset: &asset}, network.PublicNetworkPassphrase)
+	assert.NoError(t, err)
+	assert.Equal(t, strkey.MustEncode(strkey.VersionByteContract, expected[:]), contractId)
+}
@@ -0,0 +1,344 @@
+package transform
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+)
+
+// Names of the WASM custom sections the soroban sdk embeds in every contract
+const (
+	contractSpecSection    = "contractspecv0"
+	contractMetaSection    = "contractmetav0"
+	contractEnvMetaSection = "contractenvmetav0"
+)
+
+// TransformContractSpec converts a contract code ledger change entry into the interface of the uploaded WASM:
+// the functions, user-defined types, errors and events of its contract spec along with its build metadata
+func TransformContractSpec(ledgerChange ingest.Change, header xdr.LedgerHeaderHistoryEntry) (ContractSpecOutput, error) {
+	ledgerEntry, changeType, outputDeleted, err := utils.ExtractEntryFromChange(ledgerChange)
+	if err != nil {
+		return ContractSpecOutput{}, err
+	}
+
+	contractCode, ok := ledgerEntry.Data.GetContractCode()
+	if !ok {
+		return ContractSpecOutput{}, fmt.Errorf("could not extract contract code from ledger entry; actual type is %s", ledgerEntry.Data.Type)
+	}
+
+	contractCodeHash := contractCode.Hash.HexString()
+
+	sections, err := wasmCustomSections(contractCode.Code)
+	if err != nil {
+		return ContractSpecOutput{}, fmt.Errorf("could not parse wasm of contract code %s: %v", contractCodeHash, err)
+	}
+
+	transformedSpec := ContractSpecOutput{
+		ContractCodeHash:   contractCodeHash,
+		Functions:          []ContractSpecFunction{},
+		Structs:            []ContractSpecStruct{},
+		Unions:             []ContractSpecUnion{},
+		Enums:              []ContractSpecEnum{},
+		ErrorEnums:         []ContractSpecEnum{},
+		Events:             []ContractSpecEvent{},
+		Meta:               map[string]string{},
+		LastModifiedLedger: uint32(ledgerEntry.LastModifiedLedgerSeq),
+		LedgerEntryChange:  uint32(changeType),
+		Deleted:            outputDeleted,
+		LedgerSequence:     uint32(header.Header.LedgerSeq),
+	}
+
+	specSection := bytes.Join(sections[contractSpecSection], nil)
+	entries, err := decodeSpecEntries(specSection)
+	if err != nil {
+		return ContractSpecOutput{}, fmt.Errorf("could not decode %s of contract code %s: %v", contractSpecSection, contractCodeHash, err)
+	}
+	addSpecEntries(&transformedSpec, entries)
+	transformedSpec.SpecXDR = base64.StdEncoding.EncodeToString(specSection)
+
+	for _, section := range sections[contractMetaSection] {
+		if err = addMetaEntries(&transformedSpec, section); err != nil {
+			return ContractSpecOutput{}, fmt.Errorf("could not decode %s of contract code %s: %v", contractMetaSection, contractCodeHash, err)
+		}
+	}
+	transformedSpec.RustcVersion = transformedSpec.Meta["rsver"]
+	transformedSpec.SdkVersion = transformedSpec.Meta["rssdkver"]
+
+	for _, section := range sections[contractEnvMetaSection] {
+		if err = addEnvMetaEntries(&transformedSpec, section); err != nil {
+			return ContractSpecOutput{}, fmt.Errorf("could not decode %s of contract code %s: %v", contractEnvMetaSection, contractCodeHash, err)
+		}
+	}
+
+	transformedSpec.ClosedAt, err = utils.TimePointToUTCTimeStamp(header.Header.ScpValue.CloseTime)
+	if err != nil {
+		return ContractSpecOutput{}, err
+	}
+
+	return transformedSpec, nil
+}
+
+// wasmCustomSections returns the contents of the custom sections of a WASM module, keyed by section name.
+// A name can be repeated, in which case the sections are returned in the order they appear in the module.
+func wasmCustomSections(code []byte) (map[string][][]byte, error) {
+	if len(code) < 8 || !bytes.Equal(code[:4], []byte("\x00asm")) {
+		return nil, fmt.Errorf("missing wasm magic number")
+	}
+	if version := binary.LittleEndian.Uint32(code[4:8]); version != 1 {
+		return nil, fmt.Errorf("unsupported wasm version %d", version)
+	}
+
+	sections := map[string][][]byte{}
+	r := bytes.NewReader(code[8:])
+	for r.Len() > 0 {
+		id, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		size, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, fmt.Errorf("could not read size of section %d: %v", id, err)
+		}
+		if size > uint64(r.Len()) {
+			return nil, fmt.Errorf("section %d is %d bytes but only %d remain", id, size, r.Len())
+		}
+
+		content := make([]byte, size)
+		if _, err = io.ReadFull(r, content); err != nil {
+			return nil, err
+		}
+		// Only custom sections (id 0) carry the contract spec and metadata
+		if id != 0 {
+			continue
+		}
+
+		cr := bytes.NewReader(content)
+		nameLength, err := binary.ReadUvarint(cr)
+		if err != nil || nameLength > uint64(cr.Len()) {
+			return nil, fmt.Errorf("invalid custom section name")
+		}
+		name := make([]byte, nameLength)
+		if _, err = io.ReadFull(cr, name); err != nil {
+			return nil, err
+		}
+		sections[string(name)] = append(sections[string(name)], content[len(content)-cr.Len():])
+	}
+
+	return sections, nil
+}
+
+// decodeXdrStream decodes the concatenated XDR values of a custom section, calling next for each one
+func decodeXdrStream(section []byte, next func(r io.Reader) error) error {
+	r := bytes.NewReader(section)
+	for r.Len() > 0 {
+		if err := next(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeSpecEntries decodes the concatenated spec entries of a contractspecv0 section
+func decodeSpecEntries(section []byte) ([]xdr.ScSpecEntry, error) {
+	entries := []xdr.ScSpecEntry{}
+	err := decodeXdrStream(section, func(r io.Reader) error {
+		var entry xdr.ScSpecEntry
+		if _, err := xdr.Unmarshal(r, &entry); err != nil {
+			return err
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	return entries, err
+}
+
+func addSpecEntries(spec *ContractSpecOutput, entries []xdr.ScSpecEntry) {
+	for _, entry := range entries {
+		switch entry.Kind {
+		case xdr.ScSpecEntryKindScSpecEntryFunctionV0:
+			function := entry.MustFunctionV0()
+			output := ContractSpecFunction{
+				Name:    string(function.Name),
+				Doc:     function.Doc,
+				Inputs:  []ContractSpecField{},
+				Outputs: []string{},
+			}
+			for _, input := range function.Inputs {
+				output.Inputs = append(output.Inputs, ContractSpecField{Name: input.Name, Type: ScSpecTypeName(input.Type), Doc: input.Doc})
+			}
+			for _, out := range function.Outputs {
+				output.Outputs = append(output.Outputs, ScSpecTypeName(out))
+			}
+			spec.Functions = append(spec.Functions, output)
+		case xdr.ScSpecEntryKindScSpecEntryUdtStructV0:
+			udt := entry.MustUdtStructV0()
+			output := ContractSpecStruct{Name: udt.Name, Lib: udt.Lib, Doc: udt.Doc, Fields: []ContractSpecField{}}
+			for _, field := range udt.Fields {
+				output.Fields = append(output.Fields, ContractSpecField{Name: field.Name, Type: ScSpecTypeName(field.Type), Doc: field.Doc})
+			}
+			spec.Structs = append(spec.Structs, output)
+		case xdr.ScSpecEntryKindScSpecEntryUdtUnionV0:
+			udt := entry.MustUdtUnionV0()
+			output := ContractSpecUnion{Name: udt.Name, Lib: udt.Lib, Doc: udt.Doc, Cases: []ContractSpecUnionCase{}}
+			for _, unionCase := range udt.Cases {
+				switch unionCase.Kind {
+				case xdr.ScSpecUdtUnionCaseV0KindScSpecUdtUnionCaseVoidV0:
+					voidCase := unionCase.MustVoidCase()
+					output.Cases = append(output.Cases, ContractSpecUnionCase{Name: voidCase.Name, Doc: voidCase.Doc, Types: []string{}})
+				case xdr.ScSpecUdtUnionCaseV0KindScSpecUdtUnionCaseTupleV0:
+					tupleCase := unionCase.MustTupleCase()
+					types := make([]string, 0, len(tupleCase.Type))
+					for _, t := range tupleCase.Type {
+						types = append(types, ScSpecTypeName(t))
+					}
+					output.Cases = append(output.Cases, ContractSpecUnionCase{Name: tupleCase.Name, Doc: tupleCase.Doc, Types: types})
+				}
+			}
+			spec.Unions = append(spec.Unions, output)
+		case xdr.ScSpecEntryKindScSpecEntryUdtEnumV0:
+			udt := entry.MustUdtEnumV0()
+			output := ContractSpecEnum{Name: udt.Name, Lib: udt.Lib, Doc: udt.Doc, Cases: []ContractSpecEnumCase{}}
+			for _, enumCase := range udt.Cases {
+				output.Cases = append(output.Cases, ContractSpecEnumCase{Name: enumCase.Name, Value: uint32(enumCase.Value), Doc: enumCase.Doc})
+			}
+			spec.Enums = append(spec.Enums, output)
+		case xdr.ScSpecEntryKindScSpecEntryUdtErrorEnumV0:
+			udt := entry.MustUdtErrorEnumV0()
+			output := ContractSpecEnum{Name: udt.Name, Lib: udt.Lib, Doc: udt.Doc, Cases: []ContractSpecEnumCase{}}
+			for _, enumCase := range udt.Cases {
+				output.Cases = append(output.Cases, ContractSpecEnumCase{Name: enumCase.Name, Value: uint32(enumCase.Value), Doc: enumCase.Doc})
+			}
+			spec.ErrorEnums = append(spec.ErrorEnums, output)
+		case xdr.ScSpecEntryKindScSpecEntryEventV0:
+			event := entry.MustEventV0()
+			output := ContractSpecEvent{
+				Name:         string(event.Name),
+				Lib:          event.Lib,
+				Doc:          event.Doc,
+				PrefixTopics: []string{},
+				Params:       []ContractSpecEventParam{},
+				DataFormat:   eventDataFormatName(event.DataFormat),
+			}
+			for _, topic := range event.PrefixTopics {
+				output.PrefixTopics = append(output.PrefixTopics, string(topic))
+			}
+			for _, param := range event.Params {
+				location := "data"
+				if param.Location == xdr.ScSpecEventParamLocationV0ScSpecEventParamLocationTopicList {
+					location = "topic_list"
+				}
+				output.Params = append(output.Params, ContractSpecEventParam{Name: param.Name, Type: ScSpecTypeName(param.Type), Location: location, Doc: param.Doc})
+			}
+			spec.Events = append(spec.Events, output)
+		}
+	}
+}
+
+func addMetaEntries(spec *ContractSpecOutput, section []byte) error {
+	return decodeXdrStream(section, func(r io.Reader) error {
+		var entry xdr.ScMetaEntry
+		if _, err := xdr.Unmarshal(r, &entry); err != nil {
+			return err
+		}
+		if entry.V0 != nil {
+			spec.Meta[entry.V0.Key] = entry.V0.Val
+		}
+		return nil
+	})
+}
+
+func addEnvMetaEntries(spec *ContractSpecOutput, section []byte) error {
+	return decodeXdrStream(section, func(r io.Reader) error {
+		var entry xdr.ScEnvMetaEntry
+		if _, err := xdr.Unmarshal(r, &entry); err != nil {
+			return err
+		}
+		if entry.InterfaceVersion != nil {
+			spec.InterfaceProtocol = uint32(entry.InterfaceVersion.Protocol)
+			spec.InterfacePreRelease = uint32(entry.InterfaceVersion.PreRelease)
+		}
+		return nil
+	})
+}
+
+func eventDataFormatName(format xdr.ScSpecEventDataFormat) string {
+	switch format {
+	case xdr.ScSpecEventDataFormatScSpecEventDataFormatVec:
+		return "vec"
+	case xdr.ScSpecEventDataFormatScSpecEventDataFormatMap:
+		return "map"
+	default:
+		return "single_value"
+	}
+}
+
+// ScSpecTypeName renders a contract spec type the way it is written in a soroban contract, e.g. Vec<Address> or Option<i128>
+func ScSpecTypeName(t xdr.ScSpecTypeDef) string {
+	switch t.Type {
+	case xdr.ScSpecTypeScSpecTypeVal:
+		return "Val"
+	case xdr.ScSpecTypeScSpecTypeBool:
+		return "bool"
+	case xdr.ScSpecTypeScSpecTypeVoid:
+		return "void"
+	case xdr.ScSpecTypeScSpecTypeError:
+		return "Error"
+	case xdr.ScSpecTypeScSpecTypeU32:
+		return "u32"
+	case xdr.ScSpecTypeScSpecTypeI32:
+		return "i32"
+	case xdr.ScSpecTypeScSpecTypeU64:
+		return "u64"
+	case xdr.ScSpecTypeScSpecTypeI64:
+		return "i64"
+	case xdr.ScSpecTypeScSpecTypeTimepoint:
+		return "Timepoint"
+	case xdr.ScSpecTypeScSpecTypeDuration:
+		return "Duration"
+	case xdr.ScSpecTypeScSpecTypeU128:
+		return "u128"
+	case xdr.ScSpecTypeScSpecTypeI128:
+		return "i128"
+	case xdr.ScSpecTypeScSpecTypeU256:
+		return "u256"
+	case xdr.ScSpecTypeScSpecTypeI256:
+		return "i256"
+	case xdr.ScSpecTypeScSpecTypeBytes:
+		return "Bytes"
+	case xdr.ScSpecTypeScSpecTypeString:
+		return "String"
+	case xdr.ScSpecTypeScSpecTypeSymbol:
+		return "Symbol"
+	case xdr.ScSpecTypeScSpecTypeAddress:
+		return "Address"
+	case xdr.ScSpecTypeScSpecTypeMuxedAddress:
+		return "MuxedAddress"
+	case xdr.ScSpecTypeScSpecTypeOption:
+		return fmt.Sprintf("Option<%s>", ScSpecTypeName(t.Option.ValueType))
+	case xdr.ScSpecTypeScSpecTypeResult:
+		return fmt.Sprintf("Result<%s, %s>", ScSpecTypeName(t.Result.OkType), ScSpecTypeName(t.Result.ErrorType))
+	case xdr.ScSpecTypeScSpecTypeVec:
+		return fmt.Sprintf("Vec<%s>", ScSpecTypeName(t.Vec.ElementType))
+	case xdr.ScSpecTypeScSpecTypeMap:
+		return fmt.Sprintf("Map<%s, %s>", ScSpecTypeName(t.Map.KeyType), ScSpecTypeName(t.Map.ValueType))
+	case xdr.ScSpecTypeScSpecTypeTuple:
+		types := make([]string, 0, len(t.Tuple.ValueTypes))
+		for _, valueType := range t.Tuple.ValueTypes {
+			types = append(types, ScSpecTypeName(valueType))
+		}
+		return fmt.Sprintf("(%s)", strings.Join(types, ", "))
+	case xdr.ScSpecTypeScSpecTypeBytesN:
+		return fmt.Sprintf("BytesN<%d>", t.BytesN.N)
+	case xdr.ScSpecTypeScSpecTypeUdt:
+		return t.Udt.Name
+	default:
+		return t.Type.String()
+	}
+}
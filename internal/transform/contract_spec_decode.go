@@ -0,0 +1,661 @@
+package transform
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+// ContractSpec indexes the user-defined types and events of a contract spec by name so values can be decoded with them
+type ContractSpec struct {
+	functions  map[string]xdr.ScSpecFunctionV0
+	structs    map[string]xdr.ScSpecUdtStructV0
+	unions     map[string]xdr.ScSpecUdtUnionV0
+	enums      map[string]xdr.ScSpecUdtEnumV0
+	errorEnums map[string]xdr.ScSpecUdtErrorEnumV0
+	events     []xdr.ScSpecEventV0
+	// Names in the order they are declared, so inference is deterministic when shapes are ambiguous
+	structNames []string
+	unionNames  []string
+}
+
+// NewContractSpec builds a ContractSpec from the entries of a contractspecv0 section
+func NewContractSpec(entries []xdr.ScSpecEntry) *ContractSpec {
+	spec := &ContractSpec{
+		functions:  map[string]xdr.ScSpecFunctionV0{},
+		structs:    map[string]xdr.ScSpecUdtStructV0{},
+		unions:     map[string]xdr.ScSpecUdtUnionV0{},
+		enums:      map[string]xdr.ScSpecUdtEnumV0{},
+		errorEnums: map[string]xdr.ScSpecUdtErrorEnumV0{},
+	}
+	for _, entry := range entries {
+		switch entry.Kind {
+		case xdr.ScSpecEntryKindScSpecEntryFunctionV0:
+			spec.functions[string(entry.FunctionV0.Name)] = *entry.FunctionV0
+		case xdr.ScSpecEntryKindScSpecEntryUdtStructV0:
+			spec.structs[entry.UdtStructV0.Name] = *entry.UdtStructV0
+			spec.structNames = append(spec.structNames, entry.UdtStructV0.Name)
+		case xdr.ScSpecEntryKindScSpecEntryUdtUnionV0:
+			spec.unions[entry.UdtUnionV0.Name] = *entry.UdtUnionV0
+			spec.unionNames = append(spec.unionNames, entry.UdtUnionV0.Name)
+		case xdr.ScSpecEntryKindScSpecEntryUdtEnumV0:
+			spec.enums[entry.UdtEnumV0.Name] = *entry.UdtEnumV0
+		case xdr.ScSpecEntryKindScSpecEntryUdtErrorEnumV0:
+			spec.errorEnums[entry.UdtErrorEnumV0.Name] = *entry.UdtErrorEnumV0
+		case xdr.ScSpecEntryKindScSpecEntryEventV0:
+			spec.events = append(spec.events, *entry.EventV0)
+		}
+	}
+	return spec
+}
+
+// ContractSpecRegistry tracks the spec of every known contract code and the code each contract instance runs
+type ContractSpecRegistry struct {
+	specs     map[string]*ContractSpec
+	instances map[string]string
+}
+
+// NewContractSpecRegistry returns an empty registry
+func NewContractSpecRegistry() *ContractSpecRegistry {
+	return &ContractSpecRegistry{
+		specs:     map[string]*ContractSpec{},
+		instances: map[string]string{},
+	}
+}
+
+// Spec returns the spec of the code run by the contract, or nil if it is unknown.
+// A nil ContractSpec still decodes values, just without the names from the spec.
+func (r *ContractSpecRegistry) Spec(contractId string) *ContractSpec {
+	if r == nil {
+		return nil
+	}
+	return r.specs[r.instances[contractId]]
+}
+
+// AddChange learns the spec of created contract code and the code hash of created or upgraded contract instances
+func (r *ContractSpecRegistry) AddChange(change ingest.Change) error {
+	if change.Post == nil {
+		return nil
+	}
+
+	switch change.Type {
+	case xdr.LedgerEntryTypeContractCode:
+		contractCode := change.Post.Data.MustContractCode()
+		codeHash := contractCode.Hash.HexString()
+		if _, ok := r.specs[codeHash]; ok {
+			return nil
+		}
+		sections, err := wasmCustomSections(contractCode.Code)
+		if err != nil {
+			return fmt.Errorf("could not parse wasm of contract code %s: %v", codeHash, err)
+		}
+		entries, err := decodeSpecEntries(bytes.Join(sections[contractSpecSection], nil))
+		if err != nil {
+			return fmt.Errorf("could not decode %s of contract code %s: %v", contractSpecSection, codeHash, err)
+		}
+		r.specs[codeHash] = NewContractSpec(entries)
+	case xdr.LedgerEntryTypeContractData:
+		r.addInstance(change.Post.Data.MustContractData())
+	}
+	return nil
+}
+
+func (r *ContractSpecRegistry) addInstance(contractData xdr.ContractDataEntry) {
+	if contractData.Key.Type != xdr.ScValTypeScvLedgerKeyContractInstance {
+		return
+	}
+	instance, ok := contractData.Val.GetInstance()
+	if !ok || instance.Executable.WasmHash == nil {
+		return
+	}
+	contractId, ok := contractData.Contract.GetContractId()
+	if !ok {
+		return
+	}
+	r.instances[strkey.MustEncode(strkey.VersionByteContract, contractId[:])] = instance.Executable.WasmHash.HexString()
+}
+
+// LoadSpecs reads the JSON lines written by export_ledger_entry_changes --export-contract-specs
+func (r *ContractSpecRegistry) LoadSpecs(in io.Reader) error {
+	return readJSONLines(in, func(line []byte) error {
+		var row struct {
+			ContractCodeHash string `json:"contract_code_hash"`
+			SpecXDR          string `json:"spec_xdr"`
+		}
+		if err := json.Unmarshal(line, &row); err != nil {
+			return err
+		}
+		section, err := base64.StdEncoding.DecodeString(row.SpecXDR)
+		if err != nil {
+			return fmt.Errorf("invalid spec_xdr of contract code %s: %v", row.ContractCodeHash, err)
+		}
+		entries, err := decodeSpecEntries(section)
+		if err != nil {
+			return fmt.Errorf("could not decode spec of contract code %s: %v", row.ContractCodeHash, err)
+		}
+		r.specs[row.ContractCodeHash] = NewContractSpec(entries)
+		return nil
+	})
+}
+
+// LoadInstances reads the contract instances from the JSON lines written by export_ledger_entry_changes --export-contract-data
+func (r *ContractSpecRegistry) LoadInstances(in io.Reader) error {
+	return readJSONLines(in, func(line []byte) error {
+		var row struct {
+			ContractDataXDR string `json:"contract_data_xdr"`
+		}
+		if err := json.Unmarshal(line, &row); err != nil {
+			return err
+		}
+		var contractData xdr.ContractDataEntry
+		if err := xdr.SafeUnmarshalBase64(row.ContractDataXDR, &contractData); err != nil {
+			return fmt.Errorf("invalid contract_data_xdr: %v", err)
+		}
+		r.addInstance(contractData)
+		return nil
+	})
+}
+
+func readJSONLines(in io.Reader, next func(line []byte) error) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		if err := next(scanner.Bytes()); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// DecodeContractEventWithSpec replaces the decoded topics and data of an event with flat JSON. If the event is
+// declared in the spec its name is set, the topics and data are decoded with the declared parameter types and data
+// in vec or map format is keyed by parameter name. The base64 topics and data are left untouched.
+func DecodeContractEventWithSpec(output *ContractEventOutput, spec *ContractSpec) error {
+	var diagnosticEvent xdr.DiagnosticEvent
+	if err := xdr.SafeUnmarshalBase64(output.ContractEventXDR, &diagnosticEvent); err != nil {
+		return err
+	}
+	body, ok := diagnosticEvent.Event.Body.GetV0()
+	if !ok {
+		return fmt.Errorf("unsupported contract event body version %d", diagnosticEvent.Event.Body.V)
+	}
+
+	if event, ok := spec.matchEvent(body.Topics); ok {
+		output.EventName = string(event.Name)
+		output.TopicsDecoded, output.DataDecoded = spec.decodeEvent(event, body)
+		return nil
+	}
+
+	output.TopicsDecoded = make([]interface{}, 0, len(body.Topics))
+	for _, topic := range body.Topics {
+		output.TopicsDecoded = append(output.TopicsDecoded, spec.DecodeScVal(topic, nil))
+	}
+	output.DataDecoded = spec.DecodeScVal(body.Data, nil)
+	return nil
+}
+
+// DecodeContractDataWithSpec replaces the decoded key and value of a contract data entry with flat JSON.
+// Storage is not typed by the spec, so structs and union variants of the spec are recognized by their shape.
+func DecodeContractDataWithSpec(output *ContractDataOutput, spec *ContractSpec) error {
+	var contractData xdr.ContractDataEntry
+	if err := xdr.SafeUnmarshalBase64(output.ContractDataXDR, &contractData); err != nil {
+		return err
+	}
+	output.KeyDecoded = spec.DecodeScVal(contractData.Key, nil)
+	output.ValDecoded = spec.DecodeScVal(contractData.Val, nil)
+	return nil
+}
+
+// DecodeContractInvocationWithSpec replaces the decoded arguments and return value of an invocation with flat JSON,
+// decoded with the input and output types of the function when the spec declares it
+func DecodeContractInvocationWithSpec(output *ContractInvocationOutput, spec *ContractSpec) error {
+	var function xdr.ScSpecFunctionV0
+	if spec != nil {
+		function = spec.functions[output.FunctionName]
+	}
+
+	argsDecoded := make([]interface{}, 0, len(output.Args))
+	for i, arg := range output.Args {
+		var val xdr.ScVal
+		if err := xdr.SafeUnmarshalBase64(fmt.Sprint(arg), &val); err != nil {
+			return err
+		}
+		var t *xdr.ScSpecTypeDef
+		if len(function.Inputs) == len(output.Args) {
+			t = &function.Inputs[i].Type
+		}
+		argsDecoded = append(argsDecoded, spec.DecodeScVal(val, t))
+	}
+	output.ArgsDecoded = argsDecoded
+
+	if output.ReturnValue != nil {
+		var val xdr.ScVal
+		if err := xdr.SafeUnmarshalBase64(fmt.Sprint(output.ReturnValue), &val); err != nil {
+			return err
+		}
+		var t *xdr.ScSpecTypeDef
+		if len(function.Outputs) == 1 {
+			t = &function.Outputs[0]
+		}
+		output.ReturnValueDecoded = spec.DecodeScVal(val, t)
+	}
+	return nil
+}
+
+// matchEvent finds the event of the spec whose prefix topics and number of topic parameters match topics
+func (s *ContractSpec) matchEvent(topics []xdr.ScVal) (xdr.ScSpecEventV0, bool) {
+	if s == nil {
+		return xdr.ScSpecEventV0{}, false
+	}
+	for _, event := range s.events {
+		topicParams := 0
+		for _, param := range event.Params {
+			if param.Location == xdr.ScSpecEventParamLocationV0ScSpecEventParamLocationTopicList {
+				topicParams++
+			}
+		}
+		if len(event.PrefixTopics)+topicParams != len(topics) {
+			continue
+		}
+		matched := true
+		for i, prefix := range event.PrefixTopics {
+			if sym, ok := topics[i].GetSym(); !ok || sym != prefix {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return event, true
+		}
+	}
+	return xdr.ScSpecEventV0{}, false
+}
+
+func (s *ContractSpec) decodeEvent(event xdr.ScSpecEventV0, body xdr.ContractEventV0) ([]interface{}, interface{}) {
+	var topicParams, dataParams []xdr.ScSpecEventParamV0
+	for _, param := range event.Params {
+		if param.Location == xdr.ScSpecEventParamLocationV0ScSpecEventParamLocationTopicList {
+			topicParams = append(topicParams, param)
+		} else {
+			dataParams = append(dataParams, param)
+		}
+	}
+
+	topics := make([]interface{}, 0, len(body.Topics))
+	for i, topic := range body.Topics {
+		if i < len(event.PrefixTopics) {
+			topics = append(topics, string(event.PrefixTopics[i]))
+			continue
+		}
+		param := topicParams[i-len(event.PrefixTopics)]
+		topics = append(topics, s.DecodeScVal(topic, &param.Type))
+	}
+
+	switch event.DataFormat {
+	case xdr.ScSpecEventDataFormatScSpecEventDataFormatVec:
+		vec, ok := body.Data.GetVec()
+		if !ok || vec == nil || len(*vec) != len(dataParams) {
+			return topics, s.DecodeScVal(body.Data, nil)
+		}
+		data := map[string]interface{}{}
+		for i, param := range dataParams {
+			data[param.Name] = s.DecodeScVal((*vec)[i], &param.Type)
+		}
+		return topics, data
+	case xdr.ScSpecEventDataFormatScSpecEventDataFormatMap:
+		scMap, ok := body.Data.GetMap()
+		if !ok || scMap == nil {
+			return topics, s.DecodeScVal(body.Data, nil)
+		}
+		types := map[string]*xdr.ScSpecTypeDef{}
+		for i := range dataParams {
+			types[dataParams[i].Name] = &dataParams[i].Type
+		}
+		data := map[string]interface{}{}
+		for _, entry := range *scMap {
+			name := scValKeyString(entry.Key)
+			data[name] = s.DecodeScVal(entry.Val, types[name])
+		}
+		return topics, data
+	default:
+		if len(dataParams) == 1 {
+			return topics, s.DecodeScVal(body.Data, &dataParams[0].Type)
+		}
+		return topics, s.DecodeScVal(body.Data, nil)
+	}
+}
+
+// DecodeScVal renders val as flat JSON: numbers wider than 64 bits become decimal strings, bytes become hex,
+// addresses become strkeys and maps keyed by symbols become objects. When t is given the value is decoded as
+// that spec type, naming struct fields and enum variants; otherwise structs and unions of the spec are
+// recognized by their shape.
+func (s *ContractSpec) DecodeScVal(val xdr.ScVal, t *xdr.ScSpecTypeDef) interface{} {
+	if t != nil {
+		if decoded, ok := s.decodeTyped(val, *t); ok {
+			return decoded
+		}
+	}
+
+	switch val.Type {
+	case xdr.ScValTypeScvBool:
+		return *val.B
+	case xdr.ScValTypeScvVoid:
+		return nil
+	case xdr.ScValTypeScvError:
+		return val.String()
+	case xdr.ScValTypeScvU32:
+		return uint32(*val.U32)
+	case xdr.ScValTypeScvI32:
+		return int32(*val.I32)
+	case xdr.ScValTypeScvU64:
+		return uint64(*val.U64)
+	case xdr.ScValTypeScvI64:
+		return int64(*val.I64)
+	case xdr.ScValTypeScvTimepoint:
+		return uint64(*val.Timepoint)
+	case xdr.ScValTypeScvDuration:
+		return uint64(*val.Duration)
+	case xdr.ScValTypeScvU128, xdr.ScValTypeScvI128, xdr.ScValTypeScvU256, xdr.ScValTypeScvI256:
+		return val.String()
+	case xdr.ScValTypeScvBytes:
+		return hex.EncodeToString(*val.Bytes)
+	case xdr.ScValTypeScvString:
+		return string(*val.Str)
+	case xdr.ScValTypeScvSymbol:
+		return string(*val.Sym)
+	case xdr.ScValTypeScvAddress:
+		address, err := val.Address.String()
+		if err != nil {
+			return nil
+		}
+		return address
+	case xdr.ScValTypeScvVec:
+		if *val.Vec == nil {
+			return []interface{}{}
+		}
+		vec := **val.Vec
+		if decoded, ok := s.inferUnion(vec); ok {
+			return decoded
+		}
+		out := make([]interface{}, 0, len(vec))
+		for _, elem := range vec {
+			out = append(out, s.DecodeScVal(elem, nil))
+		}
+		return out
+	case xdr.ScValTypeScvMap:
+		if *val.Map == nil {
+			return map[string]interface{}{}
+		}
+		scMap := **val.Map
+		if decoded, ok := s.inferStruct(scMap); ok {
+			return decoded
+		}
+		return s.decodeMap(scMap, nil, nil)
+	case xdr.ScValTypeScvContractInstance:
+		instance := map[string]interface{}{"executable": "stellar_asset"}
+		if val.Instance.Executable.WasmHash != nil {
+			instance["executable"] = val.Instance.Executable.WasmHash.HexString()
+		}
+		if val.Instance.Storage != nil {
+			instance["storage"] = s.decodeMap(*val.Instance.Storage, nil, nil)
+		}
+		return instance
+	case xdr.ScValTypeScvLedgerKeyContractInstance:
+		return "instance"
+	case xdr.ScValTypeScvLedgerKeyNonce:
+		return map[string]interface{}{"nonce": int64(val.NonceKey.Nonce)}
+	default:
+		return nil
+	}
+}
+
+// decodeMap renders a map as an object when every key is a symbol, string or address and otherwise as a list of key/value pairs
+func (s *ContractSpec) decodeMap(scMap xdr.ScMap, keyType, valType *xdr.ScSpecTypeDef) interface{} {
+	stringKeys := true
+	for _, entry := range scMap {
+		switch entry.Key.Type {
+		case xdr.ScValTypeScvSymbol, xdr.ScValTypeScvString, xdr.ScValTypeScvAddress:
+		default:
+			stringKeys = false
+		}
+	}
+
+	if stringKeys {
+		out := make(map[string]interface{}, len(scMap))
+		for _, entry := range scMap {
+			out[scValKeyString(entry.Key)] = s.DecodeScVal(entry.Val, valType)
+		}
+		return out
+	}
+
+	out := make([]interface{}, 0, len(scMap))
+	for _, entry := range scMap {
+		out = append(out, map[string]interface{}{
+			"key":   s.DecodeScVal(entry.Key, keyType),
+			"value": s.DecodeScVal(entry.Val, valType),
+		})
+	}
+	return out
+}
+
+func scValKeyString(key xdr.ScVal) string {
+	switch key.Type {
+	case xdr.ScValTypeScvSymbol:
+		return string(*key.Sym)
+	case xdr.ScValTypeScvString:
+		return string(*key.Str)
+	case xdr.ScValTypeScvAddress:
+		if address, err := key.Address.String(); err == nil {
+			return address
+		}
+	}
+	return key.String()
+}
+
+// decodeTyped decodes val as the spec type t, returning false if the value does not have the shape of the type
+func (s *ContractSpec) decodeTyped(val xdr.ScVal, t xdr.ScSpecTypeDef) (interface{}, bool) {
+	switch t.Type {
+	case xdr.ScSpecTypeScSpecTypeOption:
+		if val.Type == xdr.ScValTypeScvVoid {
+			return nil, true
+		}
+		return s.DecodeScVal(val, &t.Option.ValueType), true
+	case xdr.ScSpecTypeScSpecTypeVec:
+		vec, ok := val.GetVec()
+		if !ok || vec == nil {
+			return nil, false
+		}
+		out := make([]interface{}, 0, len(*vec))
+		for _, elem := range *vec {
+			out = append(out, s.DecodeScVal(elem, &t.Vec.ElementType))
+		}
+		return out, true
+	case xdr.ScSpecTypeScSpecTypeMap:
+		scMap, ok := val.GetMap()
+		if !ok || scMap == nil {
+			return nil, false
+		}
+		return s.decodeMap(*scMap, &t.Map.KeyType, &t.Map.ValueType), true
+	case xdr.ScSpecTypeScSpecTypeTuple:
+		vec, ok := val.GetVec()
+		if !ok || vec == nil || len(*vec) != len(t.Tuple.ValueTypes) {
+			return nil, false
+		}
+		out := make([]interface{}, 0, len(*vec))
+		for i, elem := range *vec {
+			out = append(out, s.DecodeScVal(elem, &t.Tuple.ValueTypes[i]))
+		}
+		return out, true
+	case xdr.ScSpecTypeScSpecTypeUdt:
+		return s.decodeUdt(val, t.Udt.Name)
+	}
+	return nil, false
+}
+
+func (s *ContractSpec) decodeUdt(val xdr.ScVal, name string) (interface{}, bool) {
+	if s == nil {
+		return nil, false
+	}
+
+	if udt, ok := s.structs[name]; ok {
+		if scMap, ok := val.GetMap(); ok && scMap != nil {
+			return s.decodeStruct(udt, *scMap), true
+		}
+		// Tuple structs have numbered fields and are stored as a vec
+		if vec, ok := val.GetVec(); ok && vec != nil && len(*vec) == len(udt.Fields) {
+			out := make([]interface{}, 0, len(*vec))
+			for i, elem := range *vec {
+				out = append(out, s.DecodeScVal(elem, &udt.Fields[i].Type))
+			}
+			return out, true
+		}
+		return nil, false
+	}
+
+	if udt, ok := s.unions[name]; ok {
+		if vec, ok := val.GetVec(); ok && vec != nil {
+			return s.decodeUnion(udt, *vec)
+		}
+		return nil, false
+	}
+
+	if udt, ok := s.enums[name]; ok {
+		if value, ok := val.GetU32(); ok {
+			for _, enumCase := range udt.Cases {
+				if enumCase.Value == value {
+					return enumCase.Name, true
+				}
+			}
+		}
+		return nil, false
+	}
+
+	if udt, ok := s.errorEnums[name]; ok {
+		if scError, ok := val.GetError(); ok && scError.ContractCode != nil {
+			for _, enumCase := range udt.Cases {
+				if enumCase.Value == *scError.ContractCode {
+					return enumCase.Name, true
+				}
+			}
+		}
+		return nil, false
+	}
+
+	return nil, false
+}
+
+func (s *ContractSpec) decodeStruct(udt xdr.ScSpecUdtStructV0, scMap xdr.ScMap) map[string]interface{} {
+	types := map[string]*xdr.ScSpecTypeDef{}
+	for i := range udt.Fields {
+		types[udt.Fields[i].Name] = &udt.Fields[i].Type
+	}
+	out := make(map[string]interface{}, len(scMap))
+	for _, entry := range scMap {
+		name := scValKeyString(entry.Key)
+		out[name] = s.DecodeScVal(entry.Val, types[name])
+	}
+	return out
+}
+
+// decodeUnion renders a void variant as its name and a tuple variant as an object keyed by its name
+func (s *ContractSpec) decodeUnion(udt xdr.ScSpecUdtUnionV0, vec xdr.ScVec) (interface{}, bool) {
+	if len(vec) == 0 {
+		return nil, false
+	}
+	sym, ok := vec[0].GetSym()
+	if !ok {
+		return nil, false
+	}
+
+	for _, unionCase := range udt.Cases {
+		switch unionCase.Kind {
+		case xdr.ScSpecUdtUnionCaseV0KindScSpecUdtUnionCaseVoidV0:
+			if unionCase.VoidCase.Name == string(sym) && len(vec) == 1 {
+				return string(sym), true
+			}
+		case xdr.ScSpecUdtUnionCaseV0KindScSpecUdtUnionCaseTupleV0:
+			tupleCase := unionCase.TupleCase
+			if tupleCase.Name != string(sym) || len(vec)-1 != len(tupleCase.Type) {
+				continue
+			}
+			if len(tupleCase.Type) == 1 {
+				return map[string]interface{}{tupleCase.Name: s.DecodeScVal(vec[1], &tupleCase.Type[0])}, true
+			}
+			values := make([]interface{}, 0, len(tupleCase.Type))
+			for i, elem := range vec[1:] {
+				values = append(values, s.DecodeScVal(elem, &tupleCase.Type[i]))
+			}
+			return map[string]interface{}{tupleCase.Name: values}, true
+		}
+	}
+	return nil, false
+}
+
+// inferStruct decodes a map as the struct of the spec whose field names are exactly the keys of the map
+func (s *ContractSpec) inferStruct(scMap xdr.ScMap) (interface{}, bool) {
+	if s == nil || len(scMap) == 0 {
+		return nil, false
+	}
+	keys := make([]string, 0, len(scMap))
+	for _, entry := range scMap {
+		sym, ok := entry.Key.GetSym()
+		if !ok {
+			return nil, false
+		}
+		keys = append(keys, string(sym))
+	}
+	sort.Strings(keys)
+
+	for _, name := range s.structNames {
+		udt := s.structs[name]
+		if len(udt.Fields) != len(keys) {
+			continue
+		}
+		fields := make([]string, 0, len(udt.Fields))
+		for _, field := range udt.Fields {
+			fields = append(fields, field.Name)
+		}
+		sort.Strings(fields)
+		if equalStrings(fields, keys) {
+			return s.decodeStruct(udt, scMap), true
+		}
+	}
+	return nil, false
+}
+
+// inferUnion decodes a vec as the union variant of the spec named by its leading symbol
+func (s *ContractSpec) inferUnion(vec xdr.ScVec) (interface{}, bool) {
+	if s == nil {
+		return nil, false
+	}
+	for _, name := range s.unionNames {
+		if decoded, ok := s.decodeUnion(s.unions[name], vec); ok {
+			return decoded, true
+		}
+	}
+	return nil, false
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
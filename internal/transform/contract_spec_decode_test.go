@@ -0,0 +1,158 @@
+package transform
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+func makeContractSpecDecodeTestSpec(t *testing.T) *ContractSpec {
+	entries, err := decodeSpecEntries(contractSpecSectionOf(t, makeContractSpecTestWasm(t)))
+	assert.NoError(t, err)
+	return NewContractSpec(entries)
+}
+
+func contractSpecSectionOf(t *testing.T, code []byte) []byte {
+	sections, err := wasmCustomSections(code)
+	assert.NoError(t, err)
+	return bytes.Join(sections[contractSpecSection], nil)
+}
+
+func scSymbol(s string) xdr.ScVal {
+	sym := xdr.ScSymbol(s)
+	return xdr.ScVal{Type: xdr.ScValTypeScvSymbol, Sym: &sym}
+}
+
+func scI128(lo uint64) xdr.ScVal {
+	return xdr.ScVal{Type: xdr.ScValTypeScvI128, I128: &xdr.Int128Parts{Hi: 0, Lo: xdr.Uint64(lo)}}
+}
+
+func scU32(v uint32) xdr.ScVal {
+	u := xdr.Uint32(v)
+	return xdr.ScVal{Type: xdr.ScValTypeScvU32, U32: &u}
+}
+
+func scVec(vals ...xdr.ScVal) xdr.ScVal {
+	vec := &xdr.ScVec{}
+	*vec = append(*vec, vals...)
+	return xdr.ScVal{Type: xdr.ScValTypeScvVec, Vec: &vec}
+}
+
+func scMap(entries ...xdr.ScMapEntry) xdr.ScVal {
+	m := &xdr.ScMap{}
+	*m = append(*m, entries...)
+	return xdr.ScVal{Type: xdr.ScValTypeScvMap, Map: &m}
+}
+
+func scAccount(t *testing.T, address string) xdr.ScVal {
+	accountId := xdr.MustAddress(address)
+	return xdr.ScVal{Type: xdr.ScValTypeScvAddress, Address: &xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeAccount, AccountId: &accountId}}
+}
+
+func TestContractSpecDecodeScVal(t *testing.T) {
+	spec := makeContractSpecDecodeTestSpec(t)
+	account := "GAOEOQMXDDXPVJC3HDFX6LZFKANJ4OOLQOD2MNXJ7PGAY5FEO4BRRAQU"
+	big := xdr.ScVal{Type: xdr.ScValTypeScvU128, U128: &xdr.UInt128Parts{Hi: 1, Lo: 0}}
+	allowance := scMap(
+		xdr.ScMapEntry{Key: scSymbol("amount"), Val: scI128(100)},
+		xdr.ScMapEntry{Key: scSymbol("expiration_ledger"), Val: scU32(5)},
+	)
+	colorType := xdr.ScSpecTypeDef{Type: xdr.ScSpecTypeScSpecTypeUdt, Udt: &xdr.ScSpecTypeUdt{Name: "Color"}}
+	optionType := xdr.ScSpecTypeDef{Type: xdr.ScSpecTypeScSpecTypeOption, Option: &xdr.ScSpecTypeOption{ValueType: colorType}}
+
+	tests := []struct {
+		name string
+		spec *ContractSpec
+		val  xdr.ScVal
+		t    *xdr.ScSpecTypeDef
+		want interface{}
+	}{
+		{"u128 is a lossless string", nil, big, nil, "18446744073709551616"},
+		{"address is a strkey", nil, scAccount(t, account), nil, account},
+		{"symbol keyed map is an object", nil, allowance, nil, map[string]interface{}{"amount": "100", "expiration_ledger": uint32(5)}},
+		{"other maps are key value pairs", nil, scMap(xdr.ScMapEntry{Key: scU32(1), Val: scSymbol("a")}), nil, []interface{}{map[string]interface{}{"key": uint32(1), "value": "a"}}},
+		{"union variant is inferred", spec, scVec(scSymbol("Balance"), scAccount(t, account)), nil, map[string]interface{}{"Balance": account}},
+		{"void union variant is its name", spec, scVec(scSymbol("Admin")), nil, "Admin"},
+		{"vec without a matching variant", spec, scVec(scSymbol("Unknown")), nil, []interface{}{"Unknown"}},
+		{"enum is named with its type", spec, scU32(1), &colorType, "Blue"},
+		{"option of enum", spec, scU32(0), &optionType, "Red"},
+		{"empty option", spec, xdr.ScVal{Type: xdr.ScValTypeScvVoid}, &optionType, nil},
+		{"unknown enum value falls back to the number", spec, scU32(7), &colorType, uint32(7)},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.want, test.spec.DecodeScVal(test.val, test.t), test.name)
+	}
+}
+
+func TestDecodeContractEventWithSpec(t *testing.T) {
+	spec := makeContractSpecDecodeTestSpec(t)
+	account := "GAOEOQMXDDXPVJC3HDFX6LZFKANJ4OOLQOD2MNXJ7PGAY5FEO4BRRAQU"
+
+	makeOutput := func(topics []xdr.ScVal, data xdr.ScVal) ContractEventOutput {
+		eventXDR, err := xdr.MarshalBase64(xdr.DiagnosticEvent{
+			Event: xdr.ContractEvent{
+				Type: xdr.ContractEventTypeContract,
+				Body: xdr.ContractEventBody{V: 0, V0: &xdr.ContractEventV0{Topics: topics, Data: data}},
+			},
+		})
+		assert.NoError(t, err)
+		return ContractEventOutput{Data: "raw", ContractEventXDR: eventXDR}
+	}
+
+	output := makeOutput([]xdr.ScVal{scSymbol("transfer"), scAccount(t, account)}, scI128(100))
+	assert.NoError(t, DecodeContractEventWithSpec(&output, spec))
+	assert.Equal(t, "Transfer", output.EventName)
+	assert.Equal(t, []interface{}{"transfer", account}, output.TopicsDecoded)
+	assert.Equal(t, "100", output.DataDecoded)
+	assert.Equal(t, "raw", output.Data)
+
+	output = makeOutput([]xdr.ScVal{scSymbol("mint")}, scVec(scI128(1), scU32(2)))
+	assert.NoError(t, DecodeContractEventWithSpec(&output, spec))
+	assert.Equal(t, "", output.EventName)
+	assert.Equal(t, []interface{}{"mint"}, output.TopicsDecoded)
+	assert.Equal(t, []interface{}{"1", uint32(2)}, output.DataDecoded)
+}
+
+func TestContractSpecRegistry(t *testing.T) {
+	code := makeContractSpecTestWasm(t)
+	var codeHash, contractHash xdr.Hash
+	codeHash[0], contractHash[0] = 1, 2
+	contractId := xdr.ContractId(contractHash)
+	contract := strkey.MustEncode(strkey.VersionByteContract, contractHash[:])
+
+	instance := xdr.ContractDataEntry{
+		Contract:   xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeContract, ContractId: &contractId},
+		Key:        xdr.ScVal{Type: xdr.ScValTypeScvLedgerKeyContractInstance},
+		Durability: xdr.ContractDataDurabilityPersistent,
+		Val: xdr.ScVal{Type: xdr.ScValTypeScvContractInstance, Instance: &xdr.ScContractInstance{
+			Executable: xdr.ContractExecutable{Type: xdr.ContractExecutableTypeContractExecutableWasm, WasmHash: &codeHash},
+		}},
+	}
+
+	learned := NewContractSpecRegistry()
+	assert.Nil(t, learned.Spec(contract))
+	assert.NoError(t, learned.AddChange(ingest.Change{
+		Type: xdr.LedgerEntryTypeContractCode,
+		Post: &xdr.LedgerEntry{Data: xdr.LedgerEntryData{Type: xdr.LedgerEntryTypeContractCode, ContractCode: &xdr.ContractCodeEntry{Hash: codeHash, Code: code}}},
+	}))
+	assert.NoError(t, learned.AddChange(ingest.Change{
+		Type: xdr.LedgerEntryTypeContractData,
+		Post: &xdr.LedgerEntry{Data: xdr.LedgerEntryData{Type: xdr.LedgerEntryTypeContractData, ContractData: &instance}},
+	}))
+	assert.NotNil(t, learned.Spec(contract))
+
+	instanceXDR, err := xdr.MarshalBase64(instance)
+	assert.NoError(t, err)
+	loaded := NewContractSpecRegistry()
+	assert.NoError(t, loaded.LoadSpecs(strings.NewReader(`{"contract_code_hash":"`+codeHash.HexString()+`","spec_xdr":"`+base64.StdEncoding.EncodeToString(contractSpecSectionOf(t, code))+`"}`+"\n")))
+	assert.NoError(t, loaded.LoadInstances(strings.NewReader(`{"contract_id":"`+contract+`","contract_data_xdr":"`+instanceXDR+`"}`+"\n")))
+	assert.Equal(t, learned.Spec(contract), loaded.Spec(contract))
+}
@@ -0,0 +1,265 @@
+package transform
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+func TestTransformContractSpec(t *testing.T) {
+	type transformTest struct {
+		input      ingest.Change
+		wantOutput ContractSpecOutput
+		wantErr    error
+	}
+
+	wasm := makeContractSpecTestWasm(t)
+	wantSpec := makeContractSpecTestOutput()
+	wantSpec.SpecXDR = base64.StdEncoding.EncodeToString(contractSpecSectionOf(t, wasm))
+
+	tests := []transformTest{
+		{
+			ingest.Change{
+				ChangeType: xdr.LedgerEntryChangeTypeLedgerEntryCreated,
+				Type:       xdr.LedgerEntryTypeOffer,
+				Pre:        nil,
+				Post: &xdr.LedgerEntry{
+					Data: xdr.LedgerEntryData{
+						Type: xdr.LedgerEntryTypeOffer,
+					},
+				},
+			},
+			ContractSpecOutput{}, fmt.Errorf("could not extract contract code from ledger entry; actual type is LedgerEntryTypeOffer"),
+		},
+		{
+			makeContractSpecTestInput([]byte("not wasm")),
+			ContractSpecOutput{}, fmt.Errorf("could not parse wasm of contract code 0000000000000000000000000000000000000000000000000000000000000000: missing wasm magic number"),
+		},
+		{
+			makeContractSpecTestInput(wasm),
+			wantSpec,
+			nil,
+		},
+	}
+
+	for _, test := range tests {
+		header := xdr.LedgerHeaderHistoryEntry{
+			Header: xdr.LedgerHeader{
+				ScpValue: xdr.StellarValue{
+					CloseTime: 1000,
+				},
+				LedgerSeq: 10,
+			},
+		}
+		actualOutput, actualError := TransformContractSpec(test.input, header)
+		assert.Equal(t, test.wantErr, actualError)
+		assert.Equal(t, test.wantOutput, actualOutput)
+	}
+}
+
+func TestScSpecTypeName(t *testing.T) {
+	address := xdr.ScSpecTypeDef{Type: xdr.ScSpecTypeScSpecTypeAddress}
+	i128 := xdr.ScSpecTypeDef{Type: xdr.ScSpecTypeScSpecTypeI128}
+	udt := xdr.ScSpecTypeDef{Type: xdr.ScSpecTypeScSpecTypeUdt, Udt: &xdr.ScSpecTypeUdt{Name: "DataKey"}}
+
+	tests := []struct {
+		input xdr.ScSpecTypeDef
+		want  string
+	}{
+		{i128, "i128"},
+		{udt, "DataKey"},
+		{xdr.ScSpecTypeDef{Type: xdr.ScSpecTypeScSpecTypeOption, Option: &xdr.ScSpecTypeOption{ValueType: address}}, "Option<Address>"},
+		{xdr.ScSpecTypeDef{Type: xdr.ScSpecTypeScSpecTypeResult, Result: &xdr.ScSpecTypeResult{OkType: i128, ErrorType: xdr.ScSpecTypeDef{Type: xdr.ScSpecTypeScSpecTypeError}}}, "Result<i128, Error>"},
+		{xdr.ScSpecTypeDef{Type: xdr.ScSpecTypeScSpecTypeVec, Vec: &xdr.ScSpecTypeVec{ElementType: address}}, "Vec<Address>"},
+		{xdr.ScSpecTypeDef{Type: xdr.ScSpecTypeScSpecTypeMap, Map: &xdr.ScSpecTypeMap{KeyType: udt, ValueType: i128}}, "Map<DataKey, i128>"},
+		{xdr.ScSpecTypeDef{Type: xdr.ScSpecTypeScSpecTypeTuple, Tuple: &xdr.ScSpecTypeTuple{ValueTypes: []xdr.ScSpecTypeDef{address, i128}}}, "(Address, i128)"},
+		{xdr.ScSpecTypeDef{Type: xdr.ScSpecTypeScSpecTypeBytesN, BytesN: &xdr.ScSpecTypeBytesN{N: 32}}, "BytesN<32>"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.want, ScSpecTypeName(test.input))
+	}
+}
+
+func makeContractSpecTestInput(code []byte) ingest.Change {
+	var hash [32]byte
+
+	return ingest.Change{
+		ChangeType: xdr.LedgerEntryChangeTypeLedgerEntryCreated,
+		Type:       xdr.LedgerEntryTypeContractCode,
+		Pre:        nil,
+		Post: &xdr.LedgerEntry{
+			LastModifiedLedgerSeq: 24229503,
+			Data: xdr.LedgerEntryData{
+				Type: xdr.LedgerEntryTypeContractCode,
+				ContractCode: &xdr.ContractCodeEntry{
+					Hash: hash,
+					Code: code,
+				},
+			},
+		},
+	}
+}
+
+// makeContractSpecTestWasm builds a module with a code section and the three custom sections written by the soroban sdk
+func makeContractSpecTestWasm(t *testing.T) []byte {
+	address := xdr.ScSpecTypeDef{Type: xdr.ScSpecTypeScSpecTypeAddress}
+	i128 := xdr.ScSpecTypeDef{Type: xdr.ScSpecTypeScSpecTypeI128}
+
+	var spec bytes.Buffer
+	for _, entry := range []xdr.ScSpecEntry{
+		{
+			Kind: xdr.ScSpecEntryKindScSpecEntryFunctionV0,
+			FunctionV0: &xdr.ScSpecFunctionV0{
+				Doc:  "Returns the balance of id",
+				Name: "balance",
+				Inputs: []xdr.ScSpecFunctionInputV0{
+					{Name: "id", Type: address},
+				},
+				Outputs: []xdr.ScSpecTypeDef{i128},
+			},
+		},
+		{
+			Kind: xdr.ScSpecEntryKindScSpecEntryUdtStructV0,
+			UdtStructV0: &xdr.ScSpecUdtStructV0{
+				Name: "AllowanceValue",
+				Fields: []xdr.ScSpecUdtStructFieldV0{
+					{Name: "amount", Type: i128},
+					{Name: "expiration_ledger", Type: xdr.ScSpecTypeDef{Type: xdr.ScSpecTypeScSpecTypeU32}},
+				},
+			},
+		},
+		{
+			Kind: xdr.ScSpecEntryKindScSpecEntryUdtUnionV0,
+			UdtUnionV0: &xdr.ScSpecUdtUnionV0{
+				Name: "DataKey",
+				Cases: []xdr.ScSpecUdtUnionCaseV0{
+					{Kind: xdr.ScSpecUdtUnionCaseV0KindScSpecUdtUnionCaseVoidV0, VoidCase: &xdr.ScSpecUdtUnionCaseVoidV0{Name: "Admin"}},
+					{Kind: xdr.ScSpecUdtUnionCaseV0KindScSpecUdtUnionCaseTupleV0, TupleCase: &xdr.ScSpecUdtUnionCaseTupleV0{Name: "Balance", Type: []xdr.ScSpecTypeDef{address}}},
+				},
+			},
+		},
+		{
+			Kind: xdr.ScSpecEntryKindScSpecEntryUdtEnumV0,
+			UdtEnumV0: &xdr.ScSpecUdtEnumV0{
+				Name:  "Color",
+				Cases: []xdr.ScSpecUdtEnumCaseV0{{Name: "Red", Value: 0}, {Name: "Blue", Value: 1}},
+			},
+		},
+		{
+			Kind: xdr.ScSpecEntryKindScSpecEntryUdtErrorEnumV0,
+			UdtErrorEnumV0: &xdr.ScSpecUdtErrorEnumV0{
+				Name:  "Error",
+				Cases: []xdr.ScSpecUdtErrorEnumCaseV0{{Name: "InsufficientBalance", Value: 10}},
+			},
+		},
+		{
+			Kind: xdr.ScSpecEntryKindScSpecEntryEventV0,
+			EventV0: &xdr.ScSpecEventV0{
+				Name:         "Transfer",
+				PrefixTopics: []xdr.ScSymbol{"transfer"},
+				Params: []xdr.ScSpecEventParamV0{
+					{Name: "from", Type: address, Location: xdr.ScSpecEventParamLocationV0ScSpecEventParamLocationTopicList},
+					{Name: "amount", Type: i128, Location: xdr.ScSpecEventParamLocationV0ScSpecEventParamLocationData},
+				},
+				DataFormat: xdr.ScSpecEventDataFormatScSpecEventDataFormatSingleValue,
+			},
+		},
+	} {
+		_, err := xdr.Marshal(&spec, entry)
+		assert.NoError(t, err)
+	}
+
+	var meta bytes.Buffer
+	_, err := xdr.Marshal(&meta, xdr.ScMetaEntry{Kind: xdr.ScMetaKindScMetaV0, V0: &xdr.ScMetaV0{Key: "rsver", Val: "1.81.0"}})
+	assert.NoError(t, err)
+	_, err = xdr.Marshal(&meta, xdr.ScMetaEntry{Kind: xdr.ScMetaKindScMetaV0, V0: &xdr.ScMetaV0{Key: "rssdkver", Val: "22.0.0"}})
+	assert.NoError(t, err)
+
+	var envMeta bytes.Buffer
+	_, err = xdr.Marshal(&envMeta, xdr.ScEnvMetaEntry{
+		Kind:             xdr.ScEnvMetaKindScEnvMetaKindInterfaceVersion,
+		InterfaceVersion: &xdr.ScEnvMetaEntryInterfaceVersion{Protocol: 22, PreRelease: 0},
+	})
+	assert.NoError(t, err)
+
+	module := []byte("\x00asm\x01\x00\x00\x00")
+	module = appendWasmSection(module, 10, []byte{0x00})
+	module = appendWasmSection(module, 0, customSectionContent(contractEnvMetaSection, envMeta.Bytes()))
+	module = appendWasmSection(module, 0, customSectionContent(contractMetaSection, meta.Bytes()))
+	module = appendWasmSection(module, 0, customSectionContent(contractSpecSection, spec.Bytes()))
+	return module
+}
+
+func customSectionContent(name string, content []byte) []byte {
+	out := binary.AppendUvarint(nil, uint64(len(name)))
+	out = append(out, name...)
+	return append(out, content...)
+}
+
+func appendWasmSection(module []byte, id byte, content []byte) []byte {
+	module = append(module, id)
+	module = binary.AppendUvarint(module, uint64(len(content)))
+	return append(module, content...)
+}
+
+func makeContractSpecTestOutput() ContractSpecOutput {
+	return ContractSpecOutput{
+		ContractCodeHash: "0000000000000000000000000000000000000000000000000000000000000000",
+		Functions: []ContractSpecFunction{
+			{
+				Name:    "balance",
+				Doc:     "Returns the balance of id",
+				Inputs:  []ContractSpecField{{Name: "id", Type: "Address"}},
+				Outputs: []string{"i128"},
+			},
+		},
+		Structs: []ContractSpecStruct{
+			{
+				Name:   "AllowanceValue",
+				Fields: []ContractSpecField{{Name: "amount", Type: "i128"}, {Name: "expiration_ledger", Type: "u32"}},
+			},
+		},
+		Unions: []ContractSpecUnion{
+			{
+				Name:  "DataKey",
+				Cases: []ContractSpecUnionCase{{Name: "Admin", Types: []string{}}, {Name: "Balance", Types: []string{"Address"}}},
+			},
+		},
+		Enums: []ContractSpecEnum{
+			{Name: "Color", Cases: []ContractSpecEnumCase{{Name: "Red", Value: 0}, {Name: "Blue", Value: 1}}},
+		},
+		ErrorEnums: []ContractSpecEnum{
+			{Name: "Error", Cases: []ContractSpecEnumCase{{Name: "InsufficientBalance", Value: 10}}},
+		},
+		Events: []ContractSpecEvent{
+			{
+				Name:         "Transfer",
+				PrefixTopics: []string{"transfer"},
+				Params: []ContractSpecEventParam{
+					{Name: "from", Type: "Address", Location: "topic_list"},
+					{Name: "amount", Type: "i128", Location: "data"},
+				},
+				DataFormat: "single_value",
+			},
+		},
+		Meta:                map[string]string{"rsver": "1.81.0", "rssdkver": "22.0.0"},
+		RustcVersion:        "1.81.0",
+		SdkVersion:          "22.0.0",
+		InterfaceProtocol:   22,
+		InterfacePreRelease: 0,
+		LastModifiedLedger:  24229503,
+		LedgerEntryChange:   0,
+		Deleted:             false,
+		ClosedAt:            time.Date(1970, time.January, 1, 0, 16, 40, 0, time.UTC),
+		LedgerSequence:      10,
+	}
+}
@@ -0,0 +1,230 @@
+package transform
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+)
+
+// poolWindow is the activity of a liquidity pool within a time window. Amounts are kept in stroops.
+type poolWindow struct {
+	output     LiquidityPoolMetricOutput
+	end        time.Time
+	assetA     xdr.Asset
+	fee        int64
+	reserveA   int64
+	reserveB   int64
+	volumeA    int64
+	volumeB    int64
+	feesA      *big.Int
+	feesB      *big.Int
+	depositedA int64
+	depositedB int64
+	withdrawnA int64
+	withdrawnB int64
+}
+
+// PoolMetricsAggregator builds per pool metrics over time windows of a single resolution from the pool state changes,
+// trades, deposits and withdrawals of the transactions it is given
+type PoolMetricsAggregator struct {
+	resolution string
+	length     int64
+	windows    map[string]*poolWindow
+}
+
+// NewPoolMetricsAggregator returns an aggregator for windows of the given resolution, which must be 1m, 5m, 15m, 1h,
+// 1d or 1w
+func NewPoolMetricsAggregator(resolution string) (*PoolMetricsAggregator, error) {
+	length, ok := tradeAggregationResolutions[resolution]
+	if !ok {
+		return nil, fmt.Errorf("unsupported resolution %s", resolution)
+	}
+	return &PoolMetricsAggregator{resolution: resolution, length: length, windows: map[string]*poolWindow{}}, nil
+}
+
+// AddTransaction adds the liquidity pool activity of a successful transaction to the windows of the pools it touched
+func (a *PoolMetricsAggregator) AddTransaction(transaction ingest.LedgerTransaction, lhe xdr.LedgerHeaderHistoryEntry) error {
+	if !transaction.Result.Successful() {
+		return nil
+	}
+	closedAt, err := utils.TimePointToUTCTimeStamp(lhe.Header.ScpValue.CloseTime)
+	if err != nil {
+		return err
+	}
+	ledgerSequence := uint32(lhe.Header.LedgerSeq)
+	start := closedAt.UnixMilli() / a.length * a.length
+	operationResults, _ := transaction.Result.OperationResults()
+
+	for index, operation := range transaction.Envelope.Operations() {
+		changes, err := transaction.GetOperationChanges(uint32(index))
+		if err != nil {
+			return err
+		}
+		for _, change := range changes {
+			if change.Type != xdr.LedgerEntryTypeLiquidityPool || change.Post == nil {
+				continue
+			}
+			pool, err := TransformPool(change, lhe)
+			if err != nil {
+				return err
+			}
+			window := a.window(pool, start)
+			window.output.LastLedger = ledgerSequence
+			post := change.Post.Data.MustLiquidityPool().Body.MustConstantProduct()
+			window.assetA = post.Params.AssetA
+			window.fee = int64(post.Params.Fee)
+			window.reserveA, window.reserveB = int64(post.ReserveA), int64(post.ReserveB)
+			if change.Pre == nil {
+				continue
+			}
+			pre := change.Pre.Data.MustLiquidityPool().Body.MustConstantProduct()
+			switch operation.Body.Type {
+			case xdr.OperationTypeLiquidityPoolDeposit:
+				window.output.DepositCount++
+				window.depositedA += int64(post.ReserveA - pre.ReserveA)
+				window.depositedB += int64(post.ReserveB - pre.ReserveB)
+			case xdr.OperationTypeLiquidityPoolWithdraw:
+				window.output.WithdrawCount++
+				window.withdrawnA += int64(pre.ReserveA - post.ReserveA)
+				window.withdrawnB += int64(pre.ReserveB - post.ReserveB)
+			}
+		}
+
+		switch operation.Body.Type {
+		case xdr.OperationTypeManageBuyOffer, xdr.OperationTypeManageSellOffer, xdr.OperationTypeCreatePassiveSellOffer,
+			xdr.OperationTypePathPaymentStrictSend, xdr.OperationTypePathPaymentStrictReceive:
+		default:
+			continue
+		}
+		claimedOffers, _, _, err := extractClaimedOffers(operationResults, int32(index), operation.Body.Type)
+		if err != nil {
+			// Offers that were not created or updated have no claims
+			continue
+		}
+		for _, claim := range claimedOffers {
+			poolClaim, ok := claim.GetLiquidityPool()
+			if !ok {
+				continue
+			}
+			window, ok := a.windows[poolWindowKey(PoolIDToString(poolClaim.LiquidityPoolId), start)]
+			if !ok {
+				return fmt.Errorf("no state change for liquidity pool %s traded with in operation %d", PoolIDToString(poolClaim.LiquidityPoolId), index)
+			}
+			// The pool charges its fee on the asset it receives
+			bought, sold := int64(poolClaim.AmountBought), int64(poolClaim.AmountSold)
+			fee := new(big.Int).Div(new(big.Int).Mul(big.NewInt(bought), big.NewInt(window.fee)), big.NewInt(10000))
+			window.output.TradeCount++
+			if poolClaim.AssetBought.Equals(window.assetA) {
+				window.volumeA += bought
+				window.volumeB += sold
+				window.feesA.Add(window.feesA, fee)
+			} else {
+				window.volumeA += sold
+				window.volumeB += bought
+				window.feesB.Add(window.feesB, fee)
+			}
+		}
+	}
+	return nil
+}
+
+// window returns the window of pool starting at start, creating it if needed, with the pool state set to pool
+func (a *PoolMetricsAggregator) window(pool PoolOutput, start int64) *poolWindow {
+	key := poolWindowKey(pool.PoolID, start)
+	window, ok := a.windows[key]
+	if !ok {
+		window = &poolWindow{
+			output: LiquidityPoolMetricOutput{
+				PoolID:       pool.PoolID,
+				PoolIDStrkey: pool.PoolIDStrkey,
+				Resolution:   a.resolution,
+				Timestamp:    time.UnixMilli(start).UTC(),
+				FirstLedger:  pool.LedgerSequence,
+				PoolFee:      pool.PoolFee,
+				AssetAType:   pool.AssetAType,
+				AssetACode:   pool.AssetACode,
+				AssetAIssuer: pool.AssetAIssuer,
+				AssetAID:     pool.AssetAID,
+				AssetBType:   pool.AssetBType,
+				AssetBCode:   pool.AssetBCode,
+				AssetBIssuer: pool.AssetBIssuer,
+				AssetBID:     pool.AssetBID,
+			},
+			end:   time.UnixMilli(start + a.length).UTC(),
+			feesA: new(big.Int),
+			feesB: new(big.Int),
+		}
+		a.windows[key] = window
+	}
+	window.output.TrustlineCount = pool.TrustlineCount
+	window.output.PoolShareCount = pool.PoolShareCount
+	window.output.AssetAReserve = pool.AssetAReserve
+	window.output.AssetBReserve = pool.AssetBReserve
+	return window
+}
+
+func poolWindowKey(poolID string, start int64) string {
+	return fmt.Sprintf("%s/%d", poolID, start)
+}
+
+// Closed removes and returns the metrics of the windows that end at or before closeTime
+func (a *PoolMetricsAggregator) Closed(closeTime time.Time) []LiquidityPoolMetricOutput {
+	return a.take(func(window *poolWindow) bool { return !window.end.After(closeTime) })
+}
+
+// Flush removes and returns the metrics of every window, including the ones that could still see activity
+func (a *PoolMetricsAggregator) Flush() []LiquidityPoolMetricOutput {
+	return a.take(func(*poolWindow) bool { return true })
+}
+
+func (a *PoolMetricsAggregator) take(done func(*poolWindow) bool) []LiquidityPoolMetricOutput {
+	taken := []*poolWindow{}
+	for key, window := range a.windows {
+		if done(window) {
+			taken = append(taken, window)
+			delete(a.windows, key)
+		}
+	}
+	sort.Slice(taken, func(i, j int) bool {
+		x, y := taken[i].output, taken[j].output
+		if !x.Timestamp.Equal(y.Timestamp) {
+			return x.Timestamp.Before(y.Timestamp)
+		}
+		return x.PoolID < y.PoolID
+	})
+
+	outputs := make([]LiquidityPoolMetricOutput, 0, len(taken))
+	for _, window := range taken {
+		output := window.output
+		output.AssetAVolume = utils.ConvertStroopValueToReal(xdr.Int64(window.volumeA))
+		output.AssetBVolume = utils.ConvertStroopValueToReal(xdr.Int64(window.volumeB))
+		output.AssetAFees = utils.ConvertStroopValueToReal(xdr.Int64(window.feesA.Int64()))
+		output.AssetBFees = utils.ConvertStroopValueToReal(xdr.Int64(window.feesB.Int64()))
+		output.AssetADeposited = utils.ConvertStroopValueToReal(xdr.Int64(window.depositedA))
+		output.AssetBDeposited = utils.ConvertStroopValueToReal(xdr.Int64(window.depositedB))
+		output.AssetAWithdrawn = utils.ConvertStroopValueToReal(xdr.Int64(window.withdrawnA))
+		output.AssetBWithdrawn = utils.ConvertStroopValueToReal(xdr.Int64(window.withdrawnB))
+
+		if window.reserveA > 0 && window.reserveB > 0 {
+			// Valued at the spot price of the pool, each reserve is worth as much as the other
+			price := big.NewRat(window.reserveB, window.reserveA)
+			output.Price, _ = price.Float64()
+			output.TvlInAssetA = 2 * output.AssetAReserve
+			output.TvlInAssetB = 2 * output.AssetBReserve
+			feesInA := new(big.Rat).SetInt(window.feesA)
+			feesInA.Add(feesInA, new(big.Rat).Quo(new(big.Rat).SetInt(window.feesB), price))
+			output.FeeYield, _ = feesInA.Quo(feesInA, big.NewRat(2*window.reserveA, 1)).Float64()
+		}
+		if output.PoolShareCount > 0 {
+			output.AssetAPerShare = output.AssetAReserve / output.PoolShareCount
+			output.AssetBPerShare = output.AssetBReserve / output.PoolShareCount
+		}
+		outputs = append(outputs, output)
+	}
+	return outputs
+}
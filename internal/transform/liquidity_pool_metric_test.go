@@ -0,0 +1,123 @@
+package transform
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stretchr/testify/assert"
+)
+
+func makePoolMetricTestEntry(reserveA, reserveB, shares xdr.Int64) *xdr.LedgerEntry {
+	return &xdr.LedgerEntry{
+		Data: xdr.LedgerEntryData{
+			Type: xdr.LedgerEntryTypeLiquidityPool,
+			LiquidityPool: &xdr.LiquidityPoolEntry{
+				LiquidityPoolId: xdr.PoolId{7, 8, 9},
+				Body: xdr.LiquidityPoolEntryBody{
+					Type: xdr.LiquidityPoolTypeLiquidityPoolConstantProduct,
+					ConstantProduct: &xdr.LiquidityPoolEntryConstantProduct{
+						Params: xdr.LiquidityPoolConstantProductParameters{
+							AssetA: nativeAsset,
+							AssetB: usdtAsset,
+							Fee:    xdr.LiquidityPoolFeeV18,
+						},
+						ReserveA:                 reserveA,
+						ReserveB:                 reserveB,
+						TotalPoolShares:          shares,
+						PoolSharesTrustLineCount: 2,
+					},
+				},
+			},
+		},
+	}
+}
+
+func makePoolMetricTestInput() ingest.LedgerTransaction {
+	transaction := genericLedgerTransaction
+	envelope := genericBumpOperationEnvelope
+	envelope.Tx.Operations = []xdr.Operation{
+		{Body: xdr.OperationBody{Type: xdr.OperationTypeLiquidityPoolDeposit, LiquidityPoolDepositOp: &xdr.LiquidityPoolDepositOp{}}},
+		{Body: xdr.OperationBody{Type: xdr.OperationTypePathPaymentStrictSend, PathPaymentStrictSendOp: &xdr.PathPaymentStrictSendOp{}}},
+	}
+	transaction.Envelope.V1 = &envelope
+
+	// The path payment sells 1000 XLM to the pool for 1800 USDT
+	results := []xdr.OperationResult{
+		{Code: xdr.OperationResultCodeOpInner, Tr: &xdr.OperationResultTr{
+			Type:                       xdr.OperationTypeLiquidityPoolDeposit,
+			LiquidityPoolDepositResult: &xdr.LiquidityPoolDepositResult{Code: xdr.LiquidityPoolDepositResultCodeLiquidityPoolDepositSuccess},
+		}},
+		{Code: xdr.OperationResultCodeOpInner, Tr: &xdr.OperationResultTr{
+			Type: xdr.OperationTypePathPaymentStrictSend,
+			PathPaymentStrictSendResult: &xdr.PathPaymentStrictSendResult{
+				Code: xdr.PathPaymentStrictSendResultCodePathPaymentStrictSendSuccess,
+				Success: &xdr.PathPaymentStrictSendResultSuccess{Offers: []xdr.ClaimAtom{{
+					Type: xdr.ClaimAtomTypeClaimAtomTypeLiquidityPool,
+					LiquidityPool: &xdr.ClaimLiquidityAtom{
+						LiquidityPoolId: xdr.PoolId{7, 8, 9},
+						AssetSold:       usdtAsset,
+						AmountSold:      18000000000,
+						AssetBought:     nativeAsset,
+						AmountBought:    10000000000,
+					},
+				}}},
+			},
+		}},
+	}
+	transaction.Result.Result.Result.Results = &results
+	transaction.UnsafeMeta = xdr.TransactionMeta{V: 1, V1: &xdr.TransactionMetaV1{Operations: []xdr.OperationMeta{
+		{Changes: xdr.LedgerEntryChanges{
+			{Type: xdr.LedgerEntryChangeTypeLedgerEntryState, State: makePoolMetricTestEntry(100000000000, 200000000000, 1000000000)},
+			{Type: xdr.LedgerEntryChangeTypeLedgerEntryUpdated, Updated: makePoolMetricTestEntry(200000000000, 400000000000, 2000000000)},
+		}},
+		{Changes: xdr.LedgerEntryChanges{
+			{Type: xdr.LedgerEntryChangeTypeLedgerEntryState, State: makePoolMetricTestEntry(200000000000, 400000000000, 2000000000)},
+			{Type: xdr.LedgerEntryChangeTypeLedgerEntryUpdated, Updated: makePoolMetricTestEntry(210000000000, 382000000000, 2000000000)},
+		}},
+	}}}
+	return transaction
+}
+
+func TestPoolMetricsAggregator(t *testing.T) {
+	_, err := NewPoolMetricsAggregator("2m")
+	assert.Error(t, err)
+
+	aggregator, err := NewPoolMetricsAggregator("1h")
+	assert.NoError(t, err)
+	closeTime := time.Date(2024, time.January, 1, 10, 30, 0, 0, time.UTC)
+	lhe := xdr.LedgerHeaderHistoryEntry{Header: xdr.LedgerHeader{LedgerSeq: 50, ScpValue: xdr.StellarValue{CloseTime: xdr.TimePoint(closeTime.Unix())}}}
+	assert.NoError(t, aggregator.AddTransaction(makePoolMetricTestInput(), lhe))
+
+	assert.Empty(t, aggregator.Closed(closeTime.Add(29*time.Minute)))
+	metrics := aggregator.Closed(closeTime.Add(30 * time.Minute))
+	assert.Len(t, metrics, 1)
+	metric := metrics[0]
+	assert.Equal(t, PoolIDToString(xdr.PoolId{7, 8, 9}), metric.PoolID)
+	assert.Equal(t, time.Date(2024, time.January, 1, 10, 0, 0, 0, time.UTC), metric.Timestamp)
+	assert.Equal(t, uint32(50), metric.FirstLedger)
+	assert.Equal(t, uint32(50), metric.LastLedger)
+	assert.Equal(t, "native", metric.AssetAType)
+	assert.Equal(t, "USDT", metric.AssetBCode)
+	assert.Equal(t, 21000.0, metric.AssetAReserve)
+	assert.Equal(t, 38200.0, metric.AssetBReserve)
+	assert.Equal(t, 200.0, metric.PoolShareCount)
+	assert.InDelta(t, 382.0/210.0, metric.Price, 1e-12)
+	assert.Equal(t, 105.0, metric.AssetAPerShare)
+	assert.Equal(t, 42000.0, metric.TvlInAssetA)
+	assert.Equal(t, 76400.0, metric.TvlInAssetB)
+
+	assert.Equal(t, int64(1), metric.TradeCount)
+	assert.Equal(t, 1000.0, metric.AssetAVolume)
+	assert.Equal(t, 1800.0, metric.AssetBVolume)
+	assert.Equal(t, 3.0, metric.AssetAFees)
+	assert.Equal(t, 0.0, metric.AssetBFees)
+	assert.InDelta(t, 3.0/42000.0, metric.FeeYield, 1e-12)
+
+	assert.Equal(t, int64(1), metric.DepositCount)
+	assert.Equal(t, 10000.0, metric.AssetADeposited)
+	assert.Equal(t, 20000.0, metric.AssetBDeposited)
+	assert.Equal(t, int64(0), metric.WithdrawCount)
+	assert.Empty(t, aggregator.Flush())
+}
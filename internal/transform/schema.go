@@ -133,6 +133,34 @@ type AccountSignerOutput struct {
 	LedgerSequence     uint32      `json:"ledger_sequence"`
 }
 
+// AccountSecurityOutput is a representation of the signers and thresholds of an account, and of whether its
+// thresholds can be met without its master key. Signer counts and weights leave the master key out.
+type AccountSecurityOutput struct {
+	AccountID                    string      `json:"account_id"`
+	MasterWeight                 int32       `json:"master_weight"`
+	ThresholdLow                 int32       `json:"threshold_low"`
+	ThresholdMedium              int32       `json:"threshold_medium"`
+	ThresholdHigh                int32       `json:"threshold_high"`
+	MasterKeyDisabled            bool        `json:"master_key_disabled"`
+	NumSigners                   int32       `json:"num_signers"`
+	NumEd25519Signers            int32       `json:"num_ed25519_signers"`
+	NumPreAuthTxSigners          int32       `json:"num_pre_auth_tx_signers"`
+	NumHashXSigners              int32       `json:"num_hash_x_signers"`
+	NumSignedPayloadSigners      int32       `json:"num_signed_payload_signers"`
+	SignersWeight                int32       `json:"signers_weight"`
+	TotalWeight                  int32       `json:"total_weight"`
+	LowReachableWithoutMaster    bool        `json:"low_reachable_without_master"`
+	MediumReachableWithoutMaster bool        `json:"medium_reachable_without_master"`
+	HighReachableWithoutMaster   bool        `json:"high_reachable_without_master"`
+	Sponsor                      null.String `json:"sponsor"`
+	NumSponsoredSigners          int32       `json:"num_sponsored_signers"`
+	LastModifiedLedger           uint32      `json:"last_modified_ledger"`
+	LedgerEntryChange            uint32      `json:"ledger_entry_change"`
+	Deleted                      bool        `json:"deleted"`
+	ClosedAt                     time.Time   `json:"closed_at"`
+	LedgerSequence               uint32      `json:"ledger_sequence"`
+}
+
 // OperationOutput is a representation of an operation that aligns with the BigQuery table history_operations
 type OperationOutput struct {
 	SourceAccount        string                 `json:"source_account"`
@@ -174,6 +202,40 @@ type Claimant struct {
 	Predicate   xdr.ClaimPredicate `json:"predicate"`
 }
 
+// ClaimableBalanceEventOutput is a representation of the creation, claim, clawback or expiry of a claimable balance
+type ClaimableBalanceEventOutput struct {
+	BalanceID       string                 `json:"balance_id"`
+	EventType       string                 `json:"event_type"`
+	Account         null.String            `json:"account"`
+	AssetCode       string                 `json:"asset_code"`
+	AssetIssuer     string                 `json:"asset_issuer"`
+	AssetType       string                 `json:"asset_type"`
+	AssetID         int64                  `json:"asset_id"`
+	AssetAmount     float64                `json:"asset_amount"`
+	Sponsor         null.String            `json:"sponsor"`
+	Claimants       []ClaimantWindowOutput `json:"claimants"`
+	ExpiresAt       null.Time              `json:"expires_at"`
+	TransactionHash null.String            `json:"transaction_hash"`
+	OperationID     null.Int               `json:"operation_id"`
+	LedgerSequence  uint32                 `json:"ledger_sequence"`
+	ClosedAt        time.Time              `json:"closed_at"`
+}
+
+// ClaimantWindowOutput is a representation of the times at which a claimant can claim a balance
+type ClaimantWindowOutput struct {
+	Destination    string              `json:"destination"`
+	ClaimableFrom  null.Time           `json:"claimable_from"`
+	ClaimableUntil null.Time           `json:"claimable_until"`
+	Windows        []ClaimWindowOutput `json:"windows"`
+}
+
+// ClaimWindowOutput is a representation of a time range during which a claimant can claim a balance, which never ends
+// if until is null
+type ClaimWindowOutput struct {
+	From  time.Time `json:"from"`
+	Until null.Time `json:"until"`
+}
+
 // Price represents the price of an asset as a fraction
 type Price struct {
 	Numerator   int32 `json:"n"`
@@ -234,6 +296,49 @@ type AssetOutput struct {
 	LedgerSequence uint32    `json:"ledger_sequence"`
 }
 
+// AssetRegistryOutput is a representation of an asset along with the ledger and the way it was first seen
+type AssetRegistryOutput struct {
+	AssetCode       string    `json:"asset_code"`
+	AssetIssuer     string    `json:"asset_issuer"`
+	AssetType       string    `json:"asset_type"`
+	AssetID         int64     `json:"asset_id"`
+	ContractId      string    `json:"contract_id"`
+	SourceType      string    `json:"source_type"`
+	FirstSeenLedger uint32    `json:"first_seen_ledger"`
+	TransactionHash string    `json:"transaction_hash"`
+	OperationID     int64     `json:"operation_id"`
+	ClosedAt        time.Time `json:"closed_at"`
+}
+
+// AssetStatOutput is a representation of the supply and holders of an asset as of a ledger
+type AssetStatOutput struct {
+	AssetCode                                  string    `json:"asset_code"`
+	AssetIssuer                                string    `json:"asset_issuer"`
+	AssetType                                  string    `json:"asset_type"`
+	AssetID                                    int64     `json:"asset_id"`
+	ContractId                                 string    `json:"contract_id"`
+	NumAccountsAuthorized                      int64     `json:"num_accounts_authorized"`
+	NumAccountsAuthorizedToMaintainLiabilities int64     `json:"num_accounts_authorized_to_maintain_liabilities"`
+	NumAccountsUnauthorized                    int64     `json:"num_accounts_unauthorized"`
+	BalanceAuthorized                          float64   `json:"balance_authorized"`
+	BalanceAuthorizedToMaintainLiabilities     float64   `json:"balance_authorized_to_maintain_liabilities"`
+	BalanceUnauthorized                        float64   `json:"balance_unauthorized"`
+	NumClaimableBalances                       int64     `json:"num_claimable_balances"`
+	ClaimableBalancesAmount                    float64   `json:"claimable_balances_amount"`
+	NumLiquidityPools                          int64     `json:"num_liquidity_pools"`
+	LiquidityPoolsAmount                       float64   `json:"liquidity_pools_amount"`
+	NumContracts                               int64     `json:"num_contracts"`
+	ContractsAmount                            float64   `json:"contracts_amount"`
+	NumHolders                                 int64     `json:"num_holders"`
+	TotalSupply                                float64   `json:"total_supply"`
+	AuthRequired                               bool      `json:"auth_required"`
+	AuthRevocable                              bool      `json:"auth_revocable"`
+	AuthImmutable                              bool      `json:"auth_immutable"`
+	AuthClawbackEnabled                        bool      `json:"auth_clawback_enabled"`
+	ClosedAt                                   time.Time `json:"closed_at"`
+	LedgerSequence                             uint32    `json:"ledger_sequence"`
+}
+
 // TrustlineOutput is a representation of a trustline that aligns with the BigQuery table trust_lines
 type TrustlineOutput struct {
 	LedgerKey             string      `json:"ledger_key"`
@@ -311,6 +416,157 @@ type TradeOutput struct {
 	SellingLiquidityPoolIDStrkey null.String `json:"selling_liquidity_pool_id_strkey"`
 }
 
+// TradeAggregationOutput is a representation of an OHLCV candle of the trades of an asset pair over a time bucket
+type TradeAggregationOutput struct {
+	Resolution         string    `json:"resolution"`
+	Timestamp          time.Time `json:"timestamp"` // start of the bucket
+	TradeType          int32     `json:"trade_type"`
+	BaseAssetType      string    `json:"base_asset_type"`
+	BaseAssetCode      string    `json:"base_asset_code"`
+	BaseAssetIssuer    string    `json:"base_asset_issuer"`
+	BaseAssetID        int64     `json:"base_asset_id"`
+	CounterAssetType   string    `json:"counter_asset_type"`
+	CounterAssetCode   string    `json:"counter_asset_code"`
+	CounterAssetIssuer string    `json:"counter_asset_issuer"`
+	CounterAssetID     int64     `json:"counter_asset_id"`
+	TradeCount         int64     `json:"trade_count"`
+	BaseVolume         float64   `json:"base_volume"`
+	CounterVolume      float64   `json:"counter_volume"`
+	Average            float64   `json:"average"`
+	Open               float64   `json:"open"`
+	OpenN              int64     `json:"open_n"`
+	OpenD              int64     `json:"open_d"`
+	High               float64   `json:"high"`
+	HighN              int64     `json:"high_n"`
+	HighD              int64     `json:"high_d"`
+	Low                float64   `json:"low"`
+	LowN               int64     `json:"low_n"`
+	LowD               int64     `json:"low_d"`
+	Close              float64   `json:"close"`
+	CloseN             int64     `json:"close_n"`
+	CloseD             int64     `json:"close_d"`
+}
+
+// LiquidityPoolMetricOutput is a representation of the activity of a liquidity pool within a time window, with the
+// pool state as of the last change in the window
+type LiquidityPoolMetricOutput struct {
+	PoolID          string    `json:"liquidity_pool_id"`
+	PoolIDStrkey    string    `json:"liquidity_pool_id_strkey"`
+	Resolution      string    `json:"resolution"`
+	Timestamp       time.Time `json:"timestamp"`
+	FirstLedger     uint32    `json:"first_ledger"`
+	LastLedger      uint32    `json:"last_ledger"`
+	PoolFee         uint32    `json:"fee"`
+	AssetAType      string    `json:"asset_a_type"`
+	AssetACode      string    `json:"asset_a_code"`
+	AssetAIssuer    string    `json:"asset_a_issuer"`
+	AssetAID        int64     `json:"asset_a_id"`
+	AssetBType      string    `json:"asset_b_type"`
+	AssetBCode      string    `json:"asset_b_code"`
+	AssetBIssuer    string    `json:"asset_b_issuer"`
+	AssetBID        int64     `json:"asset_b_id"`
+	AssetAReserve   float64   `json:"asset_a_amount"`
+	AssetBReserve   float64   `json:"asset_b_amount"`
+	PoolShareCount  float64   `json:"pool_share_count"`
+	TrustlineCount  uint64    `json:"trustline_count"`
+	Price           float64   `json:"price"`
+	AssetAPerShare  float64   `json:"asset_a_per_share"`
+	AssetBPerShare  float64   `json:"asset_b_per_share"`
+	TvlInAssetA     float64   `json:"tvl_in_asset_a"`
+	TvlInAssetB     float64   `json:"tvl_in_asset_b"`
+	TradeCount      int64     `json:"trade_count"`
+	AssetAVolume    float64   `json:"asset_a_volume"`
+	AssetBVolume    float64   `json:"asset_b_volume"`
+	AssetAFees      float64   `json:"asset_a_fees"`
+	AssetBFees      float64   `json:"asset_b_fees"`
+	FeeYield        float64   `json:"fee_yield"`
+	DepositCount    int64     `json:"deposit_count"`
+	AssetADeposited float64   `json:"asset_a_deposited"`
+	AssetBDeposited float64   `json:"asset_b_deposited"`
+	WithdrawCount   int64     `json:"withdraw_count"`
+	AssetAWithdrawn float64   `json:"asset_a_withdrawn"`
+	AssetBWithdrawn float64   `json:"asset_b_withdrawn"`
+}
+
+// AccountLifecycleOutput is a representation of the creation, merge and last activity of an account
+type AccountLifecycleOutput struct {
+	AccountID              string      `json:"account_id"`
+	CreatedLedger          null.Int    `json:"created_ledger"`
+	CreatedAt              null.Time   `json:"created_at"`
+	Funder                 null.String `json:"funder"`
+	StartingBalance        null.Float  `json:"starting_balance"`
+	CreatedTransactionHash null.String `json:"created_transaction_hash"`
+	CreationSponsor        null.String `json:"creation_sponsor"`
+	MergedInto             null.String `json:"merged_into"`
+	MergedLedger           null.Int    `json:"merged_ledger"`
+	MergedAt               null.Time   `json:"merged_at"`
+	MergedTransactionHash  null.String `json:"merged_transaction_hash"`
+	LastActivityLedger     null.Int    `json:"last_activity_ledger"`
+	LastActivityAt         null.Time   `json:"last_activity_at"`
+}
+
+// BalanceDeltaOutput is a representation of the net change of a balance of an account or contract within a
+// transaction, split by what caused it. Deltas are strings because they are 128 bit integers of stroops.
+type BalanceDeltaOutput struct {
+	LedgerSequence        uint32    `json:"ledger_sequence"`
+	ClosedAt              time.Time `json:"closed_at"`
+	TransactionHash       string    `json:"transaction_hash"`
+	TransactionID         int64     `json:"transaction_id"`
+	Successful            bool      `json:"successful"`
+	Address               string    `json:"address"`
+	AddressType           string    `json:"address_type"`
+	AssetType             string    `json:"asset_type"`
+	AssetCode             string    `json:"asset_code"`
+	AssetIssuer           string    `json:"asset_issuer"`
+	AssetContractId       string    `json:"asset_contract_id"`
+	BalanceDelta          string    `json:"balance_delta"`
+	FeeDelta              string    `json:"fee_delta"`
+	TransferDelta         string    `json:"transfer_delta"`
+	TradeDelta            string    `json:"trade_delta"`
+	LiquidityPoolDelta    string    `json:"liquidity_pool_delta"`
+	ClaimableBalanceDelta string    `json:"claimable_balance_delta"`
+	ClawbackDelta         string    `json:"clawback_delta"`
+	OtherDelta            string    `json:"other_delta"`
+}
+
+// BalanceDiscrepancyOutput is a representation of a balance of an account or contract whose change within a
+// transaction does not match the sum of its token transfers. Amounts are strings because they are 128 bit integers of
+// stroops, and the difference is the balance delta minus the transfer amount.
+type BalanceDiscrepancyOutput struct {
+	LedgerSequence  uint32    `json:"ledger_sequence"`
+	ClosedAt        time.Time `json:"closed_at"`
+	TransactionHash string    `json:"transaction_hash"`
+	TransactionID   int64     `json:"transaction_id"`
+	Address         string    `json:"address"`
+	AssetType       string    `json:"asset_type"`
+	AssetCode       string    `json:"asset_code"`
+	AssetIssuer     string    `json:"asset_issuer"`
+	AssetContractId string    `json:"asset_contract_id"`
+	EventCount      int64     `json:"event_count"`
+	TransferAmount  string    `json:"transfer_amount"`
+	BalanceDelta    string    `json:"balance_delta"`
+	Difference      string    `json:"difference"`
+}
+
+// SponsorshipChangeOutput is a representation of a change of the sponsor paying the reserves of a ledger entry or an
+// account signer. The sponsored entry is identified by its ledger key hash, and by the signer for signers.
+type SponsorshipChangeOutput struct {
+	LedgerSequence  uint32      `json:"ledger_sequence"`
+	ClosedAt        time.Time   `json:"closed_at"`
+	TransactionHash string      `json:"transaction_hash"`
+	OperationID     int64       `json:"operation_id"`
+	OperationType   string      `json:"operation_type"`
+	EventType       string      `json:"event_type"`
+	EntryType       string      `json:"entry_type"`
+	LedgerKeyHash   string      `json:"ledger_key_hash"`
+	Sponsored       string      `json:"sponsored"`
+	Signer          null.String `json:"signer"`
+	Sponsor         null.String `json:"sponsor"`
+	PreviousSponsor null.String `json:"previous_sponsor"`
+	NumReserves     int64       `json:"num_reserves"`
+	Revoked         bool        `json:"revoked"`
+}
+
 // DimAccount is a representation of an account that aligns with the BigQuery table dim_accounts
 type DimAccount struct {
 	ID      uint64 `json:"account_id"`
@@ -536,6 +792,22 @@ type ContractDataOutput struct {
 	LedgerKeyHashBase64       string      `json:"ledger_key_hash_base_64"`
 }
 
+// ContractBalanceOutput is a representation of the balance of a holder kept in the storage of a token contract
+type ContractBalanceOutput struct {
+	ContractId         string    `json:"contract_id"`
+	TokenType          string    `json:"token_type"`
+	Holder             string    `json:"holder"`
+	Amount             string    `json:"amount"` // amount is a string because it is a 128 bit integer
+	Authorized         null.Bool `json:"authorized"`
+	Clawback           null.Bool `json:"clawback"`
+	LedgerKeyHash      string    `json:"ledger_key_hash"`
+	LastModifiedLedger uint32    `json:"last_modified_ledger"`
+	LedgerEntryChange  uint32    `json:"ledger_entry_change"`
+	Deleted            bool      `json:"deleted"`
+	ClosedAt           time.Time `json:"closed_at"`
+	LedgerSequence     uint32    `json:"ledger_sequence"`
+}
+
 // ContractCodeOutput is a representation of contract code that aligns with the Bigquery table soroban_contract_code
 type ContractCodeOutput struct {
 	ContractCodeHash   string    `json:"contract_code_hash"`
@@ -560,6 +832,99 @@ type ContractCodeOutput struct {
 	LedgerKeyHashBase64 string `json:"ledger_key_hash_base_64"`
 }
 
+// ContractSpecOutput is a representation of the interface of an uploaded contract, decoded from the custom sections of its WASM
+type ContractSpecOutput struct {
+	ContractCodeHash    string                 `json:"contract_code_hash"`
+	Functions           []ContractSpecFunction `json:"functions"`
+	Structs             []ContractSpecStruct   `json:"structs"`
+	Unions              []ContractSpecUnion    `json:"unions"`
+	Enums               []ContractSpecEnum     `json:"enums"`
+	ErrorEnums          []ContractSpecEnum     `json:"error_enums"`
+	Events              []ContractSpecEvent    `json:"events"`
+	Meta                map[string]string      `json:"meta"`
+	RustcVersion        string                 `json:"rustc_version"`
+	SdkVersion          string                 `json:"sdk_version"`
+	InterfaceProtocol   uint32                 `json:"interface_protocol"`
+	InterfacePreRelease uint32                 `json:"interface_pre_release"`
+	SpecXDR             string                 `json:"spec_xdr"`
+	LastModifiedLedger  uint32                 `json:"last_modified_ledger"`
+	LedgerEntryChange   uint32                 `json:"ledger_entry_change"`
+	Deleted             bool                   `json:"deleted"`
+	ClosedAt            time.Time              `json:"closed_at"`
+	LedgerSequence      uint32                 `json:"ledger_sequence"`
+}
+
+// ContractSpecField is a named and typed function input or struct field of a contract spec
+type ContractSpecField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Doc  string `json:"doc,omitempty"`
+}
+
+// ContractSpecFunction is a function exported by a contract
+type ContractSpecFunction struct {
+	Name    string              `json:"name"`
+	Doc     string              `json:"doc,omitempty"`
+	Inputs  []ContractSpecField `json:"inputs"`
+	Outputs []string            `json:"outputs"`
+}
+
+// ContractSpecStruct is a user-defined struct type of a contract
+type ContractSpecStruct struct {
+	Name   string              `json:"name"`
+	Lib    string              `json:"lib,omitempty"`
+	Doc    string              `json:"doc,omitempty"`
+	Fields []ContractSpecField `json:"fields"`
+}
+
+// ContractSpecUnion is a user-defined union type of a contract
+type ContractSpecUnion struct {
+	Name  string                  `json:"name"`
+	Lib   string                  `json:"lib,omitempty"`
+	Doc   string                  `json:"doc,omitempty"`
+	Cases []ContractSpecUnionCase `json:"cases"`
+}
+
+// ContractSpecUnionCase is a variant of a union; void variants have no types
+type ContractSpecUnionCase struct {
+	Name  string   `json:"name"`
+	Doc   string   `json:"doc,omitempty"`
+	Types []string `json:"types"`
+}
+
+// ContractSpecEnum is a user-defined enum or error enum of a contract
+type ContractSpecEnum struct {
+	Name  string                 `json:"name"`
+	Lib   string                 `json:"lib,omitempty"`
+	Doc   string                 `json:"doc,omitempty"`
+	Cases []ContractSpecEnumCase `json:"cases"`
+}
+
+// ContractSpecEnumCase is a named value of an enum
+type ContractSpecEnumCase struct {
+	Name  string `json:"name"`
+	Value uint32 `json:"value"`
+	Doc   string `json:"doc,omitempty"`
+}
+
+// ContractSpecEvent is an event a contract declares it emits
+type ContractSpecEvent struct {
+	Name         string                   `json:"name"`
+	Lib          string                   `json:"lib,omitempty"`
+	Doc          string                   `json:"doc,omitempty"`
+	PrefixTopics []string                 `json:"prefix_topics"`
+	Params       []ContractSpecEventParam `json:"params"`
+	DataFormat   string                   `json:"data_format"`
+}
+
+// ContractSpecEventParam is a parameter of an event, published either in the topics or in the data
+type ContractSpecEventParam struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Location string `json:"location"`
+	Doc      string `json:"doc,omitempty"`
+}
+
 // ConfigSettingOutput is a representation of soroban config settings that aligns with the Bigquery table config_settings
 type ConfigSettingOutput struct {
 	ConfigSettingId                        int32               `json:"config_setting_id"`
@@ -669,6 +1034,172 @@ type ContractEventOutput struct {
 	DataDecoded              interface{}   `json:"data_decoded"`
 	ContractEventXDR         string        `json:"contract_event_xdr"`
 	OperationID              null.Int      `json:"operation_id"`
+	EventName                string        `json:"event_name,omitempty"` // only set when decoding with the contract spec
+}
+
+// ContractInvocationOutput is a representation of a contract (sub)invocation of a Soroban transaction's call tree
+type ContractInvocationOutput struct {
+	TransactionHash                 string        `json:"transaction_hash"`
+	TransactionID                   int64         `json:"transaction_id"`
+	OperationID                     int64         `json:"operation_id"`
+	LedgerSequence                  uint32        `json:"ledger_sequence"`
+	ClosedAt                        time.Time     `json:"closed_at"`
+	InvocationIndex                 uint32        `json:"invocation_index"` // position of the call within the transaction, in call order
+	ParentIndex                     null.Int      `json:"parent_index"`
+	Depth                           uint32        `json:"depth"`
+	HostFunctionType                string        `json:"host_function_type"`
+	Caller                          string        `json:"caller"`
+	ContractId                      string        `json:"contract_id"`
+	FunctionName                    string        `json:"function_name"`
+	Args                            []interface{} `json:"args"`
+	ArgsDecoded                     []interface{} `json:"args_decoded"`
+	ReturnValue                     interface{}   `json:"return_value"`
+	ReturnValueDecoded              interface{}   `json:"return_value_decoded"`
+	Successful                      bool          `json:"successful"`
+	TransactionSuccessful           bool          `json:"transaction_successful"`
+	ResourceFee                     int64         `json:"resource_fee"`
+	SorobanResourcesInstructions    uint32        `json:"soroban_resources_instructions"`
+	SorobanResourcesDiskReadBytes   uint32        `json:"soroban_resources_disk_read_bytes"`
+	SorobanResourcesWriteBytes      uint32        `json:"soroban_resources_write_bytes"`
+	NonRefundableResourceFeeCharged int64         `json:"non_refundable_resource_fee_charged"`
+	RefundableResourceFeeCharged    int64         `json:"refundable_resource_fee_charged"`
+	RentFeeCharged                  int64         `json:"rent_fee_charged"`
+}
+
+// SorobanAuthEntryOutput is a representation of an authorization entry signed for an InvokeHostFunction operation
+type SorobanAuthEntryOutput struct {
+	TransactionHash           string                        `json:"transaction_hash"`
+	TransactionID             int64                         `json:"transaction_id"`
+	OperationID               int64                         `json:"operation_id"`
+	LedgerSequence            uint32                        `json:"ledger_sequence"`
+	ClosedAt                  time.Time                     `json:"closed_at"`
+	AuthEntryIndex            uint32                        `json:"auth_entry_index"`
+	CredentialsType           string                        `json:"credentials_type"`
+	Address                   string                        `json:"address"`
+	Nonce                     null.Int                      `json:"nonce"`
+	SignatureExpirationLedger null.Int                      `json:"signature_expiration_ledger"`
+	Signature                 interface{}                   `json:"signature"`
+	SignatureDecoded          interface{}                   `json:"signature_decoded"`
+	RootFunctionType          string                        `json:"root_function_type"`
+	RootContractId            string                        `json:"root_contract_id"`
+	RootFunctionName          string                        `json:"root_function_name"`
+	Invocations               []SorobanAuthInvocationOutput `json:"invocations"`
+	AuthEntryXDR              string                        `json:"auth_entry_xdr"`
+	TransactionSuccessful     bool                          `json:"transaction_successful"`
+}
+
+// SorobanAuthInvocationOutput is a node of the authorized invocation tree of an authorization entry, flattened in depth first order
+type SorobanAuthInvocationOutput struct {
+	InvocationIndex uint32        `json:"invocation_index"`
+	ParentIndex     null.Int      `json:"parent_index"`
+	Depth           uint32        `json:"depth"`
+	FunctionType    string        `json:"function_type"`
+	ContractId      string        `json:"contract_id"`
+	FunctionName    string        `json:"function_name"`
+	Args            []interface{} `json:"args"`
+	ArgsDecoded     []interface{} `json:"args_decoded"`
+	WasmHash        string        `json:"wasm_hash"`
+}
+
+// TransactionFootprintOutput is a representation of a ledger key in the footprint of a Soroban transaction
+type TransactionFootprintOutput struct {
+	TransactionHash       string    `json:"transaction_hash"`
+	TransactionID         int64     `json:"transaction_id"`
+	LedgerSequence        uint32    `json:"ledger_sequence"`
+	ClosedAt              time.Time `json:"closed_at"`
+	OperationType         string    `json:"operation_type"`
+	Access                string    `json:"access"`
+	FootprintIndex        uint32    `json:"footprint_index"` // position of the key within the read_only or read_write footprint
+	LedgerKeyHash         string    `json:"ledger_key_hash"`
+	LedgerEntryType       string    `json:"ledger_entry_type"`
+	ContractId            string    `json:"contract_id"`
+	ContractDurability    string    `json:"contract_durability"`
+	ContractCodeHash      string    `json:"contract_code_hash"`
+	AutoRestored          bool      `json:"auto_restored"`
+	Restored              bool      `json:"restored"`
+	Modified              bool      `json:"modified"`
+	TransactionSuccessful bool      `json:"transaction_successful"`
+}
+
+// TtlExtensionOutput is a representation of the TTL bought for a contract data or code entry by a Soroban transaction,
+// either by extending it, restoring it or creating it
+type TtlExtensionOutput struct {
+	TransactionHash       string    `json:"transaction_hash"`
+	TransactionID         int64     `json:"transaction_id"`
+	OperationID           int64     `json:"operation_id"`
+	LedgerSequence        uint32    `json:"ledger_sequence"`
+	ClosedAt              time.Time `json:"closed_at"`
+	FeeAccount            string    `json:"fee_account"`
+	OperationType         string    `json:"operation_type"`
+	ExtensionType         string    `json:"extension_type"`
+	EventType             string    `json:"event_type"`
+	LedgerKeyHash         string    `json:"ledger_key_hash"`
+	LedgerEntryType       string    `json:"ledger_entry_type"`
+	ContractId            string    `json:"contract_id"`
+	ContractDurability    string    `json:"contract_durability"`
+	ContractCodeHash      string    `json:"contract_code_hash"`
+	OldLiveUntilLedgerSeq null.Int  `json:"old_live_until_ledger_seq"`
+	NewLiveUntilLedgerSeq uint32    `json:"new_live_until_ledger_seq"`
+	LedgersExtended       uint32    `json:"ledgers_extended"`
+	RentFeeCharged        int64     `json:"rent_fee_charged"`
+}
+
+// ArchivalEventOutput is a representation of a contract data or code entry expiring, being evicted from the live
+// bucket list or being restored
+type ArchivalEventOutput struct {
+	LedgerSequence     uint32    `json:"ledger_sequence"`
+	ClosedAt           time.Time `json:"closed_at"`
+	EventType          string    `json:"event_type"`
+	LedgerKey          string    `json:"ledger_key"` // base64 encoded XDR of the ledger key
+	LedgerKeyHash      string    `json:"ledger_key_hash"`
+	LedgerEntryType    string    `json:"ledger_entry_type"`
+	ContractId         string    `json:"contract_id"`
+	ContractDurability string    `json:"contract_durability"`
+	ContractCodeHash   string    `json:"contract_code_hash"`
+	LiveUntilLedgerSeq null.Int  `json:"live_until_ledger_seq"`
+	TransactionHash    string    `json:"transaction_hash"`
+	OperationType      string    `json:"operation_type"`
+}
+
+// ContractLineageOutput is a representation of the creation of a contract instance or of a later change of its executable
+type ContractLineageOutput struct {
+	ContractId             string        `json:"contract_id"`
+	EventType              string        `json:"event_type"`
+	ExecutableType         string        `json:"executable_type"`
+	WasmHash               string        `json:"wasm_hash"`
+	PreviousExecutableType string        `json:"previous_executable_type"`
+	PreviousWasmHash       string        `json:"previous_wasm_hash"`
+	PreimageType           string        `json:"preimage_type"`
+	Deployer               string        `json:"deployer"`
+	Salt                   string        `json:"salt"`
+	Asset                  string        `json:"asset"`
+	ConstructorArgs        []interface{} `json:"constructor_args"`
+	ConstructorArgsDecoded []interface{} `json:"constructor_args_decoded"`
+	SourceAccount          string        `json:"source_account"`
+	TransactionHash        string        `json:"transaction_hash"`
+	TransactionID          int64         `json:"transaction_id"`
+	OperationID            int64         `json:"operation_id"`
+	LedgerSequence         uint32        `json:"ledger_sequence"`
+	ClosedAt               time.Time     `json:"closed_at"`
+}
+
+// TokenOutput is a representation of a token contract, either a Stellar Asset Contract or a SEP-41 token keeping its
+// metadata in instance storage
+type TokenOutput struct {
+	ContractId          string    `json:"contract_id"`
+	TokenType           string    `json:"token_type"`
+	Name                string    `json:"name"`
+	Symbol              string    `json:"symbol"`
+	Decimals            null.Int  `json:"decimals"`
+	Admin               string    `json:"admin"`
+	AssetCode           string    `json:"asset_code"`
+	AssetIssuer         string    `json:"asset_issuer"`
+	AssetType           string    `json:"asset_type"`
+	FirstSeenLedger     uint32    `json:"first_seen_ledger"`
+	EmitsStandardEvents bool      `json:"emits_standard_events"`
+	LastModifiedLedger  uint32    `json:"last_modified_ledger"`
+	LedgerSequence      uint32    `json:"ledger_sequence"`
+	ClosedAt            time.Time `json:"closed_at"`
 }
 
 type TokenTransferOutput struct {
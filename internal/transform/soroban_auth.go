@@ -0,0 +1,152 @@
+package transform
+
+import (
+	"fmt"
+
+	"github.com/guregu/null"
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-etl/v2/internal/toid"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+)
+
+// TransformSorobanAuthEntries converts the authorization entries of the InvokeHostFunction operations of a
+// transaction into one row per entry, each with its authorized invocation tree flattened in depth first order
+func TransformSorobanAuthEntries(transaction ingest.LedgerTransaction, lhe xdr.LedgerHeaderHistoryEntry) ([]SorobanAuthEntryOutput, error) {
+	ledgerSequence := uint32(lhe.Header.LedgerSeq)
+	transactionID := toid.New(int32(ledgerSequence), int32(transaction.Index), 0).ToInt64()
+
+	closedAt, err := utils.TimePointToUTCTimeStamp(lhe.Header.ScpValue.CloseTime)
+	if err != nil {
+		return []SorobanAuthEntryOutput{}, fmt.Errorf("for ledger %d; transaction %d (transaction id=%d): %v", ledgerSequence, transaction.Index, transactionID, err)
+	}
+
+	var transformedEntries []SorobanAuthEntryOutput
+	for operationIndex, operation := range transaction.Envelope.Operations() {
+		op, ok := operation.Body.GetInvokeHostFunctionOp()
+		if !ok {
+			continue
+		}
+		// operationIndex needs +1 increment to stay in sync with ingest package
+		operationID := toid.New(int32(ledgerSequence), int32(transaction.Index), int32(operationIndex+1)).ToInt64()
+
+		for authIndex, entry := range op.Auth {
+			entryXDR, err := xdr.MarshalBase64(entry)
+			if err != nil {
+				return []SorobanAuthEntryOutput{}, err
+			}
+
+			transformedEntry := SorobanAuthEntryOutput{
+				TransactionHash:       utils.HashToHexString(transaction.Result.TransactionHash),
+				TransactionID:         transactionID,
+				OperationID:           operationID,
+				LedgerSequence:        ledgerSequence,
+				ClosedAt:              closedAt,
+				AuthEntryIndex:        uint32(authIndex),
+				AuthEntryXDR:          entryXDR,
+				TransactionSuccessful: transaction.Result.Successful(),
+			}
+
+			switch entry.Credentials.Type {
+			case xdr.SorobanCredentialsTypeSorobanCredentialsSourceAccount:
+				// The entry is authorized by the signature of the source account of the operation
+				transformedEntry.CredentialsType = "source_account"
+				transformedEntry.Address, err = utils.GetAccountAddressFromMuxedAccount(getOperationSourceAccount(operation, transaction))
+				if err != nil {
+					return []SorobanAuthEntryOutput{}, err
+				}
+			case xdr.SorobanCredentialsTypeSorobanCredentialsAddress:
+				credentials := entry.Credentials.MustAddress()
+				transformedEntry.CredentialsType = "address"
+				transformedEntry.Address, err = credentials.Address.String()
+				if err != nil {
+					return []SorobanAuthEntryOutput{}, err
+				}
+				transformedEntry.Nonce = null.IntFrom(int64(credentials.Nonce))
+				transformedEntry.SignatureExpirationLedger = null.IntFrom(int64(credentials.SignatureExpirationLedger))
+				transformedEntry.Signature, transformedEntry.SignatureDecoded, err = serializeScVal(credentials.Signature)
+				if err != nil {
+					return []SorobanAuthEntryOutput{}, err
+				}
+			default:
+				return []SorobanAuthEntryOutput{}, fmt.Errorf("unknown soroban credentials type: %s", entry.Credentials.Type)
+			}
+
+			transformedEntry.Invocations, err = flattenAuthorizedInvocation(entry.RootInvocation, nil, 0, nil)
+			if err != nil {
+				return []SorobanAuthEntryOutput{}, err
+			}
+			root := transformedEntry.Invocations[0]
+			transformedEntry.RootFunctionType = root.FunctionType
+			transformedEntry.RootContractId = root.ContractId
+			transformedEntry.RootFunctionName = root.FunctionName
+
+			transformedEntries = append(transformedEntries, transformedEntry)
+		}
+	}
+
+	return transformedEntries, nil
+}
+
+// flattenAuthorizedInvocation appends invocation and its sub-invocations to nodes in depth first order
+func flattenAuthorizedInvocation(invocation xdr.SorobanAuthorizedInvocation, parentIndex *uint32, depth uint32, nodes []SorobanAuthInvocationOutput) ([]SorobanAuthInvocationOutput, error) {
+	node := SorobanAuthInvocationOutput{
+		InvocationIndex: uint32(len(nodes)),
+		Depth:           depth,
+		Args:            []interface{}{},
+		ArgsDecoded:     []interface{}{},
+	}
+	if parentIndex != nil {
+		node.ParentIndex = null.IntFrom(int64(*parentIndex))
+	}
+
+	var args []xdr.ScVal
+	var executable *xdr.ContractExecutable
+	function := invocation.Function
+	switch function.Type {
+	case xdr.SorobanAuthorizedFunctionTypeSorobanAuthorizedFunctionTypeContractFn:
+		node.FunctionType = "contract_fn"
+		contractFn := function.MustContractFn()
+		contractId, err := contractFn.ContractAddress.String()
+		if err != nil {
+			return nil, err
+		}
+		node.ContractId = contractId
+		node.FunctionName = string(contractFn.FunctionName)
+		args = contractFn.Args
+	case xdr.SorobanAuthorizedFunctionTypeSorobanAuthorizedFunctionTypeCreateContractHostFn:
+		node.FunctionType = "create_contract"
+		createContract := function.MustCreateContractHostFn()
+		executable = &createContract.Executable
+	case xdr.SorobanAuthorizedFunctionTypeSorobanAuthorizedFunctionTypeCreateContractV2HostFn:
+		node.FunctionType = "create_contract_v2"
+		createContract := function.MustCreateContractV2HostFn()
+		executable = &createContract.Executable
+		args = createContract.ConstructorArgs
+	default:
+		return nil, fmt.Errorf("unknown soroban authorized function type: %s", function.Type)
+	}
+	if executable != nil {
+		if wasmHash, ok := executable.GetWasmHash(); ok {
+			node.WasmHash = wasmHash.HexString()
+		}
+	}
+
+	if len(args) > 0 {
+		var err error
+		node.Args, node.ArgsDecoded, err = serializeScValArray(args)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	nodes = append(nodes, node)
+	for _, subInvocation := range invocation.SubInvocations {
+		var err error
+		nodes, err = flattenAuthorizedInvocation(subInvocation, &node.InvocationIndex, depth+1, nodes)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nodes, nil
+}
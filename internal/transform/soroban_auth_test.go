@@ -0,0 +1,116 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/guregu/null"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+func TestTransformSorobanAuthEntries(t *testing.T) {
+	var root, token xdr.ContractId
+	root[0], token[0] = 1, 2
+	var wasmHash xdr.Hash
+	wasmHash[0] = 3
+	source := "GAOEOQMXDDXPVJC3HDFX6LZFKANJ4OOLQOD2MNXJ7PGAY5FEO4BRRAQU"
+	signer := "GBVVRXLMNCJQW3IDDXC3X6XCH35B5Q7QXNMMFPENSOGUPQO7WO7HGZPA"
+	signerAccount := xdr.MustAddress(signer)
+
+	contractFn := func(contractId xdr.ContractId, name string, args ...xdr.ScVal) xdr.SorobanAuthorizedFunction {
+		return xdr.SorobanAuthorizedFunction{
+			Type: xdr.SorobanAuthorizedFunctionTypeSorobanAuthorizedFunctionTypeContractFn,
+			ContractFn: &xdr.InvokeContractArgs{
+				ContractAddress: xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeContract, ContractId: &contractId},
+				FunctionName:    xdr.ScSymbol(name),
+				Args:            args,
+			},
+		}
+	}
+
+	auth := []xdr.SorobanAuthorizationEntry{
+		{
+			Credentials: xdr.SorobanCredentials{Type: xdr.SorobanCredentialsTypeSorobanCredentialsSourceAccount},
+			RootInvocation: xdr.SorobanAuthorizedInvocation{
+				Function: contractFn(root, "swap", scU32(1)),
+				SubInvocations: []xdr.SorobanAuthorizedInvocation{
+					{
+						Function: contractFn(token, "transfer", scI128(5)),
+						SubInvocations: []xdr.SorobanAuthorizedInvocation{
+							{Function: xdr.SorobanAuthorizedFunction{
+								Type: xdr.SorobanAuthorizedFunctionTypeSorobanAuthorizedFunctionTypeCreateContractV2HostFn,
+								CreateContractV2HostFn: &xdr.CreateContractArgsV2{
+									ContractIdPreimage: xdr.ContractIdPreimage{
+										Type: xdr.ContractIdPreimageTypeContractIdPreimageFromAddress,
+										FromAddress: &xdr.ContractIdPreimageFromAddress{
+											Address: xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeAccount, AccountId: &signerAccount},
+										},
+									},
+									Executable: xdr.ContractExecutable{Type: xdr.ContractExecutableTypeContractExecutableWasm, WasmHash: &wasmHash},
+								},
+							}},
+						},
+					},
+					{Function: contractFn(token, "burn")},
+				},
+			},
+		},
+		{
+			Credentials: xdr.SorobanCredentials{
+				Type: xdr.SorobanCredentialsTypeSorobanCredentialsAddress,
+				Address: &xdr.SorobanAddressCredentials{
+					Address:                   xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeAccount, AccountId: &signerAccount},
+					Nonce:                     42,
+					SignatureExpirationLedger: 100,
+					Signature:                 scVec(),
+				},
+			},
+			RootInvocation: xdr.SorobanAuthorizedInvocation{Function: contractFn(token, "transfer", scI128(5))},
+		},
+	}
+
+	transaction, lhe := makeContractInvocationTestInput(t, nil)
+	transaction.Envelope.V1.Tx.Operations[0].Body.InvokeHostFunctionOp.Auth = auth
+	output, err := TransformSorobanAuthEntries(transaction, lhe)
+	assert.NoError(t, err)
+	assert.Len(t, output, 2)
+
+	rootAddress := strkey.MustEncode(strkey.VersionByteContract, root[:])
+	tokenAddress := strkey.MustEncode(strkey.VersionByteContract, token[:])
+	u32Arg, i128Arg := []interface{}{"AAAAAwAAAAE="}, []interface{}{"AAAACgAAAAAAAAAAAAAAAAAAAAU="}
+	expectedInvocations := [][]SorobanAuthInvocationOutput{
+		{
+			{InvocationIndex: 0, Depth: 0, FunctionType: "contract_fn", ContractId: rootAddress, FunctionName: "swap", Args: u32Arg},
+			{InvocationIndex: 1, ParentIndex: null.IntFrom(0), Depth: 1, FunctionType: "contract_fn", ContractId: tokenAddress, FunctionName: "transfer", Args: i128Arg},
+			{InvocationIndex: 2, ParentIndex: null.IntFrom(1), Depth: 2, FunctionType: "create_contract_v2", Args: []interface{}{}, WasmHash: wasmHash.HexString()},
+			{InvocationIndex: 3, ParentIndex: null.IntFrom(0), Depth: 1, FunctionType: "contract_fn", ContractId: tokenAddress, FunctionName: "burn", Args: []interface{}{}},
+		},
+		{
+			{InvocationIndex: 0, Depth: 0, FunctionType: "contract_fn", ContractId: tokenAddress, FunctionName: "transfer", Args: i128Arg},
+		},
+	}
+	for i, entry := range output {
+		for j := range entry.Invocations {
+			entry.Invocations[j].ArgsDecoded = nil
+		}
+		assert.Equal(t, expectedInvocations[i], entry.Invocations)
+		assert.Equal(t, uint32(i), entry.AuthEntryIndex)
+		assert.Equal(t, int64(42949677057), entry.OperationID)
+		assert.Equal(t, expectedInvocations[i][0].ContractId, entry.RootContractId)
+		assert.Equal(t, expectedInvocations[i][0].FunctionName, entry.RootFunctionName)
+		assert.Equal(t, "contract_fn", entry.RootFunctionType)
+	}
+
+	assert.Equal(t, "source_account", output[0].CredentialsType)
+	assert.Equal(t, source, output[0].Address)
+	assert.False(t, output[0].Nonce.Valid)
+	assert.Nil(t, output[0].Signature)
+
+	assert.Equal(t, "address", output[1].CredentialsType)
+	assert.Equal(t, signer, output[1].Address)
+	assert.Equal(t, null.IntFrom(42), output[1].Nonce)
+	assert.Equal(t, null.IntFrom(100), output[1].SignatureExpirationLedger)
+	assert.Equal(t, "AAAAEAAAAAEAAAAA", output[1].Signature)
+}
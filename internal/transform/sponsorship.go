@@ -0,0 +1,154 @@
+package transform
+
+import (
+	"sort"
+
+	"github.com/guregu/null"
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-etl/v2/internal/toid"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+)
+
+// sponsoredEntry is a ledger entry, or a signer of an account entry, whose reserves can be paid by a sponsor
+type sponsoredEntry struct {
+	entryType   string
+	sponsored   string
+	signer      null.String
+	numReserves int64
+	sponsor     null.String
+}
+
+// TransformSponsorships returns the changes of the sponsors of the reserve-bearing entries changed by a successful
+// transaction, which are accounts, trustlines, offers, data entries, claimable balances and account signers. A sponsor
+// begins paying the reserves of an entry when a sponsored entry is created or a sponsorship is added, ends when the
+// entry is removed or the sponsorship revoked, and transfers them when revoke_sponsorship moves them to another sponsor.
+func TransformSponsorships(transaction ingest.LedgerTransaction, lhe xdr.LedgerHeaderHistoryEntry) ([]SponsorshipChangeOutput, error) {
+	if !transaction.Result.Successful() {
+		return nil, nil
+	}
+	closedAt, err := utils.TimePointToUTCTimeStamp(lhe.Header.ScpValue.CloseTime)
+	if err != nil {
+		return nil, err
+	}
+	ledgerSequence := uint32(lhe.Header.LedgerSeq)
+	transactionHash := utils.HashToHexString(transaction.Result.TransactionHash)
+
+	outputs := []SponsorshipChangeOutput{}
+	for index, operation := range transaction.Envelope.Operations() {
+		operationType, err := mapOperationType(operation)
+		if err != nil {
+			return nil, err
+		}
+		changes, err := transaction.GetOperationChanges(uint32(index))
+		if err != nil {
+			return nil, err
+		}
+		for _, change := range changes {
+			pre, err := sponsoredEntries(change.Pre)
+			if err != nil {
+				return nil, err
+			}
+			post, err := sponsoredEntries(change.Post)
+			if err != nil {
+				return nil, err
+			}
+			var ledgerKeyHash string
+			if change.Post != nil {
+				ledgerKeyHash = utils.LedgerEntryToLedgerKeyHash(*change.Post)
+			} else {
+				ledgerKeyHash = utils.LedgerEntryToLedgerKeyHash(*change.Pre)
+			}
+
+			keys := make([]string, 0, len(pre)+len(post))
+			for key := range pre {
+				keys = append(keys, key)
+			}
+			for key := range post {
+				if _, ok := pre[key]; !ok {
+					keys = append(keys, key)
+				}
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				before, after := pre[key], post[key]
+				if before.sponsor == after.sponsor {
+					continue
+				}
+				entry := after
+				if entry.entryType == "" {
+					entry = before
+				}
+				output := SponsorshipChangeOutput{
+					LedgerSequence:  ledgerSequence,
+					ClosedAt:        closedAt,
+					TransactionHash: transactionHash,
+					OperationID:     toid.New(int32(ledgerSequence), int32(transaction.Index), int32(index)+1).ToInt64(),
+					OperationType:   operationType,
+					EntryType:       entry.entryType,
+					LedgerKeyHash:   ledgerKeyHash,
+					Sponsored:       entry.sponsored,
+					Signer:          entry.signer,
+					Sponsor:         after.sponsor,
+					PreviousSponsor: before.sponsor,
+					NumReserves:     entry.numReserves,
+					Revoked:         operation.Body.Type == xdr.OperationTypeRevokeSponsorship,
+				}
+				switch {
+				case !before.sponsor.Valid:
+					output.EventType = "begin"
+				case !after.sponsor.Valid:
+					output.EventType = "end"
+				default:
+					output.EventType = "transfer"
+				}
+				outputs = append(outputs, output)
+			}
+		}
+	}
+	return outputs, nil
+}
+
+// sponsoredEntries returns the reserve-bearing entries held in a ledger entry, keyed by signer for the signers of an
+// account entry, along with their sponsors. An entry that does not exist holds none.
+func sponsoredEntries(entry *xdr.LedgerEntry) (map[string]sponsoredEntry, error) {
+	entries := map[string]sponsoredEntry{}
+	if entry == nil {
+		return entries, nil
+	}
+	sponsor := ledgerEntrySponsorToNullString(*entry)
+	switch entry.Data.Type {
+	case xdr.LedgerEntryTypeAccount:
+		account := entry.Data.MustAccount()
+		address := account.AccountId.Address()
+		entries[""] = sponsoredEntry{entryType: "account", sponsored: address, numReserves: 2, sponsor: sponsor}
+		sponsors := account.SponsorPerSigner()
+		for _, signer := range account.Signers {
+			key := signer.Key.Address()
+			var signerSponsor null.String
+			if id, ok := sponsors[key]; ok {
+				signerSponsor = null.StringFrom(id.Address())
+			}
+			entries[key] = sponsoredEntry{entryType: "signer", sponsored: address, signer: null.StringFrom(key), numReserves: 1, sponsor: signerSponsor}
+		}
+	case xdr.LedgerEntryTypeTrustline:
+		trustline := entry.Data.MustTrustLine()
+		numReserves := int64(1)
+		if trustline.Asset.Type == xdr.AssetTypeAssetTypePoolShare {
+			numReserves = 2
+		}
+		entries[""] = sponsoredEntry{entryType: "trustline", sponsored: trustline.AccountId.Address(), numReserves: numReserves, sponsor: sponsor}
+	case xdr.LedgerEntryTypeOffer:
+		entries[""] = sponsoredEntry{entryType: "offer", sponsored: entry.Data.MustOffer().SellerId.Address(), numReserves: 1, sponsor: sponsor}
+	case xdr.LedgerEntryTypeData:
+		entries[""] = sponsoredEntry{entryType: "data", sponsored: entry.Data.MustData().AccountId.Address(), numReserves: 1, sponsor: sponsor}
+	case xdr.LedgerEntryTypeClaimableBalance:
+		balance := entry.Data.MustClaimableBalance()
+		balanceID, err := xdr.MarshalHex(balance.BalanceId)
+		if err != nil {
+			return nil, err
+		}
+		entries[""] = sponsoredEntry{entryType: "claimable_balance", sponsored: balanceID, numReserves: int64(len(balance.Claimants)), sponsor: sponsor}
+	}
+	return entries, nil
+}
@@ -0,0 +1,91 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/guregu/null"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func makeSponsorshipTestAccount(sponsor, signerSponsor *xdr.AccountId) *xdr.LedgerEntry {
+	return &xdr.LedgerEntry{
+		Data: xdr.LedgerEntryData{Type: xdr.LedgerEntryTypeAccount, Account: &xdr.AccountEntry{
+			AccountId: testAccount2ID,
+			Signers:   []xdr.Signer{{Key: xdr.MustSigner(testAccount1Address), Weight: 1}},
+			Ext: xdr.AccountEntryExt{V: 1, V1: &xdr.AccountEntryExtensionV1{Ext: xdr.AccountEntryExtensionV1Ext{V: 2, V2: &xdr.AccountEntryExtensionV2{
+				NumSponsored:        2,
+				SignerSponsoringIDs: []xdr.SponsorshipDescriptor{signerSponsor},
+			}}}},
+		}},
+		Ext: xdr.LedgerEntryExt{V: 1, V1: &xdr.LedgerEntryExtensionV1{SponsoringId: sponsor}},
+	}
+}
+
+func TestTransformSponsorships(t *testing.T) {
+	sponsor, newSponsor := testAccount3ID, testAccount4ID
+	revoke := makeAccountLifecycleTestInput(testAccount3, xdr.Operation{Body: xdr.OperationBody{
+		Type: xdr.OperationTypeRevokeSponsorship,
+		RevokeSponsorshipOp: &xdr.RevokeSponsorshipOp{
+			Type:      xdr.RevokeSponsorshipTypeRevokeSponsorshipLedgerEntry,
+			LedgerKey: &xdr.LedgerKey{Type: xdr.LedgerEntryTypeAccount, Account: &xdr.LedgerKeyAccount{AccountId: testAccount2ID}},
+		},
+	}}, xdr.LedgerEntryChanges{
+		{Type: xdr.LedgerEntryChangeTypeLedgerEntryState, State: makeSponsorshipTestAccount(&sponsor, &sponsor)},
+		{Type: xdr.LedgerEntryChangeTypeLedgerEntryUpdated, Updated: makeSponsorshipTestAccount(&newSponsor, nil)},
+	})
+	lhe := xdr.LedgerHeaderHistoryEntry{Header: xdr.LedgerHeader{LedgerSeq: 10, ScpValue: xdr.StellarValue{CloseTime: 1000}}}
+	closedAt, _ := utils.TimePointToUTCTimeStamp(1000)
+
+	sponsorships, err := TransformSponsorships(revoke, lhe)
+	assert.NoError(t, err)
+	row := SponsorshipChangeOutput{
+		LedgerSequence:  10,
+		ClosedAt:        closedAt,
+		TransactionHash: utils.HashToHexString(revoke.Result.TransactionHash),
+		OperationID:     42949677057,
+		OperationType:   "revoke_sponsorship",
+		EventType:       "transfer",
+		EntryType:       "account",
+		LedgerKeyHash:   utils.LedgerEntryToLedgerKeyHash(*makeSponsorshipTestAccount(nil, nil)),
+		Sponsored:       testAccount2Address,
+		Sponsor:         null.StringFrom(testAccount4Address),
+		PreviousSponsor: null.StringFrom(testAccount3Address),
+		NumReserves:     2,
+		Revoked:         true,
+	}
+	signerRow := row
+	signerRow.EventType = "end"
+	signerRow.EntryType = "signer"
+	signerRow.Signer = null.StringFrom(testAccount1Address)
+	signerRow.Sponsor = null.String{}
+	signerRow.NumReserves = 1
+	assert.Equal(t, []SponsorshipChangeOutput{row, signerRow}, sponsorships)
+
+	// Claimable balances are always sponsored, with one reserve per claimant
+	balance := &xdr.LedgerEntry{
+		Data: xdr.LedgerEntryData{Type: xdr.LedgerEntryTypeClaimableBalance, ClaimableBalance: &xdr.ClaimableBalanceEntry{
+			BalanceId: xdr.ClaimableBalanceId{Type: xdr.ClaimableBalanceIdTypeClaimableBalanceIdTypeV0, V0: &xdr.Hash{1}},
+			Claimants: []xdr.Claimant{
+				{Type: xdr.ClaimantTypeClaimantTypeV0, V0: &xdr.ClaimantV0{Destination: testAccount1ID, Predicate: xdr.ClaimPredicate{Type: xdr.ClaimPredicateTypeClaimPredicateUnconditional}}},
+				{Type: xdr.ClaimantTypeClaimantTypeV0, V0: &xdr.ClaimantV0{Destination: testAccount2ID, Predicate: xdr.ClaimPredicate{Type: xdr.ClaimPredicateTypeClaimPredicateUnconditional}}},
+			},
+			Asset:  nativeAsset,
+			Amount: 10,
+		}},
+		Ext: xdr.LedgerEntryExt{V: 1, V1: &xdr.LedgerEntryExtensionV1{SponsoringId: &sponsor}},
+	}
+	create := makeAccountLifecycleTestInput(testAccount3, xdr.Operation{Body: xdr.OperationBody{
+		Type:                     xdr.OperationTypeCreateClaimableBalance,
+		CreateClaimableBalanceOp: &xdr.CreateClaimableBalanceOp{Asset: nativeAsset, Amount: 10},
+	}}, xdr.LedgerEntryChanges{{Type: xdr.LedgerEntryChangeTypeLedgerEntryCreated, Created: balance}})
+	sponsorships, err = TransformSponsorships(create, lhe)
+	assert.NoError(t, err)
+	assert.Len(t, sponsorships, 1)
+	assert.Equal(t, "begin", sponsorships[0].EventType)
+	assert.Equal(t, "claimable_balance", sponsorships[0].EntryType)
+	assert.Equal(t, "000000000100000000000000000000000000000000000000000000000000000000000000", sponsorships[0].Sponsored)
+	assert.Equal(t, int64(2), sponsorships[0].NumReserves)
+	assert.False(t, sponsorships[0].Revoked)
+}
@@ -0,0 +1,202 @@
+package transform
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/guregu/null"
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+)
+
+var (
+	// tokenMetadataSym is the instance storage key of the token metadata written by the soroban-token-sdk, which is
+	// also used by the Stellar Asset Contract
+	// https://github.com/stellar/rs-soroban-sdk/blob/v22.0.0/soroban-token-sdk/src/metadata.rs
+	tokenMetadataSym = xdr.ScSymbol("METADATA")
+	tokenAdminSym    = xdr.ScSymbol("Admin")
+
+	// standardTokenEvents are the SEP-41 events that move balances
+	standardTokenEvents = map[xdr.ScSymbol]bool{"transfer": true, "mint": true, "burn": true, "clawback": true}
+)
+
+// TransformToken converts a contract instance into a token if it is the instance of a Stellar Asset Contract, or keeps
+// SEP-41 metadata (decimal, name and symbol) in its instance storage. The boolean is false for other contracts.
+func TransformToken(ledgerEntry xdr.LedgerEntry, passphrase string, header xdr.LedgerHeaderHistoryEntry) (TokenOutput, bool, error) {
+	instance, contractId, ok := contractInstanceFromEntry(ledgerEntry)
+	if !ok {
+		return TokenOutput{}, false, nil
+	}
+
+	closedAt, err := utils.TimePointToUTCTimeStamp(header.Header.ScpValue.CloseTime)
+	if err != nil {
+		return TokenOutput{}, false, err
+	}
+	ledgerSequence := uint32(header.Header.LedgerSeq)
+
+	token := TokenOutput{
+		ContractId:         contractId,
+		TokenType:          "sep41",
+		FirstSeenLedger:    ledgerSequence,
+		LastModifiedLedger: uint32(ledgerEntry.LastModifiedLedgerSeq),
+		LedgerSequence:     ledgerSequence,
+		ClosedAt:           closedAt,
+	}
+
+	hasMetadata := false
+	if instance.Storage != nil {
+		for _, entry := range *instance.Storage {
+			switch {
+			case entry.Key.Equals(xdr.ScVal{Type: xdr.ScValTypeScvSymbol, Sym: &tokenMetadataSym}):
+				hasMetadata = addTokenMetadata(&token, entry.Val)
+			case isTokenAdminKey(entry.Key):
+				if address, ok := entry.Val.GetAddress(); ok {
+					token.Admin, err = address.String()
+					if err != nil {
+						return TokenOutput{}, false, err
+					}
+				}
+			}
+		}
+	}
+
+	if instance.Executable.Type == xdr.ContractExecutableTypeContractExecutableStellarAsset {
+		token.TokenType = "sac"
+		if asset := AssetFromContractData(ledgerEntry, passphrase); asset != nil {
+			if err := asset.Extract(&token.AssetType, &token.AssetCode, &token.AssetIssuer); err != nil {
+				return TokenOutput{}, false, err
+			}
+			token.AssetCode = strings.ReplaceAll(token.AssetCode, "\x00", "")
+		}
+		return token, true, nil
+	}
+
+	return token, hasMetadata, nil
+}
+
+// addTokenMetadata sets the name, symbol and decimals of token from the METADATA map and reports whether it was valid
+func addTokenMetadata(token *TokenOutput, metadata xdr.ScVal) bool {
+	entries, ok := metadata.GetMap()
+	if !ok || entries == nil {
+		return false
+	}
+
+	found := 0
+	for _, entry := range *entries {
+		key, ok := entry.Key.GetSym()
+		if !ok {
+			continue
+		}
+		switch key {
+		case "decimal":
+			if decimals, ok := entry.Val.GetU32(); ok {
+				token.Decimals = null.IntFrom(int64(decimals))
+				found++
+			}
+		case "name":
+			if name, ok := entry.Val.GetStr(); ok {
+				token.Name = string(name)
+				found++
+			}
+		case "symbol":
+			if symbol, ok := entry.Val.GetStr(); ok {
+				token.Symbol = string(symbol)
+				found++
+			}
+		}
+	}
+	return found == 3
+}
+
+// isTokenAdminKey matches the instance storage key of the token administrator, either the Admin variant of a DataKey
+// enum as written by the Stellar Asset Contract and the example token, or a plain Admin symbol
+func isTokenAdminKey(key xdr.ScVal) bool {
+	if sym, ok := key.GetSym(); ok {
+		return sym == tokenAdminSym
+	}
+	vec, ok := key.GetVec()
+	if !ok || vec == nil || len(*vec) != 1 {
+		return false
+	}
+	sym, ok := (*vec)[0].GetSym()
+	return ok && sym == tokenAdminSym
+}
+
+// IsStandardTokenEvent reports whether event is a SEP-41 transfer, mint, burn or clawback event, whose data is the
+// amount moved or a map holding it
+func IsStandardTokenEvent(event xdr.ContractEvent) bool {
+	if event.Type != xdr.ContractEventTypeContract || event.ContractId == nil {
+		return false
+	}
+	body, ok := event.Body.GetV0()
+	if !ok || len(body.Topics) < 2 {
+		return false
+	}
+	name, ok := body.Topics[0].GetSym()
+	if !ok || !standardTokenEvents[name] {
+		return false
+	}
+
+	if _, ok := body.Data.GetI128(); ok {
+		return true
+	}
+	if entries, ok := body.Data.GetMap(); ok && entries != nil {
+		for _, entry := range *entries {
+			if key, ok := entry.Key.GetSym(); ok && key == "amount" {
+				_, ok := entry.Val.GetI128()
+				return ok
+			}
+		}
+	}
+	return false
+}
+
+// TransformTokenEvent converts a standard token event of a contract that is not in the registry into a SEP-41 token
+// without metadata, so contracts that move balances the SEP-41 way are registered whether or not their instance keeps
+// the soroban-token-sdk metadata
+func TransformTokenEvent(event xdr.ContractEvent, header xdr.LedgerHeaderHistoryEntry) (TokenOutput, error) {
+	closedAt, err := utils.TimePointToUTCTimeStamp(header.Header.ScpValue.CloseTime)
+	if err != nil {
+		return TokenOutput{}, err
+	}
+	ledgerSequence := uint32(header.Header.LedgerSeq)
+	return TokenOutput{
+		ContractId:          TokenEventContractId(event),
+		TokenType:           "sep41",
+		FirstSeenLedger:     ledgerSequence,
+		EmitsStandardEvents: true,
+		LedgerSequence:      ledgerSequence,
+		ClosedAt:            closedAt,
+	}, nil
+}
+
+// TokenEventContractId is the strkey of the contract that emitted event
+func TokenEventContractId(event xdr.ContractEvent) string {
+	if event.ContractId == nil {
+		return ""
+	}
+	return strkey.MustEncode(strkey.VersionByteContract, event.ContractId[:])
+}
+
+// LoadTokens reads the JSON lines written by export_tokens, keeping the last row of every contract
+func LoadTokens(in io.Reader) (map[string]TokenOutput, error) {
+	tokens := map[string]TokenOutput{}
+	err := readJSONLines(in, func(line []byte) error {
+		var token TokenOutput
+		if err := json.Unmarshal(line, &token); err != nil {
+			return err
+		}
+		if token.ContractId == "" {
+			return fmt.Errorf("token without a contract_id")
+		}
+		tokens[token.ContractId] = token
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
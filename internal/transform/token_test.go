@@ -0,0 +1,150 @@
+package transform
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/guregu/null"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/stellar/go-stellar-sdk/network"
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+)
+
+func scString(s string) xdr.ScVal {
+	str := xdr.ScString(s)
+	return xdr.ScVal{Type: xdr.ScValTypeScvString, Str: &str}
+}
+
+func makeTokenTestEntry(contractId xdr.ContractId, executable xdr.ContractExecutable, storage ...xdr.ScMapEntry) xdr.LedgerEntry {
+	entry := makeContractInstanceTestEntry(contractId, executable)
+	instanceStorage := xdr.ScMap(storage)
+	entry.Data.ContractData.Val.Instance.Storage = &instanceStorage
+	entry.LastModifiedLedgerSeq = 5
+	return entry
+}
+
+func makeTokenTestMetadata(name, symbol string, decimals uint32) xdr.ScMapEntry {
+	return xdr.ScMapEntry{
+		Key: scSymbol("METADATA"),
+		Val: scMap(
+			xdr.ScMapEntry{Key: scSymbol("decimal"), Val: scU32(decimals)},
+			xdr.ScMapEntry{Key: scSymbol("name"), Val: scString(name)},
+			xdr.ScMapEntry{Key: scSymbol("symbol"), Val: scString(symbol)},
+		),
+	}
+}
+
+func TestTransformToken(t *testing.T) {
+	issuer := "GAOEOQMXDDXPVJC3HDFX6LZFKANJ4OOLQOD2MNXJ7PGAY5FEO4BRRAQU"
+	admin := "GBVVRXLMNCJQW3IDDXC3X6XCH35B5Q7QXNMMFPENSOGUPQO7WO7HGZPA"
+	header := xdr.LedgerHeaderHistoryEntry{Header: xdr.LedgerHeader{LedgerSeq: 10, ScpValue: xdr.StellarValue{CloseTime: 1000}}}
+	closedAt, _ := utils.TimePointToUTCTimeStamp(header.Header.ScpValue.CloseTime)
+	adminEntry := xdr.ScMapEntry{Key: scVec(scSymbol("Admin")), Val: scAccount(t, admin)}
+
+	asset := xdr.MustNewCreditAsset("USDC", issuer)
+	sacId, err := asset.ContractID(network.TestNetworkPassphrase)
+	assert.NoError(t, err)
+	issuerBytes := strkey.MustDecode(strkey.VersionByteAccountID, issuer)
+	sac := makeTokenTestEntry(sacId, xdr.ContractExecutable{Type: xdr.ContractExecutableTypeContractExecutableStellarAsset},
+		xdr.ScMapEntry{Key: assetInfoKey, Val: scVec(scSymbol("AlphaNum4"), scMap(
+			xdr.ScMapEntry{Key: scSymbol("asset_code"), Val: scString("USDC")},
+			xdr.ScMapEntry{Key: scSymbol("issuer"), Val: xdr.ScVal{Type: xdr.ScValTypeScvBytes, Bytes: (*xdr.ScBytes)(&issuerBytes)}},
+		))},
+		makeTokenTestMetadata("USDC:"+issuer, "USDC", 7),
+		adminEntry,
+	)
+
+	var wasmHash xdr.Hash
+	var customId, otherId xdr.ContractId
+	customId[0], otherId[0] = 1, 2
+	wasm := xdr.ContractExecutable{Type: xdr.ContractExecutableTypeContractExecutableWasm, WasmHash: &wasmHash}
+	custom := makeTokenTestEntry(customId, wasm, makeTokenTestMetadata("Custom Token", "CTK", 6), adminEntry)
+	other := makeTokenTestEntry(otherId, wasm, xdr.ScMapEntry{Key: scSymbol("counter"), Val: scU32(1)})
+
+	token, ok, err := TransformToken(sac, network.TestNetworkPassphrase, header)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, TokenOutput{
+		ContractId:         strkey.MustEncode(strkey.VersionByteContract, sacId[:]),
+		TokenType:          "sac",
+		Name:               "USDC:" + issuer,
+		Symbol:             "USDC",
+		Decimals:           null.IntFrom(7),
+		Admin:              admin,
+		AssetCode:          "USDC",
+		AssetIssuer:        issuer,
+		AssetType:          "credit_alphanum4",
+		FirstSeenLedger:    10,
+		LastModifiedLedger: 5,
+		LedgerSequence:     10,
+		ClosedAt:           closedAt,
+	}, token)
+
+	token, ok, err = TransformToken(custom, network.TestNetworkPassphrase, header)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, TokenOutput{
+		ContractId:         strkey.MustEncode(strkey.VersionByteContract, customId[:]),
+		TokenType:          "sep41",
+		Name:               "Custom Token",
+		Symbol:             "CTK",
+		Decimals:           null.IntFrom(6),
+		Admin:              admin,
+		FirstSeenLedger:    10,
+		LastModifiedLedger: 5,
+		LedgerSequence:     10,
+		ClosedAt:           closedAt,
+	}, token)
+
+	_, ok, err = TransformToken(other, network.TestNetworkPassphrase, header)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestIsStandardTokenEvent(t *testing.T) {
+	var contractId xdr.ContractId
+	account := "GAOEOQMXDDXPVJC3HDFX6LZFKANJ4OOLQOD2MNXJ7PGAY5FEO4BRRAQU"
+	event := func(data xdr.ScVal, topics ...xdr.ScVal) xdr.ContractEvent {
+		return xdr.ContractEvent{
+			ContractId: &contractId,
+			Type:       xdr.ContractEventTypeContract,
+			Body:       xdr.ContractEventBody{V: 0, V0: &xdr.ContractEventV0{Topics: topics, Data: data}},
+		}
+	}
+
+	assert.True(t, IsStandardTokenEvent(event(scI128(5), scSymbol("transfer"), scAccount(t, account), scAccount(t, account))))
+	assert.True(t, IsStandardTokenEvent(event(scMap(xdr.ScMapEntry{Key: scSymbol("amount"), Val: scI128(5)}), scSymbol("mint"), scAccount(t, account))))
+	assert.False(t, IsStandardTokenEvent(event(scU32(5), scSymbol("transfer"), scAccount(t, account))))
+	assert.False(t, IsStandardTokenEvent(event(scI128(5), scSymbol("swap"), scAccount(t, account))))
+}
+
+func TestTransformTokenEvent(t *testing.T) {
+	contractId := xdr.ContractId{1}
+	header := xdr.LedgerHeaderHistoryEntry{Header: xdr.LedgerHeader{LedgerSeq: 10, ScpValue: xdr.StellarValue{CloseTime: 1000}}}
+	closedAt, _ := utils.TimePointToUTCTimeStamp(header.Header.ScpValue.CloseTime)
+	event := xdr.ContractEvent{
+		ContractId: &contractId,
+		Type:       xdr.ContractEventTypeContract,
+		Body:       xdr.ContractEventBody{V: 0, V0: &xdr.ContractEventV0{Topics: []xdr.ScVal{scSymbol("burn"), scSymbol("from")}, Data: scI128(5)}},
+	}
+
+	token, err := TransformTokenEvent(event, header)
+	assert.NoError(t, err)
+	assert.Equal(t, TokenOutput{
+		ContractId:          strkey.MustEncode(strkey.VersionByteContract, contractId[:]),
+		TokenType:           "sep41",
+		FirstSeenLedger:     10,
+		EmitsStandardEvents: true,
+		LedgerSequence:      10,
+		ClosedAt:            closedAt,
+	}, token)
+}
+
+func TestLoadTokens(t *testing.T) {
+	tokens, err := LoadTokens(strings.NewReader(`{"contract_id":"CA","symbol":"A"}` + "\n" + `{"contract_id":"CA","symbol":"B"}` + "\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]TokenOutput{"CA": {ContractId: "CA", Symbol: "B"}}, tokens)
+}
@@ -12,9 +12,7 @@ import (
 )
 
 func TransformTokenTransfer(ledgerCloseMeta xdr.LedgerCloseMeta, networkPassphrase string) ([]TokenTransferOutput, error) {
-	eventsProcessor := token_transfer.NewEventsProcessorForUnifiedEvents(networkPassphrase)
-
-	events, err := eventsProcessor.EventsFromLedger(ledgerCloseMeta)
+	transformedTTP, err := TransformUnverifiedTokenTransfer(ledgerCloseMeta, networkPassphrase)
 	if err != nil {
 		return []TokenTransferOutput{}, err
 	}
@@ -24,14 +22,20 @@ func TransformTokenTransfer(ledgerCloseMeta xdr.LedgerCloseMeta, networkPassphra
 		return []TokenTransferOutput{}, err
 	}
 
-	var transformedTTP []TokenTransferOutput
+	return transformedTTP, nil
+}
+
+// TransformUnverifiedTokenTransfer returns the token transfer events of a ledger without checking that they agree with
+// the balance changes of the ledger, so that disagreements can be looked into
+func TransformUnverifiedTokenTransfer(ledgerCloseMeta xdr.LedgerCloseMeta, networkPassphrase string) ([]TokenTransferOutput, error) {
+	eventsProcessor := token_transfer.NewEventsProcessorForUnifiedEvents(networkPassphrase)
 
-	transformedTTP, err = transformEvents(events, ledgerCloseMeta)
+	events, err := eventsProcessor.EventsFromLedger(ledgerCloseMeta)
 	if err != nil {
 		return []TokenTransferOutput{}, err
 	}
 
-	return transformedTTP, nil
+	return transformEvents(events, ledgerCloseMeta)
 }
 
 func transformEvents(events []*token_transfer.TokenTransferEvent, ledgerCloseMeta xdr.LedgerCloseMeta) ([]TokenTransferOutput, error) {
@@ -0,0 +1,198 @@
+package transform
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/stellar/go-stellar-sdk/ingest"
+)
+
+// tradeAggregationResolutions maps the supported candle resolutions to their length in milliseconds
+var tradeAggregationResolutions = map[string]int64{
+	"1m":  int64(time.Minute / time.Millisecond),
+	"5m":  int64(5 * time.Minute / time.Millisecond),
+	"15m": int64(15 * time.Minute / time.Millisecond),
+	"1h":  int64(time.Hour / time.Millisecond),
+	"1d":  int64(24 * time.Hour / time.Millisecond),
+	"1w":  int64(7 * 24 * time.Hour / time.Millisecond),
+}
+
+// tradeCandle is a candle being aggregated. Volumes are kept in stroops and prices as exact rationals.
+type tradeCandle struct {
+	output        TradeAggregationOutput
+	resolution    int64
+	end           time.Time
+	baseVolume    *big.Int
+	counterVolume *big.Int
+	open          *big.Rat
+	high          *big.Rat
+	low           *big.Rat
+	close         *big.Rat
+}
+
+// TradeAggregator builds OHLCV candles of the trades it is given, per resolution, trade type and asset pair. The base
+// asset of a pair is the one with the lowest asset id, and prices are the counter amount per unit of base amount.
+type TradeAggregator struct {
+	resolutions map[string]int64
+	candles     map[string]*tradeCandle
+}
+
+// NewTradeAggregator returns an aggregator for the given resolutions, which must be 1m, 5m, 15m, 1h, 1d or 1w
+func NewTradeAggregator(resolutions []string) (*TradeAggregator, error) {
+	aggregator := &TradeAggregator{resolutions: map[string]int64{}, candles: map[string]*tradeCandle{}}
+	for _, resolution := range resolutions {
+		length, ok := tradeAggregationResolutions[resolution]
+		if !ok {
+			return nil, fmt.Errorf("unsupported resolution %s", resolution)
+		}
+		aggregator.resolutions[resolution] = length
+	}
+	if len(aggregator.resolutions) == 0 {
+		return nil, fmt.Errorf("no resolution given")
+	}
+	return aggregator, nil
+}
+
+// AddTrades adds the trades of an operation to the candles they fall in. The trades are read with TransformTrade,
+// while their amounts are taken from the claimed offers of the operation result to keep them exact.
+func (a *TradeAggregator) AddTrades(operationIndex int32, operationID int64, transaction ingest.LedgerTransaction, ledgerCloseTime time.Time) error {
+	trades, err := TransformTrade(operationIndex, operationID, transaction, ledgerCloseTime)
+	if err != nil {
+		return err
+	}
+	if len(trades) == 0 {
+		return nil
+	}
+	operationResults, _ := transaction.Result.OperationResults()
+	operation := transaction.Envelope.Operations()[operationIndex]
+	claimedOffers, _, _, err := extractClaimedOffers(operationResults, operationIndex, operation.Body.Type)
+	if err != nil {
+		return err
+	}
+
+	for _, trade := range trades {
+		claim := claimedOffers[trade.Order]
+		soldAmount, boughtAmount := int64(claim.AmountSold()), int64(claim.AmountBought())
+		// Trades that moved a single side have no price
+		if soldAmount == 0 || boughtAmount == 0 {
+			continue
+		}
+
+		candle := TradeAggregationOutput{
+			TradeType:          trade.TradeType,
+			BaseAssetType:      trade.SellingAssetType,
+			BaseAssetCode:      trade.SellingAssetCode,
+			BaseAssetIssuer:    trade.SellingAssetIssuer,
+			BaseAssetID:        trade.SellingAssetID,
+			CounterAssetType:   trade.BuyingAssetType,
+			CounterAssetCode:   trade.BuyingAssetCode,
+			CounterAssetIssuer: trade.BuyingAssetIssuer,
+			CounterAssetID:     trade.BuyingAssetID,
+		}
+		baseAmount, counterAmount := soldAmount, boughtAmount
+		if trade.BuyingAssetID < trade.SellingAssetID {
+			candle.BaseAssetType, candle.BaseAssetCode, candle.BaseAssetIssuer, candle.BaseAssetID = trade.BuyingAssetType, trade.BuyingAssetCode, trade.BuyingAssetIssuer, trade.BuyingAssetID
+			candle.CounterAssetType, candle.CounterAssetCode, candle.CounterAssetIssuer, candle.CounterAssetID = trade.SellingAssetType, trade.SellingAssetCode, trade.SellingAssetIssuer, trade.SellingAssetID
+			baseAmount, counterAmount = boughtAmount, soldAmount
+		}
+		price := big.NewRat(counterAmount, baseAmount)
+
+		for resolution, length := range a.resolutions {
+			start := ledgerCloseTime.UnixMilli() / length * length
+			key := fmt.Sprintf("%s/%d/%d/%d/%d", resolution, start, candle.TradeType, candle.BaseAssetID, candle.CounterAssetID)
+			current, ok := a.candles[key]
+			if !ok {
+				output := candle
+				output.Resolution = resolution
+				output.Timestamp = time.UnixMilli(start).UTC()
+				current = &tradeCandle{
+					output:        output,
+					resolution:    length,
+					end:           time.UnixMilli(start + length).UTC(),
+					baseVolume:    new(big.Int),
+					counterVolume: new(big.Int),
+					open:          price,
+					high:          price,
+					low:           price,
+				}
+				a.candles[key] = current
+			}
+			current.output.TradeCount++
+			current.baseVolume.Add(current.baseVolume, big.NewInt(baseAmount))
+			current.counterVolume.Add(current.counterVolume, big.NewInt(counterAmount))
+			if price.Cmp(current.high) > 0 {
+				current.high = price
+			}
+			if price.Cmp(current.low) < 0 {
+				current.low = price
+			}
+			current.close = price
+		}
+	}
+	return nil
+}
+
+// Closed removes and returns the candles that end at or before closeTime, which no later ledger can add trades to
+func (a *TradeAggregator) Closed(closeTime time.Time) []TradeAggregationOutput {
+	return a.take(func(candle *tradeCandle) bool { return !candle.end.After(closeTime) })
+}
+
+// LongestResolution is the length of the longest resolution of the aggregator. As every resolution divides it, no
+// candle spans the start of one of its buckets.
+func (a *TradeAggregator) LongestResolution() time.Duration {
+	longest := int64(0)
+	for _, length := range a.resolutions {
+		if length > longest {
+			longest = length
+		}
+	}
+	return time.Duration(longest) * time.Millisecond
+}
+
+func (a *TradeAggregator) take(done func(*tradeCandle) bool) []TradeAggregationOutput {
+	taken := []*tradeCandle{}
+	for key, candle := range a.candles {
+		if done(candle) {
+			taken = append(taken, candle)
+			delete(a.candles, key)
+		}
+	}
+	sort.Slice(taken, func(i, j int) bool {
+		x, y := taken[i].output, taken[j].output
+		switch {
+		case !x.Timestamp.Equal(y.Timestamp):
+			return x.Timestamp.Before(y.Timestamp)
+		case taken[i].resolution != taken[j].resolution:
+			return taken[i].resolution < taken[j].resolution
+		case x.TradeType != y.TradeType:
+			return x.TradeType < y.TradeType
+		case x.BaseAssetID != y.BaseAssetID:
+			return x.BaseAssetID < y.BaseAssetID
+		default:
+			return x.CounterAssetID < y.CounterAssetID
+		}
+	})
+
+	outputs := make([]TradeAggregationOutput, 0, len(taken))
+	for _, candle := range taken {
+		output := candle.output
+		output.BaseVolume, _ = new(big.Rat).SetFrac(candle.baseVolume, big.NewInt(10000000)).Float64()
+		output.CounterVolume, _ = new(big.Rat).SetFrac(candle.counterVolume, big.NewInt(10000000)).Float64()
+		output.Average, _ = new(big.Rat).SetFrac(candle.counterVolume, candle.baseVolume).Float64()
+		output.Open, output.OpenN, output.OpenD = ratParts(candle.open)
+		output.High, output.HighN, output.HighD = ratParts(candle.high)
+		output.Low, output.LowN, output.LowD = ratParts(candle.low)
+		output.Close, output.CloseN, output.CloseD = ratParts(candle.close)
+		outputs = append(outputs, output)
+	}
+	return outputs
+}
+
+// ratParts returns the value of a price along with its numerator and denominator, which fit in an int64 since the
+// price is the reduced ratio of two int64 amounts
+func ratParts(price *big.Rat) (float64, int64, int64) {
+	value, _ := price.Float64()
+	return value, price.Num().Int64(), price.Denom().Int64()
+}
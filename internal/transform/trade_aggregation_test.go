@@ -0,0 +1,58 @@
+package transform
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTradeAggregator(t *testing.T) {
+	_, err := NewTradeAggregator([]string{"2m"})
+	assert.Error(t, err)
+
+	aggregator, err := NewTradeAggregator([]string{"1m", "1h"})
+	assert.NoError(t, err)
+
+	// Operation 0 sells 13300347 ETH for 12634 USDT, operation 2 repeats that trade and sells 500 USDT for 20 XLM
+	transaction := makeTradeTestInput()
+	closeTime := time.Date(2024, time.January, 1, 10, 0, 10, 0, time.UTC)
+	assert.NoError(t, aggregator.AddTrades(0, 100, transaction, closeTime))
+	assert.NoError(t, aggregator.AddTrades(2, 100, transaction, closeTime.Add(30*time.Second)))
+
+	assert.Empty(t, aggregator.Closed(closeTime.Add(49*time.Second)))
+	candles := aggregator.Closed(closeTime.Add(50 * time.Second))
+	assert.Len(t, candles, 2)
+	for _, candle := range candles {
+		assert.Equal(t, "1m", candle.Resolution)
+		assert.Equal(t, time.Date(2024, time.January, 1, 10, 0, 0, 0, time.UTC), candle.Timestamp)
+		assert.Equal(t, int32(1), candle.TradeType)
+		assert.Less(t, candle.BaseAssetID, candle.CounterAssetID)
+	}
+
+	ethUsdt := candles[0]
+	if ethUsdt.TradeCount != 2 {
+		ethUsdt = candles[1]
+	}
+	assert.Equal(t, int64(2), ethUsdt.TradeCount)
+	ethAmount, usdtAmount := int64(13300347), int64(12634)
+	price := big.NewRat(usdtAmount, ethAmount)
+	baseVolume, counterVolume := 2*1.3300347, 2*0.0012634
+	if ethUsdt.BaseAssetCode == "USDT" {
+		price = big.NewRat(ethAmount, usdtAmount)
+		baseVolume, counterVolume = counterVolume, baseVolume
+	}
+	assert.InDelta(t, baseVolume, ethUsdt.BaseVolume, 1e-12)
+	assert.InDelta(t, counterVolume, ethUsdt.CounterVolume, 1e-12)
+	assert.Equal(t, price.Num().Int64(), ethUsdt.OpenN)
+	assert.Equal(t, price.Denom().Int64(), ethUsdt.OpenD)
+	assert.Equal(t, ethUsdt.OpenN, ethUsdt.CloseN)
+	assert.Equal(t, ethUsdt.OpenD, ethUsdt.HighD)
+
+	assert.Empty(t, aggregator.Closed(closeTime.Add(time.Hour-11*time.Second)))
+	candles = aggregator.Closed(closeTime.Add(time.Hour - 10*time.Second))
+	assert.Len(t, candles, 2)
+	assert.Equal(t, "1h", candles[0].Resolution)
+	assert.Equal(t, time.Hour, aggregator.LongestResolution())
+}
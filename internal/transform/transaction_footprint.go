@@ -0,0 +1,104 @@
+package transform
+
+import (
+	"fmt"
+
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-etl/v2/internal/toid"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+)
+
+// TransformTransactionFootprint converts the footprint of a Soroban transaction into one row per ledger key, marking
+// the keys that were restored or modified according to the transaction meta
+func TransformTransactionFootprint(transaction ingest.LedgerTransaction, lhe xdr.LedgerHeaderHistoryEntry) ([]TransactionFootprintOutput, error) {
+	ledgerSequence := uint32(lhe.Header.LedgerSeq)
+	transactionID := toid.New(int32(ledgerSequence), int32(transaction.Index), 0).ToInt64()
+
+	sorobanData, ok := getTransactionV1Envelope(transaction.Envelope).Tx.Ext.GetSorobanData()
+	if !ok {
+		return []TransactionFootprintOutput{}, fmt.Errorf("for ledger %d; transaction %d (transaction id=%d): not a soroban transaction", ledgerSequence, transaction.Index, transactionID)
+	}
+
+	closedAt, err := utils.TimePointToUTCTimeStamp(lhe.Header.ScpValue.CloseTime)
+	if err != nil {
+		return []TransactionFootprintOutput{}, fmt.Errorf("for ledger %d; transaction %d (transaction id=%d): %v", ledgerSequence, transaction.Index, transactionID, err)
+	}
+
+	var operationType string
+	if operations := transaction.Envelope.Operations(); len(operations) > 0 {
+		operationType, err = mapOperationType(operations[0])
+		if err != nil {
+			return []TransactionFootprintOutput{}, err
+		}
+	}
+
+	// The archived entries are the indexes of the read write keys that were restored automatically
+	autoRestored := map[uint32]bool{}
+	if sorobanData.Ext.ResourceExt != nil {
+		for _, index := range sorobanData.Ext.ResourceExt.ArchivedSorobanEntries {
+			autoRestored[uint32(index)] = true
+		}
+	}
+
+	// Only the changes of the operation are made by the host function; the transaction level changes, such as the
+	// sequence number bump and the fee charged to the source account, are not
+	changes, err := transaction.GetOperationChanges(0)
+	if err != nil {
+		return []TransactionFootprintOutput{}, err
+	}
+	restored := map[string]bool{}
+	modified := map[string]bool{}
+	for _, change := range changes {
+		key, err := change.LedgerKey()
+		if err != nil {
+			return []TransactionFootprintOutput{}, err
+		}
+		keyHash := utils.LedgerKeyToLedgerKeyHash(key)
+		switch change.ChangeType {
+		case xdr.LedgerEntryChangeTypeLedgerEntryRestored:
+			restored[keyHash] = true
+		case xdr.LedgerEntryChangeTypeLedgerEntryCreated, xdr.LedgerEntryChangeTypeLedgerEntryUpdated, xdr.LedgerEntryChangeTypeLedgerEntryRemoved:
+			modified[keyHash] = true
+		}
+	}
+
+	footprint := sorobanData.Resources.Footprint
+	transformedFootprint := make([]TransactionFootprintOutput, 0, len(footprint.ReadOnly)+len(footprint.ReadWrite))
+	for _, access := range []struct {
+		name string
+		keys []xdr.LedgerKey
+	}{
+		{"read_only", footprint.ReadOnly},
+		{"read_write", footprint.ReadWrite},
+	} {
+		for i, key := range access.keys {
+			keyHash := utils.LedgerKeyToLedgerKeyHash(key)
+			output := TransactionFootprintOutput{
+				TransactionHash:       utils.HashToHexString(transaction.Result.TransactionHash),
+				TransactionID:         transactionID,
+				LedgerSequence:        ledgerSequence,
+				ClosedAt:              closedAt,
+				OperationType:         operationType,
+				Access:                access.name,
+				FootprintIndex:        uint32(i),
+				LedgerKeyHash:         keyHash,
+				LedgerEntryType:       key.Type.String(),
+				AutoRestored:          access.name == "read_write" && autoRestored[uint32(i)],
+				Restored:              restored[keyHash],
+				Modified:              modified[keyHash],
+				TransactionSuccessful: transaction.Result.Successful(),
+			}
+			switch key.Type {
+			case xdr.LedgerEntryTypeContractData:
+				output.ContractId = contractIdFromContractData(key)
+				output.ContractDurability = key.ContractData.Durability.String()
+			case xdr.LedgerEntryTypeContractCode:
+				output.ContractCodeHash = key.ContractCode.Hash.HexString()
+			}
+			transformedFootprint = append(transformedFootprint, output)
+		}
+	}
+
+	return transformedFootprint, nil
+}
@@ -0,0 +1,105 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+)
+
+func TestTransformTransactionFootprint(t *testing.T) {
+	var contractHash, codeHash xdr.Hash
+	contractHash[0], codeHash[0] = 1, 2
+	contractId := xdr.ContractId(contractHash)
+	contract := xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeContract, ContractId: &contractId}
+
+	contractDataKey := func(key xdr.ScVal) xdr.LedgerKey {
+		return xdr.LedgerKey{
+			Type:         xdr.LedgerEntryTypeContractData,
+			ContractData: &xdr.LedgerKeyContractData{Contract: contract, Key: key, Durability: xdr.ContractDataDurabilityPersistent},
+		}
+	}
+	contractDataEntry := func(key xdr.ScVal, val xdr.ScVal) xdr.LedgerEntry {
+		return xdr.LedgerEntry{Data: xdr.LedgerEntryData{
+			Type:         xdr.LedgerEntryTypeContractData,
+			ContractData: &xdr.ContractDataEntry{Contract: contract, Key: key, Durability: xdr.ContractDataDurabilityPersistent, Val: val},
+		}}
+	}
+
+	codeKey := xdr.LedgerKey{Type: xdr.LedgerEntryTypeContractCode, ContractCode: &xdr.LedgerKeyContractCode{Hash: codeHash}}
+	balanceKey := contractDataKey(scSymbol("balance"))
+	archivedKey := contractDataKey(scSymbol("archived"))
+	before := contractDataEntry(scSymbol("balance"), scI128(1))
+	after := contractDataEntry(scSymbol("balance"), scI128(2))
+	restored := contractDataEntry(scSymbol("archived"), scU32(1))
+	accountKey := xdr.LedgerKey{Type: xdr.LedgerEntryTypeAccount, Account: &xdr.LedgerKeyAccount{AccountId: testAccount1ID}}
+	account := func(seqNum xdr.SequenceNumber) *xdr.LedgerEntry {
+		return &xdr.LedgerEntry{Data: xdr.LedgerEntryData{Type: xdr.LedgerEntryTypeAccount, Account: &xdr.AccountEntry{AccountId: testAccount1ID, SeqNum: seqNum}}}
+	}
+
+	transaction, lhe := makeContractInvocationTestInput(t, nil)
+	transaction.Envelope.V1.Tx.Ext = xdr.TransactionExt{
+		V: 1,
+		SorobanData: &xdr.SorobanTransactionData{
+			Ext: xdr.SorobanTransactionDataExt{V: 1, ResourceExt: &xdr.SorobanResourcesExtV0{ArchivedSorobanEntries: []xdr.Uint32{1}}},
+			Resources: xdr.SorobanResources{
+				Footprint: xdr.LedgerFootprint{
+					ReadOnly:  []xdr.LedgerKey{codeKey, accountKey},
+					ReadWrite: []xdr.LedgerKey{balanceKey, archivedKey},
+				},
+			},
+		},
+	}
+	// The sequence number bump is not a change of the host function
+	transaction.UnsafeMeta.V3.TxChangesBefore = xdr.LedgerEntryChanges{
+		{Type: xdr.LedgerEntryChangeTypeLedgerEntryState, State: account(1)},
+		{Type: xdr.LedgerEntryChangeTypeLedgerEntryUpdated, Updated: account(2)},
+	}
+	transaction.UnsafeMeta.V3.Operations = []xdr.OperationMeta{
+		{Changes: xdr.LedgerEntryChanges{
+			{Type: xdr.LedgerEntryChangeTypeLedgerEntryState, State: &before},
+			{Type: xdr.LedgerEntryChangeTypeLedgerEntryUpdated, Updated: &after},
+			{Type: xdr.LedgerEntryChangeTypeLedgerEntryRestored, Restored: &restored},
+		}},
+	}
+
+	output, err := TransformTransactionFootprint(transaction, lhe)
+	assert.NoError(t, err)
+
+	closedAt, _ := utils.TimePointToUTCTimeStamp(lhe.Header.ScpValue.CloseTime)
+	contractAddress := strkey.MustEncode(strkey.VersionByteContract, contractHash[:])
+	base := TransactionFootprintOutput{
+		TransactionHash:       utils.HashToHexString(transaction.Result.TransactionHash),
+		TransactionID:         42949677056,
+		LedgerSequence:        10,
+		ClosedAt:              closedAt,
+		OperationType:         "invoke_host_function",
+		TransactionSuccessful: true,
+	}
+	expected := []TransactionFootprintOutput{base, base, base, base}
+	expected[0].Access, expected[0].FootprintIndex = "read_only", 0
+	expected[0].LedgerKeyHash = utils.LedgerKeyToLedgerKeyHash(codeKey)
+	expected[0].LedgerEntryType = "LedgerEntryTypeContractCode"
+	expected[0].ContractCodeHash = codeHash.HexString()
+
+	expected[1].Access, expected[1].FootprintIndex = "read_only", 1
+	expected[1].LedgerKeyHash = utils.LedgerKeyToLedgerKeyHash(accountKey)
+	expected[1].LedgerEntryType = "LedgerEntryTypeAccount"
+
+	expected[2].Access, expected[2].FootprintIndex = "read_write", 0
+	expected[2].LedgerKeyHash = utils.LedgerKeyToLedgerKeyHash(balanceKey)
+	expected[2].LedgerEntryType = "LedgerEntryTypeContractData"
+	expected[2].ContractId, expected[2].ContractDurability = contractAddress, "ContractDataDurabilityPersistent"
+	expected[2].Modified = true
+
+	expected[3].Access, expected[3].FootprintIndex = "read_write", 1
+	expected[3].LedgerKeyHash = utils.LedgerKeyToLedgerKeyHash(archivedKey)
+	expected[3].LedgerEntryType = "LedgerEntryTypeContractData"
+	expected[3].ContractId, expected[3].ContractDurability = contractAddress, "ContractDataDurabilityPersistent"
+	expected[3].AutoRestored, expected[3].Restored = true, true
+
+	assert.Equal(t, expected, output)
+}
@@ -0,0 +1,121 @@
+package transform
+
+import (
+	"fmt"
+
+	"github.com/guregu/null"
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/xdr"
+	"github.com/stellar/stellar-etl/v2/internal/toid"
+	"github.com/stellar/stellar-etl/v2/internal/utils"
+)
+
+// TransformTtlExtensions converts the TTL changes of a Soroban transaction into one row per entry whose TTL was
+// extended, restored or created. Each row carries the rent fee charged to the whole transaction: the rent of a single
+// entry depends on its size, durability and the rent settings of the network, none of which the meta holds.
+func TransformTtlExtensions(transaction ingest.LedgerTransaction, lhe xdr.LedgerHeaderHistoryEntry) ([]TtlExtensionOutput, error) {
+	ledgerSequence := uint32(lhe.Header.LedgerSeq)
+	transactionID := toid.New(int32(ledgerSequence), int32(transaction.Index), 0).ToInt64()
+
+	sorobanData, ok := getTransactionV1Envelope(transaction.Envelope).Tx.Ext.GetSorobanData()
+	if !ok {
+		return []TtlExtensionOutput{}, fmt.Errorf("for ledger %d; transaction %d (transaction id=%d): not a soroban transaction", ledgerSequence, transaction.Index, transactionID)
+	}
+	if !transaction.Result.Successful() {
+		return []TtlExtensionOutput{}, nil
+	}
+
+	closedAt, err := utils.TimePointToUTCTimeStamp(lhe.Header.ScpValue.CloseTime)
+	if err != nil {
+		return []TtlExtensionOutput{}, fmt.Errorf("for ledger %d; transaction %d (transaction id=%d): %v", ledgerSequence, transaction.Index, transactionID, err)
+	}
+
+	var operationType string
+	if operations := transaction.Envelope.Operations(); len(operations) > 0 {
+		operationType, err = mapOperationType(operations[0])
+		if err != nil {
+			return []TtlExtensionOutput{}, err
+		}
+	}
+	extensionType := "implicit"
+	if operationType == "extend_footprint_ttl" || operationType == "restore_footprint" {
+		extensionType = operationType
+	}
+
+	feeAccount := transaction.Envelope.SourceAccount().ToAccountId()
+	if transaction.Envelope.IsFeeBump() {
+		feeAccount = transaction.Envelope.FeeBumpAccount().ToAccountId()
+	}
+
+	// The key hash of a TTL entry is the hash of the key of the entry it belongs to, which is in the footprint
+	footprintKeys := map[string]xdr.LedgerKey{}
+	for _, key := range append(sorobanData.Resources.Footprint.ReadOnly, sorobanData.Resources.Footprint.ReadWrite...) {
+		footprintKeys[utils.LedgerKeyToLedgerKeyHash(key)] = key
+	}
+
+	changes, err := transaction.GetChanges()
+	if err != nil {
+		return []TtlExtensionOutput{}, err
+	}
+	_, _, rentFee := sorobanFeesCharged(transaction.UnsafeMeta)
+
+	transformedExtensions := []TtlExtensionOutput{}
+	for _, change := range changes {
+		if change.Type != xdr.LedgerEntryTypeTtl || change.Post == nil {
+			continue
+		}
+		newTtl := change.Post.Data.MustTtl()
+		output := TtlExtensionOutput{
+			TransactionHash:       utils.HashToHexString(transaction.Result.TransactionHash),
+			TransactionID:         transactionID,
+			OperationID:           toid.New(int32(ledgerSequence), int32(transaction.Index), 1).ToInt64(),
+			LedgerSequence:        ledgerSequence,
+			ClosedAt:              closedAt,
+			FeeAccount:            feeAccount.Address(),
+			OperationType:         operationType,
+			ExtensionType:         extensionType,
+			LedgerKeyHash:         newTtl.KeyHash.HexString(),
+			NewLiveUntilLedgerSeq: uint32(newTtl.LiveUntilLedgerSeq),
+			RentFeeCharged:        rentFee,
+		}
+
+		// The TTL bought by a restoration or creation starts at the current ledger
+		startLedger := ledgerSequence
+		switch {
+		case change.ChangeType == xdr.LedgerEntryChangeTypeLedgerEntryRestored:
+			output.EventType = "restored"
+		case change.Pre == nil:
+			output.EventType = "created"
+		default:
+			oldLiveUntil := uint32(change.Pre.Data.MustTtl().LiveUntilLedgerSeq)
+			if oldLiveUntil >= output.NewLiveUntilLedgerSeq {
+				continue
+			}
+			output.OldLiveUntilLedgerSeq = null.IntFrom(int64(oldLiveUntil))
+			if oldLiveUntil < ledgerSequence {
+				// Entries restored before protocol 23 kept their expired TTL entry
+				output.EventType = "restored"
+			} else {
+				output.EventType = "extended"
+				startLedger = oldLiveUntil + 1
+			}
+		}
+		if output.NewLiveUntilLedgerSeq >= startLedger {
+			output.LedgersExtended = output.NewLiveUntilLedgerSeq - startLedger + 1
+		}
+
+		if key, ok := footprintKeys[output.LedgerKeyHash]; ok {
+			output.LedgerEntryType = key.Type.String()
+			switch key.Type {
+			case xdr.LedgerEntryTypeContractData:
+				output.ContractId = contractIdFromContractData(key)
+				output.ContractDurability = key.ContractData.Durability.String()
+			case xdr.LedgerEntryTypeContractCode:
+				output.ContractCodeHash = key.ContractCode.Hash.HexString()
+			}
+		}
+		transformedExtensions = append(transformedExtensions, output)
+	}
+
+	return transformedExtensions, nil
+}
@@ -0,0 +1,99 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/guregu/null"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/stellar/go-stellar-sdk/hash"
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+func makeTtlTestEntry(t *testing.T, key xdr.LedgerKey, liveUntil uint32) *xdr.LedgerEntry {
+	keyBytes, err := key.MarshalBinary()
+	assert.NoError(t, err)
+	return &xdr.LedgerEntry{Data: xdr.LedgerEntryData{
+		Type: xdr.LedgerEntryTypeTtl,
+		Ttl:  &xdr.TtlEntry{KeyHash: hash.Hash(keyBytes), LiveUntilLedgerSeq: xdr.Uint32(liveUntil)},
+	}}
+}
+
+func TestTransformTtlExtensions(t *testing.T) {
+	var contractHash, codeHash xdr.Hash
+	contractHash[0], codeHash[0] = 1, 2
+	contractId := xdr.ContractId(contractHash)
+	codeKey := xdr.LedgerKey{Type: xdr.LedgerEntryTypeContractCode, ContractCode: &xdr.LedgerKeyContractCode{Hash: codeHash}}
+	dataKey := xdr.LedgerKey{
+		Type: xdr.LedgerEntryTypeContractData,
+		ContractData: &xdr.LedgerKeyContractData{
+			Contract:   xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeContract, ContractId: &contractId},
+			Key:        scSymbol("balance"),
+			Durability: xdr.ContractDataDurabilityPersistent,
+		},
+	}
+	withFootprint := func(keys ...xdr.LedgerKey) xdr.TransactionExt {
+		return xdr.TransactionExt{V: 1, SorobanData: &xdr.SorobanTransactionData{
+			Resources: xdr.SorobanResources{Footprint: xdr.LedgerFootprint{ReadOnly: keys}},
+		}}
+	}
+
+	// Extending the code and data of a contract, the TTL of the data is extended further
+	transaction, lhe := makeContractInvocationTestInput(t, nil)
+	transaction.Envelope.V1.Tx.Operations[0].Body = xdr.OperationBody{
+		Type:                 xdr.OperationTypeExtendFootprintTtl,
+		ExtendFootprintTtlOp: &xdr.ExtendFootprintTtlOp{ExtendTo: 990},
+	}
+	transaction.Envelope.V1.Tx.Ext = withFootprint(codeKey, dataKey)
+	transaction.UnsafeMeta.V3.SorobanMeta.Ext = xdr.SorobanTransactionMetaExt{V: 1, V1: &xdr.SorobanTransactionMetaExtV1{RentFeeCharged: 1000}}
+	transaction.UnsafeMeta.V3.Operations = []xdr.OperationMeta{
+		{Changes: xdr.LedgerEntryChanges{
+			{Type: xdr.LedgerEntryChangeTypeLedgerEntryState, State: makeTtlTestEntry(t, codeKey, 50)},
+			{Type: xdr.LedgerEntryChangeTypeLedgerEntryUpdated, Updated: makeTtlTestEntry(t, codeKey, 1000)},
+			{Type: xdr.LedgerEntryChangeTypeLedgerEntryState, State: makeTtlTestEntry(t, dataKey, 500)},
+			{Type: xdr.LedgerEntryChangeTypeLedgerEntryUpdated, Updated: makeTtlTestEntry(t, dataKey, 1000)},
+		}},
+	}
+
+	// Changes are sorted by ledger key, so the TTL of the data comes first
+	output, err := TransformTtlExtensions(transaction, lhe)
+	assert.NoError(t, err)
+	assert.Len(t, output, 2)
+	data, code := output[0], output[1]
+	assert.Equal(t, "extend_footprint_ttl", code.ExtensionType)
+	assert.Equal(t, "extended", code.EventType)
+	assert.Equal(t, "GAOEOQMXDDXPVJC3HDFX6LZFKANJ4OOLQOD2MNXJ7PGAY5FEO4BRRAQU", code.FeeAccount)
+	assert.Equal(t, int64(42949677057), code.OperationID)
+	assert.Equal(t, "LedgerEntryTypeContractCode", code.LedgerEntryType)
+	assert.Equal(t, codeHash.HexString(), code.ContractCodeHash)
+	assert.Equal(t, null.IntFrom(50), code.OldLiveUntilLedgerSeq)
+	assert.Equal(t, uint32(1000), code.NewLiveUntilLedgerSeq)
+	assert.Equal(t, uint32(950), code.LedgersExtended)
+	assert.Equal(t, strkey.MustEncode(strkey.VersionByteContract, contractHash[:]), data.ContractId)
+	assert.Equal(t, "ContractDataDurabilityPersistent", data.ContractDurability)
+	assert.Equal(t, uint32(500), data.LedgersExtended)
+	assert.Equal(t, int64(1000), data.RentFeeCharged)
+	assert.Equal(t, int64(1000), code.RentFeeCharged)
+
+	// A contract call creating an entry and restoring an expired one
+	transaction, lhe = makeContractInvocationTestInput(t, nil)
+	transaction.Envelope.V1.Tx.Ext = withFootprint(codeKey, dataKey)
+	transaction.UnsafeMeta.V3.Operations = []xdr.OperationMeta{
+		{Changes: xdr.LedgerEntryChanges{
+			{Type: xdr.LedgerEntryChangeTypeLedgerEntryCreated, Created: makeTtlTestEntry(t, dataKey, 109)},
+			{Type: xdr.LedgerEntryChangeTypeLedgerEntryState, State: makeTtlTestEntry(t, codeKey, 5)},
+			{Type: xdr.LedgerEntryChangeTypeLedgerEntryUpdated, Updated: makeTtlTestEntry(t, codeKey, 59)},
+		}},
+	}
+
+	output, err = TransformTtlExtensions(transaction, lhe)
+	assert.NoError(t, err)
+	assert.Len(t, output, 2)
+	assert.Equal(t, "implicit", output[0].ExtensionType)
+	assert.Equal(t, "created", output[0].EventType)
+	assert.False(t, output[0].OldLiveUntilLedgerSeq.Valid)
+	assert.Equal(t, uint32(100), output[0].LedgersExtended)
+	assert.Equal(t, "restored", output[1].EventType)
+	assert.Equal(t, uint32(50), output[1].LedgersExtended)
+}
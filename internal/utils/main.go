@@ -243,6 +243,9 @@ func AddCommonFlags(flags *pflag.FlagSet) {
 	flags.Uint32("retry-limit", 3, "Datastore GetLedger retry limit.")
 	flags.Uint32("retry-wait", 5, "Time in seconds to wait for GetLedger retry.")
 	flags.Bool("write-parquet", false, "If set, write output as parquet files.")
+	flags.String("output-format", "json", "Format of the exported files: json (newline-delimited) or csv")
+	flags.String("output-compression", "none", "Compression of the exported files: none, gzip or zstd")
+	flags.Bool("stdout", false, "If set, write the exported rows to stdout as newline-delimited JSON instead of to files. Logs are written to stderr.")
 }
 
 // AddArchiveFlags adds the history archive specific flags: output, and limit
@@ -273,6 +276,35 @@ func AddCloudStorageFlags(flags *pflag.FlagSet) {
 	flags.String("cloud-provider", "", "Cloud provider for storage services.")
 }
 
+// AddKafkaFlags adds the flags used to publish exported rows to Kafka: kafka-brokers, kafka-topic-prefix, kafka-topics,
+// kafka-datasets, kafka-partition-key, kafka-acks and kafka-resume
+func AddKafkaFlags(flags *pflag.FlagSet) {
+	flags.StringSlice("kafka-brokers", []string{}, "Comma separated list of Kafka brokers. If set, exported rows are also published to Kafka as each ledger is processed.")
+	flags.String("kafka-topic-prefix", "stellar-etl.", "Prefix of the Kafka topic each dataset is published to; the topic is the prefix followed by the dataset name.")
+	flags.StringToString("kafka-topics", map[string]string{}, "Per dataset Kafka topic overrides, e.g. operations=ops,trades=dex-trades")
+	flags.StringSlice("kafka-datasets", []string{}, "Datasets to publish to Kafka. If empty, every dataset the command exports is published.")
+	flags.StringToString("kafka-partition-key", map[string]string{}, "Per dataset JSON field whose value is used as the Kafka message key, e.g. operations=transaction_id. Rows of datasets without a key are spread across partitions.")
+	flags.String("kafka-acks", "all", "Acknowledgements required before a write is considered delivered: none, one or all")
+	flags.Bool("kafka-resume", false, "If set, start from the last ledger already published to the dataset topics instead of start-ledger")
+}
+
+// AddPostgresFlags adds the flags used to load exported rows into PostgreSQL: postgres-dsn, postgres-schema,
+// postgres-table-prefix and postgres-create-tables
+func AddPostgresFlags(flags *pflag.FlagSet) {
+	flags.String("postgres-dsn", "", "PostgreSQL connection string. If set, every exported batch is also loaded into PostgreSQL with COPY.")
+	flags.String("postgres-schema", "public", "PostgreSQL schema the tables are loaded into")
+	flags.String("postgres-table-prefix", "", "Prefix added to the name of every PostgreSQL table")
+	flags.Bool("postgres-create-tables", true, "If set, missing PostgreSQL tables are created from the output schemas before loading")
+}
+
+// AddContractSpecFlags adds the flags used to decode contract values with the spec of their contract: decode-with-spec,
+// contract-specs-file and contract-instances-file
+func AddContractSpecFlags(flags *pflag.FlagSet) {
+	flags.Bool("decode-with-spec", false, "If set, decoded contract values are rendered as flat JSON named after the spec of their contract")
+	flags.String("contract-specs-file", "", "contract_specs export used to know the spec of contract code uploaded before start-ledger")
+	flags.String("contract-instances-file", "", "contract_data export used to know the code of contracts deployed before start-ledger")
+}
+
 // AddCoreFlags adds the captive core specific flags: core-executable, core-config, batch-size, and output flags
 // TODO: https://stellarorg.atlassian.net/browse/HUBBLE-386 Deprecate?
 func AddCoreFlags(flags *pflag.FlagSet, defaultFolder string) {
@@ -290,12 +322,15 @@ func AddCoreFlags(flags *pflag.FlagSet, defaultFolder string) {
 // AddExportTypeFlags adds the captive core specifc flags: export-{type} flags
 func AddExportTypeFlags(flags *pflag.FlagSet) {
 	flags.BoolP("export-accounts", "a", false, "set in order to export account changes")
+	flags.BoolP("export-account-security", "", false, "set in order to export the signers and thresholds of accounts when they change")
 	flags.BoolP("export-trustlines", "t", false, "set in order to export trustline changes")
 	flags.BoolP("export-offers", "f", false, "set in order to export offer changes")
 	flags.BoolP("export-pools", "p", false, "set in order to export liquidity pool changes")
 	flags.BoolP("export-balances", "l", false, "set in order to export claimable balance changes")
 	flags.BoolP("export-contract-code", "", false, "set in order to export contract code changes")
 	flags.BoolP("export-contract-data", "", false, "set in order to export contract data changes")
+	flags.BoolP("export-contract-specs", "", false, "set in order to export the interface of uploaded contract code")
+	flags.BoolP("export-contract-balances", "", false, "set in order to export token balance changes kept in contract data")
 	flags.BoolP("export-config-settings", "", false, "set in order to export config settings changes")
 	flags.BoolP("export-ttl", "", false, "set in order to export ttl changes")
 	flags.BoolP("export-restored-keys", "", false, "set in order to export restored ledger keys")
@@ -452,18 +487,21 @@ func MustFlags(flags *pflag.FlagSet, logger *EtlLogger) FlagValues {
 }
 
 type CommonFlagValues struct {
-	EndNum         uint32
-	StrictExport   bool
-	IsTest         bool
-	IsFuture       bool
-	Extra          map[string]string
-	UseCaptiveCore bool
-	DatastorePath  string
-	BufferSize     uint32
-	NumWorkers     uint32
-	RetryLimit     uint32
-	RetryWait      uint32
-	WriteParquet   bool
+	EndNum            uint32
+	StrictExport      bool
+	IsTest            bool
+	IsFuture          bool
+	Extra             map[string]string
+	UseCaptiveCore    bool
+	DatastorePath     string
+	BufferSize        uint32
+	NumWorkers        uint32
+	RetryLimit        uint32
+	RetryWait         uint32
+	WriteParquet      bool
+	OutputFormat      string
+	OutputCompression string
+	Stdout            bool
 }
 
 // MustCommonFlags gets the values of the the flags common to all commands: end-ledger and strict-export.
@@ -532,19 +570,46 @@ func MustCommonFlags(flags *pflag.FlagSet, logger *EtlLogger) CommonFlagValues {
 		logger.Fatal("could not get write-parquet flag: ", err)
 	}
 
+	outputFormat, err := flags.GetString("output-format")
+	if err != nil {
+		logger.Fatal("could not get output-format: ", err)
+	}
+	if outputFormat != "json" && outputFormat != "csv" {
+		logger.Fatalf("output-format must be json or csv, got %s", outputFormat)
+	}
+
+	outputCompression, err := flags.GetString("output-compression")
+	if err != nil {
+		logger.Fatal("could not get output-compression: ", err)
+	}
+	if outputCompression != "none" && outputCompression != "gzip" && outputCompression != "zstd" {
+		logger.Fatalf("output-compression must be none, gzip or zstd, got %s", outputCompression)
+	}
+
+	stdout, err := flags.GetBool("stdout")
+	if err != nil {
+		logger.Fatal("could not get stdout flag: ", err)
+	}
+	if stdout && (outputFormat != "json" || outputCompression != "none") {
+		logger.Fatal("stdout only supports uncompressed json output")
+	}
+
 	return CommonFlagValues{
-		EndNum:         endNum,
-		StrictExport:   strictExport,
-		IsTest:         isTest,
-		IsFuture:       isFuture,
-		Extra:          extra,
-		UseCaptiveCore: useCaptiveCore,
-		DatastorePath:  datastorePath,
-		BufferSize:     bufferSize,
-		NumWorkers:     numWorkers,
-		RetryLimit:     retryLimit,
-		RetryWait:      retryWait,
-		WriteParquet:   WriteParquet,
+		EndNum:            endNum,
+		StrictExport:      strictExport,
+		IsTest:            isTest,
+		IsFuture:          isFuture,
+		Extra:             extra,
+		UseCaptiveCore:    useCaptiveCore,
+		DatastorePath:     datastorePath,
+		BufferSize:        bufferSize,
+		NumWorkers:        numWorkers,
+		RetryLimit:        retryLimit,
+		RetryWait:         retryWait,
+		WriteParquet:      WriteParquet,
+		OutputFormat:      outputFormat,
+		OutputCompression: outputCompression,
+		Stdout:            stdout,
 	}
 }
 
@@ -629,6 +694,164 @@ func MustCloudStorageFlags(flags *pflag.FlagSet, logger *EtlLogger) (bucket, cre
 	return
 }
 
+type KafkaFlagValues struct {
+	Brokers      []string
+	TopicPrefix  string
+	Topics       map[string]string
+	Datasets     []string
+	PartitionKey map[string]string
+	Acks         string
+	Resume       bool
+}
+
+// Enabled returns true if rows of the given dataset should be published to Kafka
+func (k KafkaFlagValues) Enabled(dataset string) bool {
+	if len(k.Brokers) == 0 {
+		return false
+	}
+	if len(k.Datasets) == 0 {
+		return true
+	}
+	for _, d := range k.Datasets {
+		if d == dataset {
+			return true
+		}
+	}
+	return false
+}
+
+// Topic returns the Kafka topic the given dataset is published to
+func (k KafkaFlagValues) Topic(dataset string) string {
+	if topic, ok := k.Topics[dataset]; ok {
+		return topic
+	}
+	return k.TopicPrefix + dataset
+}
+
+// MustKafkaFlags gets the values of the Kafka flags. If any do not exist or kafka-acks is invalid, it stops the program fatally using the logger
+func MustKafkaFlags(flags *pflag.FlagSet, logger *EtlLogger) KafkaFlagValues {
+	brokers, err := flags.GetStringSlice("kafka-brokers")
+	if err != nil {
+		logger.Fatal("could not get kafka brokers: ", err)
+	}
+
+	topicPrefix, err := flags.GetString("kafka-topic-prefix")
+	if err != nil {
+		logger.Fatal("could not get kafka topic prefix: ", err)
+	}
+
+	topics, err := flags.GetStringToString("kafka-topics")
+	if err != nil {
+		logger.Fatal("could not get kafka topics: ", err)
+	}
+
+	datasets, err := flags.GetStringSlice("kafka-datasets")
+	if err != nil {
+		logger.Fatal("could not get kafka datasets: ", err)
+	}
+
+	partitionKey, err := flags.GetStringToString("kafka-partition-key")
+	if err != nil {
+		logger.Fatal("could not get kafka partition key: ", err)
+	}
+
+	acks, err := flags.GetString("kafka-acks")
+	if err != nil {
+		logger.Fatal("could not get kafka acks: ", err)
+	}
+	switch acks {
+	case "none", "one", "all":
+	default:
+		logger.Fatalf("kafka-acks must be one of none, one or all; got %s", acks)
+	}
+
+	resume, err := flags.GetBool("kafka-resume")
+	if err != nil {
+		logger.Fatal("could not get kafka-resume flag: ", err)
+	}
+
+	return KafkaFlagValues{
+		Brokers:      brokers,
+		TopicPrefix:  topicPrefix,
+		Topics:       topics,
+		Datasets:     datasets,
+		PartitionKey: partitionKey,
+		Acks:         acks,
+		Resume:       resume,
+	}
+}
+
+type PostgresFlagValues struct {
+	DSN          string
+	Schema       string
+	TablePrefix  string
+	CreateTables bool
+}
+
+// MustPostgresFlags gets the values of the PostgreSQL flags. If any do not exist, it stops the program fatally using the logger
+func MustPostgresFlags(flags *pflag.FlagSet, logger *EtlLogger) PostgresFlagValues {
+	dsn, err := flags.GetString("postgres-dsn")
+	if err != nil {
+		logger.Fatal("could not get postgres dsn: ", err)
+	}
+
+	schema, err := flags.GetString("postgres-schema")
+	if err != nil {
+		logger.Fatal("could not get postgres schema: ", err)
+	}
+
+	tablePrefix, err := flags.GetString("postgres-table-prefix")
+	if err != nil {
+		logger.Fatal("could not get postgres table prefix: ", err)
+	}
+
+	createTables, err := flags.GetBool("postgres-create-tables")
+	if err != nil {
+		logger.Fatal("could not get postgres-create-tables flag: ", err)
+	}
+
+	return PostgresFlagValues{
+		DSN:          dsn,
+		Schema:       schema,
+		TablePrefix:  tablePrefix,
+		CreateTables: createTables,
+	}
+}
+
+type ContractSpecFlagValues struct {
+	Decode        bool
+	SpecsFile     string
+	InstancesFile string
+}
+
+// MustContractSpecFlags gets the values of the contract spec flags. If any do not exist, it stops the program fatally using the logger
+func MustContractSpecFlags(flags *pflag.FlagSet, logger *EtlLogger) ContractSpecFlagValues {
+	decode, err := flags.GetBool("decode-with-spec")
+	if err != nil {
+		logger.Fatal("could not get decode-with-spec flag: ", err)
+	}
+
+	specsFile, err := flags.GetString("contract-specs-file")
+	if err != nil {
+		logger.Fatal("could not get contract specs file: ", err)
+	}
+
+	instancesFile, err := flags.GetString("contract-instances-file")
+	if err != nil {
+		logger.Fatal("could not get contract instances file: ", err)
+	}
+
+	if !decode && (specsFile != "" || instancesFile != "") {
+		logger.Fatal("contract-specs-file and contract-instances-file require decode-with-spec")
+	}
+
+	return ContractSpecFlagValues{
+		Decode:        decode,
+		SpecsFile:     specsFile,
+		InstancesFile: instancesFile,
+	}
+}
+
 // MustCoreFlags gets the values for the core-executable, core-config, start ledger batch-size, and output flags. If any do not exist, it stops the program fatally using the logger
 func MustCoreFlags(flags *pflag.FlagSet, logger *EtlLogger) (execPath, configPath string, startNum, batchSize uint32, path, parquetPath string) {
 	execPath, err := flags.GetString("core-executable")
@@ -669,16 +892,19 @@ func MustCoreFlags(flags *pflag.FlagSet, logger *EtlLogger) (execPath, configPat
 func MustExportTypeFlags(flags *pflag.FlagSet, logger *EtlLogger) map[string]bool {
 	var err error
 	exports := map[string]bool{
-		"export-accounts":        false,
-		"export-trustlines":      false,
-		"export-offers":          false,
-		"export-pools":           false,
-		"export-balances":        false,
-		"export-contract-code":   false,
-		"export-contract-data":   false,
-		"export-config-settings": false,
-		"export-ttl":             false,
-		"export-restored-keys":   false,
+		"export-accounts":          false,
+		"export-account-security":  false,
+		"export-trustlines":        false,
+		"export-offers":            false,
+		"export-pools":             false,
+		"export-balances":          false,
+		"export-contract-code":     false,
+		"export-contract-data":     false,
+		"export-contract-specs":    false,
+		"export-contract-balances": false,
+		"export-config-settings":   false,
+		"export-ttl":               false,
+		"export-restored-keys":     false,
 	}
 
 	// Check if any flag was explicitly set to true
@@ -1043,6 +1269,16 @@ func CreateDatastore(ctx context.Context, env EnvironmentDetails) (datastore.Dat
 	return datastore, dataStoreConfig, error
 }
 
+// GetBufferedStorageBackendConfig builds the BufferedStorageBackend config from the common datastore flags
+func GetBufferedStorageBackendConfig(env EnvironmentDetails) ledgerbackend.BufferedStorageBackendConfig {
+	return ledgerbackend.BufferedStorageBackendConfig{
+		BufferSize: env.CommonFlagValues.BufferSize,
+		NumWorkers: env.CommonFlagValues.NumWorkers,
+		RetryLimit: env.CommonFlagValues.RetryLimit,
+		RetryWait:  time.Duration(env.CommonFlagValues.RetryWait) * time.Second,
+	}
+}
+
 // CreateLedgerBackend creates a ledger backend using captive core or datastore
 // Defaults to using datastore
 func CreateLedgerBackend(ctx context.Context, useCaptiveCore bool, env EnvironmentDetails) (ledgerbackend.LedgerBackend, error) {
@@ -1060,12 +1296,7 @@ func CreateLedgerBackend(ctx context.Context, useCaptiveCore bool, env Environme
 		return nil, err
 	}
 
-	BSBackendConfig := ledgerbackend.BufferedStorageBackendConfig{
-		BufferSize: env.CommonFlagValues.BufferSize,
-		NumWorkers: env.CommonFlagValues.NumWorkers,
-		RetryLimit: env.CommonFlagValues.RetryLimit,
-		RetryWait:  time.Duration(env.CommonFlagValues.RetryWait) * time.Second,
-	}
+	BSBackendConfig := GetBufferedStorageBackendConfig(env)
 
 	var schema datastore.DataStoreSchema
 	schema, err = datastore.LoadSchema(context.Background(), dataStore, datastoreConfig)